@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,8 +12,13 @@ import (
 	"github.com/Sudo-Ivan/fusionx/auth"
 	"github.com/Sudo-Ivan/fusionx/conf"
 	"github.com/Sudo-Ivan/fusionx/frontend"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
 	"github.com/Sudo-Ivan/fusionx/repo"
 	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/archive"
+	"github.com/Sudo-Ivan/fusionx/service/favicon"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+	"github.com/Sudo-Ivan/fusionx/service/pull"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -31,11 +37,30 @@ type Params struct {
 	UseSecureCookie bool
 	TLSCert         string
 	TLSKey          string
-	DBPath          string
 	DemoMode        bool
+	MetricsEnabled  bool
+	MetricsAddr     string
+	MetricsToken    string
+	Puller          *pull.Puller
+	Jobs            *jobs.Manager
+	PurgeAfter      time.Duration
+	// PublicURL is the address of the web UI in published feeds, the
+	// request's address if empty.
+	PublicURL string
+	// SecretBox encrypts the credentials of integrations, nil if there is no
+	// key.
+	SecretBox *secret.Box
+	// NewsletterDomain is the domain of the addresses of newsletters, empty
+	// if they are disabled.
+	NewsletterDomain string
 }
 
-func Run(params Params) {
+// shutdownTimeout is how long in-flight requests may take to finish after
+// shutdown starts.
+const shutdownTimeout = 10 * time.Second
+
+// Run serves the web server until ctx is done, then shuts it down gracefully.
+func Run(ctx context.Context, params Params) {
 	r := echo.New()
 
 	if conf.Debug {
@@ -52,6 +77,9 @@ func Run(params Params) {
 	r.HTTPErrorHandler = errorHandler
 	r.Validator = newCustomValidator()
 	r.Use(middleware.Recover())
+	if params.MetricsEnabled {
+		r.Use(metricsMiddleware)
+	}
 	r.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
 		LogStatus:   true,
 		LogURI:      true,
@@ -70,6 +98,12 @@ func Run(params Params) {
 		},
 	}))
 	r.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		// the timeout handler buffers the whole response, which doesn't
+		// work for streaming large backups, exports or events
+		Skipper: func(c echo.Context) bool {
+			path := c.Request().URL.Path
+			return path == "/api/backup" || path == "/api/items/export" || path == "/api/events"
+		},
 		Timeout: 30 * time.Second,
 	}))
 	if params.PasswordHash != nil {
@@ -91,6 +125,21 @@ func Run(params Params) {
 		Browse:     false,
 	}))
 
+	healthAPIHandler := newHealthAPI(server.NewHealth(ctx, repo.Ping, func(context.Context) error {
+		return params.Puller.Check()
+	}))
+	r.GET("/healthz", healthAPIHandler.Live)
+	r.GET("/readyz", healthAPIHandler.Ready)
+
+	publishedFeedAPIHandler := newPublishedFeedAPI(server.NewPublishedFeed(
+		repo.NewPublishedFeed(repo.DB), repo.NewItem(repo.DB), repo.NewGroup(repo.DB), repo.NewSmartFolder(repo.DB),
+	), params.PublicURL)
+	itemShareAPIHandler := newItemShareAPI(server.NewItemShare(repo.NewItemShare(repo.DB), repo.NewItem(repo.DB)), params.PublicURL)
+	// public feeds and shared items don't need a session, the token in the
+	// path grants access
+	r.GET("/api/public/feeds/:token/:format", publishedFeedAPIHandler.Render)
+	r.GET("/share/:token", itemShareAPIHandler.View)
+
 	authed := r.Group("/api")
 
 	if params.PasswordHash != nil && !params.DemoMode {
@@ -132,11 +181,14 @@ func Run(params Params) {
 	}
 
 	feeds := authed.Group("/feeds")
-	feedAPIHandler := newFeedAPI(server.NewFeed(repo.NewFeed(repo.DB)))
+	feedAPIHandler := newFeedAPI(server.NewFeed(repo.NewFeed(repo.DB), repo.NewFetchLog(repo.DB), params.Jobs, params.NewsletterDomain))
 	feeds.GET("", feedAPIHandler.List)
 	feeds.GET("/:id", feedAPIHandler.Get)
+	feeds.GET("/:id/history", feedAPIHandler.History)
 	feeds.POST("", feedAPIHandler.Create)
 	feeds.POST("/validation", feedAPIHandler.CheckValidity)
+	feeds.POST("/preview", feedAPIHandler.Preview)
+	feeds.POST("/newsletters", feedAPIHandler.CreateNewsletter)
 	feeds.PATCH("/:id", feedAPIHandler.Update)
 	feeds.DELETE("/:id", feedAPIHandler.Delete)
 	feeds.POST("/refresh", feedAPIHandler.Refresh)
@@ -148,35 +200,105 @@ func Run(params Params) {
 	groups.PATCH("/:id", groupAPIHandler.Update)
 	groups.DELETE("/:id", groupAPIHandler.Delete)
 
+	smartFolders := authed.Group("/smart_folders")
+	smartFolderAPIHandler := newSmartFolderAPI(server.NewSmartFolder(repo.NewSmartFolder(repo.DB), repo.NewItem(repo.DB)))
+	smartFolders.GET("", smartFolderAPIHandler.All)
+	smartFolders.POST("", smartFolderAPIHandler.Create)
+	smartFolders.PATCH("/:id", smartFolderAPIHandler.Update)
+	smartFolders.DELETE("/:id", smartFolderAPIHandler.Delete)
+
+	publishedFeeds := authed.Group("/published_feeds")
+	publishedFeeds.GET("", publishedFeedAPIHandler.All)
+	publishedFeeds.POST("", publishedFeedAPIHandler.Create)
+	publishedFeeds.PATCH("/:id", publishedFeedAPIHandler.Update)
+	publishedFeeds.POST("/:id/token", publishedFeedAPIHandler.RotateToken)
+	publishedFeeds.DELETE("/:id", publishedFeedAPIHandler.Delete)
+
 	items := authed.Group("/items")
-	itemAPIHandler := newItemAPI(server.NewItem(repo.NewItem(repo.DB)))
+	itemAPIHandler := newItemAPI(server.NewItem(repo.NewItem(repo.DB), repo.NewSmartFolder(repo.DB), archive.NewStore(archive.CacheDir)))
 	items.GET("", itemAPIHandler.List)
+	items.GET("/changes", itemAPIHandler.Changes)
+	items.GET("/export", itemAPIHandler.Export)
 	items.GET("/:id", itemAPIHandler.Get)
+	items.GET("/:id/revisions", itemAPIHandler.Revisions)
+	items.GET("/:id/archive", itemAPIHandler.Archive)
 	items.PATCH("/:id/bookmark", itemAPIHandler.UpdateBookmark)
 	items.PATCH("/-/unread", itemAPIHandler.UpdateUnread)
 	items.DELETE("/:id", itemAPIHandler.Delete)
+	items.POST("/:id/share", itemShareAPIHandler.Create)
+	integrationAPIHandler := newIntegrationAPI(server.NewIntegration(repo.NewIntegration(repo.DB), repo.NewItem(repo.DB), params.SecretBox))
+	items.POST("/:id/send/:integration", integrationAPIHandler.Send)
+
+	shares := authed.Group("/shares")
+	shares.GET("", itemShareAPIHandler.List)
+	shares.DELETE("/:id", itemShareAPIHandler.Delete)
+
+	importAPIHandler := newImportAPI(server.NewImport(repo.NewFeed(repo.DB), repo.NewGroup(repo.DB), repo.NewItem(repo.DB), params.Jobs))
+	authed.POST("/imports", importAPIHandler.Create, middleware.BodyLimit("300M"))
+
+	integrations := authed.Group("/integrations")
+	integrations.GET("", integrationAPIHandler.All)
+	integrations.GET("/kinds", integrationAPIHandler.Kinds)
+	integrations.POST("", integrationAPIHandler.Create)
+	integrations.PATCH("/:id", integrationAPIHandler.Update)
+	integrations.DELETE("/:id", integrationAPIHandler.Delete)
+
+	jobs := authed.Group("/jobs")
+	jobAPIHandler := newJobAPI(server.NewJob(params.Jobs, repo.NewJob(repo.DB), params.PurgeAfter))
+	jobs.GET("", jobAPIHandler.List)
+	jobs.GET("/:id", jobAPIHandler.Get)
+	jobs.POST("", jobAPIHandler.Create)
+	jobs.POST("/:id/cancel", jobAPIHandler.Cancel)
 
 	favicons := authed.Group("/favicons")
-	faviconAPIHandler := newFaviconAPI("./cache/favicons")
+	faviconAPIHandler := newFaviconAPI(favicon.CacheDir)
 	favicons.GET("/:filename", faviconAPIHandler.ServeFavicon)
 
-	statsAPIHandler := newStatsAPI(server.NewStats(repo.NewStats(repo.DB), params.DBPath))
+	statsSrv := server.NewStats(repo.NewStats(repo.DB), archive.NewStore(archive.CacheDir))
+	statsAPIHandler := newStatsAPI(statsSrv)
 	authed.GET("/stats", statsAPIHandler.Get)
 
+	if params.MetricsEnabled {
+		if err := serveMetrics(ctx, r, params.MetricsAddr, params.MetricsToken, statsSrv); err != nil {
+			slog.Error("failed to set up metrics", "error", err)
+			return
+		}
+	}
+
+	eventsAPIHandler := newEventsAPI(ctx)
+	authed.GET("/events", eventsAPIHandler.Stream)
+
+	backupAPIHandler := newBackupAPI(server.NewBackup(params.DemoMode), favicon.CacheDir)
+	authed.GET("/backup", backupAPIHandler.Get)
+
 	configAPIHandler := newConfigAPI(server.NewConfig(repo.NewConfig(repo.DB), params.DemoMode))
 	authed.GET("/config", configAPIHandler.Get)
 	authed.PATCH("/config", configAPIHandler.Update)
 
-	var err error
 	addr := fmt.Sprintf("%s:%d", params.Host, params.Port)
-	if params.TLSCert != "" {
-		err = r.StartTLS(addr, params.TLSCert, params.TLSKey)
-	} else {
-		err = r.Start(addr)
-	}
-	if err != nil {
-		slog.Error(err.Error())
+	errCh := make(chan error, 1)
+	go func() {
+		if params.TLSCert != "" {
+			errCh <- r.StartTLS(addr, params.TLSCert, params.TLSKey)
+		} else {
+			errCh <- r.Start(addr)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(err.Error())
+		}
 		return
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down web server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := r.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to shut down web server gracefully", "error", err)
 	}
 }
 
@@ -225,6 +347,15 @@ func (v *CustomValidator) Validate(i interface{}) error {
 	return err
 }
 
+// baseURL returns the configured public URL of the web UI, or else the
+// address of the request.
+func baseURL(c echo.Context, publicURL string) string {
+	if publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+	return c.Scheme() + "://" + c.Request().Host
+}
+
 func bindAndValidate(i interface{}, c echo.Context) error {
 	if err := c.Bind(i); err != nil {
 		return err
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type backupAPI struct {
+	srv        *server.Backup
+	faviconDir string
+}
+
+func newBackupAPI(srv *server.Backup, faviconDir string) *backupAPI {
+	return &backupAPI{
+		srv:        srv,
+		faviconDir: faviconDir,
+	}
+}
+
+// Get streams an archive of the database and the favicon cache.
+func (b backupAPI) Get(c echo.Context) error {
+	snapshot, err := b.srv.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "application/gzip")
+	resp.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+snapshot.Filename()+`"`)
+	resp.WriteHeader(http.StatusOK)
+	if err := snapshot.WriteArchive(resp, b.faviconDir); err != nil {
+		// the status is already sent, so the client only sees a truncated
+		// archive, which fails to decompress
+		slog.Error("failed to write backup", "error", err)
+	}
+	return nil
+}
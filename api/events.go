@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/service/events"
+
+	"github.com/labstack/echo/v4"
+)
+
+// eventKeepAlive is how often a comment is sent on an idle stream, so that
+// proxies don't close it.
+const eventKeepAlive = 25 * time.Second
+
+type eventsAPI struct {
+	// ctx is done when the server shuts down, which must end the streams
+	ctx context.Context
+}
+
+func newEventsAPI(ctx context.Context) *eventsAPI {
+	return &eventsAPI{
+		ctx: ctx,
+	}
+}
+
+// Stream sends the events of the application as Server-Sent Events. The
+// event name is the event type and the data is its JSON encoded data.
+//
+// The stream ends when the client can't keep up; clients should reconnect
+// and reload their state then, as they missed events.
+func (e eventsAPI) Stream(c echo.Context) error {
+	ch, unsubscribe := events.Subscribe(64)
+	defer unsubscribe()
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set(echo.HeaderCacheControl, "no-cache")
+	resp.Header().Set(echo.HeaderConnection, "keep-alive")
+	// disable buffering in nginx
+	resp.Header().Set("X-Accel-Buffering", "no")
+	resp.WriteHeader(http.StatusOK)
+	// tell the client how long to wait before reconnecting
+	fmt.Fprint(resp, "retry: 3000\n\n")
+	resp.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-e.ctx.Done():
+			return nil
+		case <-ticker.C:
+			fmt.Fprint(resp, ": keep-alive\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				slog.Error("failed to encode event", "event", ev.Type, "error", err)
+				continue
+			}
+			fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		resp.Flush()
+	}
+}
@@ -46,6 +46,20 @@ func (f feedAPI) Get(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+func (f feedAPI) History(c echo.Context) error {
+	var req server.ReqFeedHistory
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.History(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (f feedAPI) Create(c echo.Context) error {
 	var req server.ReqFeedCreate
 	if err := bindAndValidate(&req, c); err != nil {
@@ -60,6 +74,34 @@ func (f feedAPI) Create(c echo.Context) error {
 	return c.JSON(http.StatusCreated, resp)
 }
 
+func (f feedAPI) CreateNewsletter(c echo.Context) error {
+	var req server.ReqNewsletterCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.CreateNewsletter(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (f feedAPI) Preview(c echo.Context) error {
+	var req server.ReqFeedPreview
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.Preview(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (f feedAPI) CheckValidity(c echo.Context) error {
 	var req server.ReqFeedCheckValidity
 	if err := bindAndValidate(&req, c); err != nil {
@@ -107,9 +149,13 @@ func (f feedAPI) Refresh(c echo.Context) error {
 		return err
 	}
 
-	if err := f.srv.Refresh(c.Request().Context(), &req); err != nil {
+	resp, err := f.srv.Refresh(c.Request().Context(), &req)
+	if err != nil {
 		return err
 	}
+	if resp.JobID != nil {
+		return c.JSON(http.StatusAccepted, resp)
+	}
 
 	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type healthAPI struct {
+	srv *server.Health
+}
+
+func newHealthAPI(srv *server.Health) *healthAPI {
+	return &healthAPI{
+		srv: srv,
+	}
+}
+
+func (h healthAPI) Live(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
+	defer cancel()
+
+	resp, code := h.srv.Live(ctx)
+	return c.JSON(code, resp)
+}
+
+func (h healthAPI) Ready(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 5*time.Second)
+	defer cancel()
+
+	resp, code := h.srv.Ready(ctx)
+	return c.JSON(code, resp)
+}
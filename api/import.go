@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/importer"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxImportSize limits the total size of the uploaded exports.
+const maxImportSize = 256 << 20
+
+type importAPI struct {
+	srv *server.Import
+}
+
+func newImportAPI(srv *server.Import) *importAPI {
+	return &importAPI{
+		srv: srv,
+	}
+}
+
+// Create imports the exports uploaded as the "files" fields of a multipart
+// form.
+func (i importAPI) Create(c echo.Context) error {
+	var req server.ReqImportCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "a multipart form is required")
+	}
+	size := int64(0)
+	for _, fh := range form.File["files"] {
+		size += fh.Size
+		if size > maxImportSize {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("the files are larger than %d MiB", maxImportSize>>20))
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		req.Files = append(req.Files, importer.File{Name: fh.Filename, Content: content})
+	}
+	if len(req.Files) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "no files")
+	}
+
+	resp, err := i.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type integrationAPI struct {
+	srv *server.Integration
+}
+
+func newIntegrationAPI(srv *server.Integration) *integrationAPI {
+	return &integrationAPI{
+		srv: srv,
+	}
+}
+
+func (i integrationAPI) Kinds(c echo.Context) error {
+	return c.JSON(http.StatusOK, i.srv.Kinds(c.Request().Context()))
+}
+
+func (i integrationAPI) All(c echo.Context) error {
+	resp, err := i.srv.All(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (i integrationAPI) Create(c echo.Context) error {
+	var req server.ReqIntegrationCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := i.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (i integrationAPI) Update(c echo.Context) error {
+	var req server.ReqIntegrationUpdate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := i.srv.Update(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (i integrationAPI) Delete(c echo.Context) error {
+	var req server.ReqIntegrationDelete
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := i.srv.Delete(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (i integrationAPI) Send(c echo.Context) error {
+	var req server.ReqItemSend
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := i.srv.Send(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
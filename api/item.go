@@ -1,6 +1,8 @@
 package api
 
 import (
+	"log/slog"
+	"mime"
 	"net/http"
 
 	"github.com/Sudo-Ivan/fusionx/server"
@@ -32,6 +34,65 @@ func (i itemAPI) List(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// Export streams the items matching the list filters as a file.
+func (i itemAPI) Export(c echo.Context) error {
+	var req server.ReqItemExport
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := i.srv.Export(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, resp.ContentType)
+	w.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": resp.Filename}))
+	w.WriteHeader(http.StatusOK)
+	if err := resp.Write(c.Request().Context(), w); err != nil {
+		// the status is already sent, so the client only sees a truncated
+		// file
+		slog.Error("failed to write export", "error", err)
+	}
+	return nil
+}
+
+// Archive serves the archived page of an item. The page comes from another
+// site, so it's sandboxed: scripts don't run and it can't reach the API.
+func (i itemAPI) Archive(c echo.Context) error {
+	var req server.ReqItemArchive
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := i.srv.Archive(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	h := c.Response().Header()
+	h.Set("Content-Security-Policy", "sandbox allow-popups allow-popups-to-escape-sandbox; default-src 'none'; "+
+		"img-src data: http: https:; style-src 'unsafe-inline' data: http: https:; font-src data: http: https:; media-src http: https:")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, no-cache")
+	return c.Blob(http.StatusOK, "text/html; charset=utf-8", resp.Body)
+}
+
+func (i itemAPI) Changes(c echo.Context) error {
+	var req server.ReqItemChanges
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := i.srv.Changes(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (i itemAPI) Get(c echo.Context) error {
 	var req server.ReqItemGet
 	if err := bindAndValidate(&req, c); err != nil {
@@ -46,6 +107,20 @@ func (i itemAPI) Get(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+func (i itemAPI) Revisions(c echo.Context) error {
+	var req server.ReqItemRevisions
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := i.srv.Revisions(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
 func (i itemAPI) Delete(c echo.Context) error {
 	var req server.ReqItemDelete
 	if err := bindAndValidate(&req, c); err != nil {
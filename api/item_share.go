@@ -0,0 +1,107 @@
+package api
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed templates/share.html
+var shareTemplateFS embed.FS
+
+var shareTemplate = template.Must(template.ParseFS(shareTemplateFS, "templates/share.html"))
+
+// shareCSP only allows the inline styles of the page and media from
+// anywhere, so that nothing in the item's content can run.
+const shareCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src http: https: data:; media-src http: https:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+type itemShareAPI struct {
+	srv       *server.ItemShare
+	publicURL string
+}
+
+func newItemShareAPI(srv *server.ItemShare, publicURL string) *itemShareAPI {
+	return &itemShareAPI{
+		srv:       srv,
+		publicURL: publicURL,
+	}
+}
+
+func (s itemShareAPI) Create(c echo.Context) error {
+	var req server.ReqItemShareCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+	req.BaseURL = baseURL(c, s.publicURL)
+
+	resp, err := s.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (s itemShareAPI) List(c echo.Context) error {
+	var req server.ReqItemShareList
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+	req.BaseURL = baseURL(c, s.publicURL)
+
+	resp, err := s.srv.List(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (s itemShareAPI) Delete(c echo.Context) error {
+	var req server.ReqItemShareDelete
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := s.srv.Delete(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// View renders a shared item as a standalone page without a session.
+func (s itemShareAPI) View(c echo.Context) error {
+	var req server.ReqItemShareView
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	data := struct {
+		Item *server.RespItemShareView
+	}{}
+	resp, err := s.srv.View(c.Request().Context(), &req)
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		status = http.StatusNotFound
+	case err != nil:
+		return err
+	default:
+		data.Item = resp
+	}
+
+	h := c.Response().Header()
+	h.Set("Content-Security-Policy", shareCSP)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, no-cache")
+	h.Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(status)
+	return shareTemplate.Execute(c.Response(), data)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type jobAPI struct {
+	srv *server.Job
+}
+
+func newJobAPI(srv *server.Job) *jobAPI {
+	return &jobAPI{
+		srv: srv,
+	}
+}
+
+func (j jobAPI) List(c echo.Context) error {
+	var req server.ReqJobList
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := j.srv.List(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (j jobAPI) Get(c echo.Context) error {
+	var req server.ReqJobGet
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := j.srv.Get(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (j jobAPI) Create(c echo.Context) error {
+	var req server.ReqJobCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := j.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+func (j jobAPI) Cancel(c echo.Context) error {
+	var req server.ReqJobCancel
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := j.srv.Cancel(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
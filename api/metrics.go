@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsMiddleware records the latency of every request that matched a route.
+func metricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		// use the route pattern rather than the raw URI to keep the label
+		// cardinality bounded
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Response().Status
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			status = httpErr.Code
+		}
+		metrics.ObserveHTTPRequest(c.Request().Method, route, status, time.Since(start))
+		return err
+	}
+}
+
+// serveMetrics exposes the Prometheus metrics, either on the main server or,
+// when addr is set, on a dedicated listener that is closed when ctx is done.
+// If token is set, scrapers must authenticate with it as a bearer token. It's
+// required on the main server, which is usually public.
+func serveMetrics(ctx context.Context, r *echo.Echo, addr, token string, stats *server.Stats) error {
+	if addr == "" && token == "" {
+		return errors.New("serving metrics on the main server needs a token")
+	}
+	if err := metrics.RegisterState(stats.MetricsState); err != nil {
+		return err
+	}
+
+	var handler http.Handler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+	if token != "" {
+		handler = metricsAuth(token, handler)
+	}
+
+	if addr == "" {
+		r.GET("/metrics", echo.WrapHandler(handler))
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("serving metrics", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		// #nosec G104 - the process is exiting, nothing to do about the error
+		srv.Shutdown(shutdownCtx)
+	}()
+	return nil
+}
+
+func metricsAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got := []byte(req.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type publishedFeedAPI struct {
+	srv       *server.PublishedFeed
+	publicURL string
+}
+
+func newPublishedFeedAPI(srv *server.PublishedFeed, publicURL string) *publishedFeedAPI {
+	return &publishedFeedAPI{
+		srv:       srv,
+		publicURL: publicURL,
+	}
+}
+
+func (f publishedFeedAPI) All(c echo.Context) error {
+	resp, err := f.srv.All(c.Request().Context(), baseURL(c, f.publicURL))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (f publishedFeedAPI) Create(c echo.Context) error {
+	var req server.ReqPublishedFeedCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (f publishedFeedAPI) Update(c echo.Context) error {
+	var req server.ReqPublishedFeedUpdate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	err := f.srv.Update(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (f publishedFeedAPI) RotateToken(c echo.Context) error {
+	var req server.ReqPublishedFeedToken
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.RotateToken(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (f publishedFeedAPI) Delete(c echo.Context) error {
+	var req server.ReqPublishedFeedDelete
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := f.srv.Delete(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Render serves a published feed without a session, the token in the path
+// is the credential.
+func (f publishedFeedAPI) Render(c echo.Context) error {
+	var req server.ReqPublishedFeedRender
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+	req.BaseURL = baseURL(c, f.publicURL)
+
+	resp, err := f.srv.Render(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	// keep the token out of shared caches
+	c.Response().Header().Set("Cache-Control", "private, max-age=300")
+	return c.Blob(http.StatusOK, resp.ContentType, resp.Body)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/server"
+
+	"github.com/labstack/echo/v4"
+)
+
+type smartFolderAPI struct {
+	srv *server.SmartFolder
+}
+
+func newSmartFolderAPI(srv *server.SmartFolder) *smartFolderAPI {
+	return &smartFolderAPI{
+		srv: srv,
+	}
+}
+
+func (f smartFolderAPI) All(c echo.Context) error {
+	resp, err := f.srv.All(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (f smartFolderAPI) Create(c echo.Context) error {
+	var req server.ReqSmartFolderCreate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	resp, err := f.srv.Create(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (f smartFolderAPI) Update(c echo.Context) error {
+	var req server.ReqSmartFolderUpdate
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	err := f.srv.Update(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (f smartFolderAPI) Delete(c echo.Context) error {
+	var req server.ReqSmartFolderDelete
+	if err := bindAndValidate(&req, c); err != nil {
+		return err
+	}
+
+	if err := f.srv.Delete(c.Request().Context(), &req); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -22,6 +22,13 @@ func (hp HashedPassword) Equals(other HashedPassword) bool {
 	return subtle.ConstantTimeCompare(hp.hash, other.hash) != 0
 }
 
+// NewHashedPassword wraps a hash previously obtained from HashedPassword.Bytes.
+func NewHashedPassword(hash []byte) HashedPassword {
+	return HashedPassword{
+		hash: hash,
+	}
+}
+
 func HashPassword(password string) (HashedPassword, error) {
 	if len(password) == 0 {
 		return HashedPassword{}, ErrPasswordTooShort
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/archive"
+)
+
+var bookmarksExportCmd = &command{
+	name: "bookmarks export",
+	help: "Print all bookmarked items",
+	run: func(a *app, args []string) error {
+		srv := server.NewItem(repo.NewItem(repo.DB), repo.NewSmartFolder(repo.DB), archive.NewStore(archive.CacheDir))
+		items := make([]*server.ItemForm, 0)
+		req := &server.ReqItemList{
+			Paginate:      server.Paginate{Page: 1, PageSize: 100},
+			ReqItemFilter: server.ReqItemFilter{Bookmark: ptr.To(true)},
+		}
+		for {
+			resp, err := srv.List(context.Background(), req)
+			if err != nil {
+				return err
+			}
+			items = append(items, resp.Items...)
+			if len(resp.Items) < req.PageSize || len(items) >= ptr.From(resp.Total) {
+				break
+			}
+			req.Page++
+		}
+
+		return a.print(items, func(w io.Writer) {
+			for _, item := range items {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", ptr.From(item.Feed.Name), ptr.From(item.Title), ptr.From(item.Link))
+			}
+		})
+	},
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/maintenance"
+)
+
+var dbVacuumCmd = &command{
+	name: "db vacuum",
+	help: "Rebuild the database to reclaim unused space",
+	run: func(a *app, args []string) error {
+		stats := repo.NewStats(repo.DB)
+		before, err := stats.GetDatabaseSize()
+		if err != nil {
+			return err
+		}
+		if err := repo.Vacuum(); err != nil {
+			return err
+		}
+		after, err := stats.GetDatabaseSize()
+		if err != nil {
+			return err
+		}
+
+		return a.print(map[string]int64{"size_before": before, "size_after": after}, func(w io.Writer) {
+			fmt.Fprintf(w, "database size: %d -> %d bytes\n", before, after)
+		})
+	},
+}
+
+var dbMigrationsCmd = &command{
+	name:      "db migrations",
+	help:      "Show applied and pending schema migrations without applying them",
+	noMigrate: true,
+	run: func(a *app, args []string) error {
+		states, err := repo.Migrations()
+		if err != nil {
+			return err
+		}
+		current, err := repo.SchemaVersion()
+		if err != nil {
+			return err
+		}
+
+		return a.print(states, func(w io.Writer) {
+			fmt.Fprintf(w, "schema version: %d (latest known: %d)\n", current, repo.LatestSchemaVersion())
+			for _, s := range states {
+				applied := "pending"
+				if s.AppliedAt != nil {
+					applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%4d  %-24s %s\n", s.Version, s.Name, applied)
+			}
+		})
+	},
+}
+
+var purgeAfterDays int
+
+var dbMaintenanceCmd = &command{
+	name: "db maintenance",
+	help: "Purge old soft-deleted rows, release free space and refresh query statistics",
+	setup: func(fs *flag.FlagSet) {
+		fs.IntVar(&purgeAfterDays, "purge-after-days", 30, "remove rows deleted more than this many days ago, 0 keeps them")
+	},
+	run: func(a *app, args []string) error {
+		if purgeAfterDays < 0 {
+			return errors.New("--purge-after-days must not be negative")
+		}
+		job := maintenance.NewJob(repo.NewMaintenance(repo.DB), repo.NewStats(repo.DB),
+			time.Duration(purgeAfterDays)*24*time.Hour)
+		run, err := job.RunOnce()
+		if err != nil {
+			return err
+		}
+
+		return a.print(map[string]int64{
+			"purged_rows":     run.PurgedRows,
+			"reclaimed_bytes": run.ReclaimedBytes,
+			"duration_ms":     run.Duration,
+		}, func(w io.Writer) {
+			fmt.Fprintf(w, "purged %d rows, reclaimed %d bytes in %dms\n", run.PurgedRows, run.ReclaimedBytes, run.Duration)
+		})
+	},
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Sudo-Ivan/fusionx/conf"
+	"github.com/Sudo-Ivan/fusionx/pkg/mail"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/digest"
+)
+
+var digestDryRun bool
+
+var digestSendCmd = &command{
+	name: "digest send",
+	help: "Send the email digest of the items added since the last one now",
+	setup: func(fs *flag.FlagSet) {
+		fs.BoolVar(&digestDryRun, "dry-run", false, "print the plain text digest instead of sending it")
+	},
+	run: func(a *app, args []string) error {
+		config, err := conf.Load()
+		if err != nil {
+			return err
+		}
+		if config.DigestSchedule == nil {
+			return errors.New("digests are disabled, set DIGEST_TO and the SMTP_* settings")
+		}
+		job := digest.NewJob(repo.NewDigest(repo.DB), repo.NewItem(repo.DB), func(ctx context.Context, msg *mail.Message) error {
+			return mail.Send(ctx, config.SMTP, msg)
+		}, config.Digest)
+
+		result := struct {
+			Items     int  `json:"items"`
+			Watermark uint `json:"watermark"`
+			Sent      bool `json:"sent"`
+		}{}
+		if digestDryRun {
+			d, err := job.Build()
+			if err != nil {
+				return err
+			}
+			if d != nil {
+				result.Items, result.Watermark = d.Items, d.Watermark
+			}
+			return a.print(result, func(w io.Writer) {
+				if d == nil {
+					fmt.Fprintln(w, "no new items")
+					return
+				}
+				fmt.Fprintf(w, "Subject: %s\n\n%s", d.Message.Subject, d.Message.Text)
+			})
+		}
+
+		run, err := job.RunOnce(context.Background())
+		if err != nil {
+			return err
+		}
+		if run != nil {
+			result.Items, result.Watermark, result.Sent = run.Items, run.Watermark, true
+		}
+		return a.print(result, func(w io.Writer) {
+			if run == nil {
+				fmt.Fprintln(w, "no new items, nothing sent")
+				return
+			}
+			fmt.Fprintf(w, "sent a digest of %d items\n", run.Items)
+		})
+	},
+}
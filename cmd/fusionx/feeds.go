@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/pull"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+func newPuller() *pull.Puller {
+	return pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB),
+		server.NewConfig(repo.NewConfig(repo.DB), false), repo.NewFetchLog(repo.DB))
+}
+
+// newFeedService returns the feed service without a job manager, so it must
+// not be used to refresh all feeds or create several at once.
+func newFeedService() *server.Feed {
+	return server.NewFeed(repo.NewFeed(repo.DB), repo.NewFetchLog(repo.DB), nil, "")
+}
+
+var feedsListFailing bool
+
+var feedsListCmd = &command{
+	name: "feeds list",
+	help: "List feeds",
+	setup: func(fs *flag.FlagSet) {
+		fs.BoolVar(&feedsListFailing, "failing", false, "only list feeds whose last fetch failed")
+	},
+	run: func(a *app, args []string) error {
+		resp, err := newFeedService().List(context.Background(), &server.ReqFeedList{})
+		if err != nil {
+			return err
+		}
+
+		feeds := make([]*server.FeedForm, 0, len(resp.Feeds))
+		for _, f := range resp.Feeds {
+			if feedsListFailing && ptr.From(f.Failure) == "" {
+				continue
+			}
+			feeds = append(feeds, f)
+		}
+
+		return a.print(feeds, func(w io.Writer) {
+			for _, f := range feeds {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", f.ID, ptr.From(f.Group.Name), ptr.From(f.Name), ptr.From(f.Link))
+				if failure := ptr.From(f.Failure); failure != "" {
+					fmt.Fprintf(w, "\tfailed %d time(s): %s\n", f.ConsecutiveFailures, failure)
+				}
+			}
+		})
+	},
+}
+
+var feedsAddOpts struct {
+	name    string
+	groupID uint
+	proxy   string
+	noPull  bool
+}
+
+var feedsAddCmd = &command{
+	name: "feeds add",
+	args: "<url>",
+	help: "Subscribe to a feed",
+	setup: func(fs *flag.FlagSet) {
+		fs.StringVar(&feedsAddOpts.name, "name", "", "feed name (defaults to the title of the feed)")
+		fs.UintVar(&feedsAddOpts.groupID, "group-id", 1, "group to add the feed to")
+		fs.StringVar(&feedsAddOpts.proxy, "proxy", "", "proxy used to fetch the feed")
+		fs.BoolVar(&feedsAddOpts.noPull, "no-pull", false, "don't fetch items right away")
+	},
+	run: func(a *app, args []string) error {
+		if len(args) != 1 {
+			return errors.New("exactly one feed URL is required")
+		}
+		link := args[0]
+		options := model.FeedRequestOptions{}
+		if feedsAddOpts.proxy != "" {
+			options.ReqProxy = &feedsAddOpts.proxy
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		name := feedsAddOpts.name
+		if name == "" {
+			title, err := client.NewFeedClient().FetchTitle(ctx, link, options)
+			if err != nil {
+				return fmt.Errorf("failed to fetch the feed title, use --name to set it: %w", err)
+			}
+			name = title
+		}
+		if _, err := repo.NewGroup(repo.DB).Get(feedsAddOpts.groupID); err != nil {
+			return fmt.Errorf("group %d: %w", feedsAddOpts.groupID, err)
+		}
+
+		feed := &model.Feed{
+			Name:               &name,
+			Link:               &link,
+			FeedRequestOptions: options,
+			GroupID:            feedsAddOpts.groupID,
+		}
+		if err := repo.NewFeed(repo.DB).Create([]*model.Feed{feed}); err != nil {
+			return err
+		}
+		if !feedsAddOpts.noPull {
+			if err := newPuller().PullOne(ctx, feed.ID); err != nil {
+				return err
+			}
+		}
+
+		return a.print(map[string]uint{"id": feed.ID}, func(w io.Writer) {
+			fmt.Fprintf(w, "added feed %d: %s\n", feed.ID, name)
+		})
+	},
+}
+
+var feedsRefreshID uint
+
+var feedsRefreshCmd = &command{
+	name: "feeds refresh",
+	help: "Fetch new items of one or all feeds, ignoring the refresh interval",
+	setup: func(fs *flag.FlagSet) {
+		fs.UintVar(&feedsRefreshID, "id", 0, "only refresh this feed")
+	},
+	run: func(a *app, args []string) error {
+		ctx := context.Background()
+		puller := newPuller()
+		if feedsRefreshID == 0 {
+			if err := puller.PullAll(ctx, true, nil); err != nil {
+				return err
+			}
+			// report the feeds that failed
+			feedsListFailing = true
+			return feedsListCmd.run(a, nil)
+		}
+
+		if err := puller.PullOne(ctx, feedsRefreshID); err != nil {
+			return err
+		}
+		feed, err := newFeedService().Get(ctx, &server.ReqFeedGet{ID: feedsRefreshID})
+		if err != nil {
+			return err
+		}
+		return a.print(feed, func(w io.Writer) {
+			if failure := ptr.From(feed.Failure); failure != "" {
+				fmt.Fprintf(w, "failed to refresh %s: %s\n", ptr.From(feed.Name), failure)
+				return
+			}
+			fmt.Fprintf(w, "refreshed %s\n", ptr.From(feed.Name))
+		})
+	},
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/importer"
+)
+
+var importOpts struct {
+	format  string
+	history bool
+}
+
+var importCmd = &command{
+	name: "import",
+	args: "<file>...",
+	help: "Import feeds, starred items and read history exported by another reader",
+	setup: func(fs *flag.FlagSet) {
+		fs.StringVar(&importOpts.format, "from", "", "reader that exported the files: "+strings.Join(importer.Formats, ", "))
+		fs.BoolVar(&importOpts.history, "history", false, "also import the items that aren't starred, marked read")
+	},
+	run: func(a *app, args []string) error {
+		if len(args) == 0 {
+			return errors.New("at least one exported file is required")
+		}
+		files := make([]importer.File, 0, len(args))
+		for _, name := range args {
+			// #nosec G304 - the files are chosen by the admin running the CLI
+			content, err := os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			files = append(files, importer.File{Name: name, Content: content})
+		}
+		data, err := importer.Parse(importOpts.format, files)
+		if errors.Is(err, importer.ErrUnknownFormat) {
+			return fmt.Errorf("--from must be one of %s", strings.Join(importer.Formats, ", "))
+		}
+		if err != nil {
+			return err
+		}
+
+		im := importer.NewImporter(repo.NewFeed(repo.DB), repo.NewGroup(repo.DB), repo.NewItem(repo.DB))
+		res, err := im.Run(context.Background(), data, importer.Options{History: importOpts.history}, nil)
+		if err != nil {
+			return err
+		}
+
+		return a.print(res, func(w io.Writer) {
+			fmt.Fprintf(w, "created %d group(s) and %d feed(s), imported %d bookmark(s) and %d read item(s)\n",
+				res.Groups, res.Feeds, res.Bookmarks, res.History)
+			if res.Feeds > 0 {
+				fmt.Fprintln(w, `run "fusionx feeds refresh" to fetch the items of the new feeds`)
+			}
+		})
+	},
+}
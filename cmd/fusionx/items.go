@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/pull"
+)
+
+var itemsRepairFeedID uint
+
+var itemsRepairCmd = &command{
+	name: "items repair",
+	help: "Re-key stored items for their feed's identity strategy and merge duplicates",
+	setup: func(fs *flag.FlagSet) {
+		fs.UintVar(&itemsRepairFeedID, "feed-id", 0, "only repair the items of this feed")
+	},
+	run: func(a *app, args []string) error {
+		feedRepo := repo.NewFeed(repo.DB)
+		var feeds []*model.Feed
+		if itemsRepairFeedID != 0 {
+			feed, err := feedRepo.Get(itemsRepairFeedID)
+			if err != nil {
+				return err
+			}
+			feeds = append(feeds, feed)
+		} else {
+			var err error
+			feeds, err = feedRepo.List(nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		type feedResult struct {
+			ID   uint   `json:"id"`
+			Name string `json:"name"`
+			pull.IdentityRepairResult
+		}
+		results := make([]feedResult, 0, len(feeds))
+		itemRepo := repo.NewItem(repo.DB)
+		for _, feed := range feeds {
+			res, err := pull.RepairItemIdentity(itemRepo, feed)
+			if err != nil {
+				return fmt.Errorf("feed %d: %w", feed.ID, err)
+			}
+			results = append(results, feedResult{ID: feed.ID, Name: ptr.From(feed.Name), IdentityRepairResult: res})
+		}
+
+		return a.print(results, func(w io.Writer) {
+			changed := 0
+			for _, r := range results {
+				if r.Rekeyed == 0 && r.Merged == 0 {
+					continue
+				}
+				changed++
+				fmt.Fprintf(w, "%d\t%s\tre-keyed %d, merged %d duplicates\n", r.ID, r.Name, r.Rekeyed, r.Merged)
+			}
+			fmt.Fprintf(w, "checked %d feeds, %d changed\n", len(results), changed)
+		})
+	},
+}
@@ -0,0 +1,189 @@
+// Command fusionx is the admin command-line interface of fusion. It works on
+// the same database as the server and can be used while the server is running.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/conf"
+	"github.com/Sudo-Ivan/fusionx/repo"
+
+	"gorm.io/gorm/logger"
+)
+
+type command struct {
+	name  string
+	args  string
+	help  string
+	run   func(a *app, args []string) error
+	setup func(fs *flag.FlagSet)
+	// noMigrate opens the database without applying pending migrations.
+	noMigrate bool
+	// noDB doesn't open the database, e.g. to replace it.
+	noDB bool
+}
+
+var commands = []*command{
+	feedsListCmd,
+	feedsAddCmd,
+	feedsRefreshCmd,
+	itemsRepairCmd,
+	opmlImportCmd,
+	importCmd,
+	bookmarksExportCmd,
+	digestSendCmd,
+	passwordSetCmd,
+	passwordClearCmd,
+	dbVacuumCmd,
+	dbMigrationsCmd,
+	dbMaintenanceCmd,
+	backupCreateCmd,
+	backupRestoreCmd,
+}
+
+// app holds the options shared by all commands.
+type app struct {
+	dbPath   string
+	dbDriver string
+	json     bool
+	out      io.Writer
+}
+
+// flags returns a flag set that also accepts the global flags, so that they
+// can be given before or after the command name.
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&a.dbPath, "db", a.dbPath, "path or DSN of the database (defaults to DB from the environment)")
+	fs.StringVar(&a.dbDriver, "db-driver", a.dbDriver, "database driver, sqlite or postgres (detected from --db by default)")
+	fs.BoolVar(&a.json, "json", a.json, "print output as JSON")
+	return fs
+}
+
+// resolveDB sets the database from the environment unless --db is given.
+func (a *app) resolveDB() error {
+	if a.dbPath != "" {
+		var err error
+		a.dbDriver, err = repo.ResolveDriver(a.dbDriver, a.dbPath)
+		return err
+	}
+	config, err := conf.Load()
+	if err != nil {
+		return err
+	}
+	a.dbPath = config.DB
+	a.dbDriver = config.DBDriver
+	return nil
+}
+
+func (a *app) openDB(migrate bool) error {
+	// keep stdout clean for the command output
+	repo.Logger = logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: 200 * time.Millisecond,
+		LogLevel:      logger.Error,
+	})
+	if !migrate {
+		return repo.Open(a.dbDriver, a.dbPath)
+	}
+	repo.Init(a.dbDriver, a.dbPath)
+	return nil
+}
+
+// print writes v as JSON when --json is set, and calls text otherwise.
+func (a *app) print(v any, text func(w io.Writer)) error {
+	if a.json {
+		enc := json.NewEncoder(a.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(a.out)
+	return nil
+}
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	})))
+
+	a := &app{out: os.Stdout}
+	global := a.flags("fusionx")
+	global.Usage = func() { usage(global.Output()) }
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	args := global.Args()
+	cmd, rest := findCommand(args)
+	if cmd == nil {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	fs := a.flags(cmd.name)
+	if cmd.setup != nil {
+		cmd.setup(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: fusionx %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	if err := a.resolveDB(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if !cmd.noDB {
+		if err := a.openDB(!cmd.noMigrate); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+	err := cmd.run(a, fs.Args())
+	if !cmd.noDB {
+		if closeErr := repo.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func findCommand(args []string) (*command, []string) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):]
+		}
+	}
+	return nil, nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: fusionx [--db path] [--db-driver name] [--json] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", c.name, c.help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "fusionx <command> --help" for the flags of a command.`)
+}
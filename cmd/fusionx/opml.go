@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+var opmlImportCmd = &command{
+	name: "opml import",
+	args: "<file>",
+	help: "Import feeds from an OPML file, creating missing groups",
+	run: func(a *app, args []string) error {
+		if len(args) != 1 {
+			return errors.New("exactly one OPML file is required")
+		}
+		// #nosec G304 - the file is chosen by the admin running the CLI
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		feeds, err := opml.Parse(f)
+		if err != nil {
+			return fmt.Errorf("invalid OPML: %w", err)
+		}
+
+		groupRepo := repo.NewGroup(repo.DB)
+		groups, err := groupRepo.All()
+		if err != nil {
+			return err
+		}
+		groupIDs := make(map[string]uint, len(groups))
+		for _, g := range groups {
+			groupIDs[ptr.From(g.Name)] = g.ID
+		}
+
+		data := make([]*model.Feed, 0, len(feeds))
+		for _, f := range feeds {
+			groupID := uint(1)
+			if f.Group != "" {
+				id, ok := groupIDs[f.Group]
+				if !ok {
+					group := &model.Group{Name: ptr.To(f.Group)}
+					if err := groupRepo.Create(group); err != nil {
+						return fmt.Errorf("create group %q: %w", f.Group, err)
+					}
+					id = group.ID
+					groupIDs[f.Group] = id
+				}
+				groupID = id
+			}
+			data = append(data, &model.Feed{
+				Name:    ptr.To(f.Name),
+				Link:    ptr.To(f.Link),
+				GroupID: groupID,
+			})
+		}
+
+		if len(data) > 0 {
+			if err := repo.NewFeed(repo.DB).Create(data); err != nil {
+				return err
+			}
+		}
+
+		return a.print(map[string]int{"imported": len(data)}, func(w io.Writer) {
+			fmt.Fprintf(w, "imported %d feed(s), run \"fusionx feeds refresh\" to fetch their items\n", len(data))
+		})
+	},
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/server"
+)
+
+var passwordSetCmd = &command{
+	name: "password set",
+	help: "Set the web UI password, read from stdin. It overrides PASSWORD after a server restart",
+	run: func(a *app, args []string) error {
+		if !a.json {
+			fmt.Fprint(os.Stderr, "New password: ")
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return errors.New("password must not be empty")
+		}
+
+		if err := server.NewConfig(repo.NewConfig(repo.DB), false).SetPassword(password); err != nil {
+			return err
+		}
+		return a.print(map[string]bool{"ok": true}, func(w io.Writer) {
+			fmt.Fprintln(w, "password updated, restart the server to apply it")
+		})
+	},
+}
+
+var passwordClearCmd = &command{
+	name: "password clear",
+	help: "Remove the password set by the CLI, so that PASSWORD applies again",
+	run: func(a *app, args []string) error {
+		if err := server.NewConfig(repo.NewConfig(repo.DB), false).SetPassword(""); err != nil {
+			return err
+		}
+		return a.print(map[string]bool{"ok": true}, func(w io.Writer) {
+			fmt.Fprintln(w, "password cleared, restart the server to apply it")
+		})
+	},
+}
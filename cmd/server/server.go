@@ -1,21 +1,45 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"net/http"
 	_ "net/http/pprof" // #nosec G108 - pprof is only enabled in debug mode for development
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"log/slog"
 
 	"github.com/Sudo-Ivan/fusionx/api"
 	"github.com/Sudo-Ivan/fusionx/conf"
+	"github.com/Sudo-Ivan/fusionx/pkg/mail"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
 	"github.com/Sudo-Ivan/fusionx/repo"
 	"github.com/Sudo-Ivan/fusionx/server"
+	"github.com/Sudo-Ivan/fusionx/service/archive"
+	"github.com/Sudo-Ivan/fusionx/service/backup"
 	"github.com/Sudo-Ivan/fusionx/service/demo"
+	"github.com/Sudo-Ivan/fusionx/service/digest"
+	"github.com/Sudo-Ivan/fusionx/service/favicon"
+	"github.com/Sudo-Ivan/fusionx/service/integration"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+	"github.com/Sudo-Ivan/fusionx/service/maintenance"
+	"github.com/Sudo-Ivan/fusionx/service/newsletter"
 	"github.com/Sudo-Ivan/fusionx/service/pull"
+	"github.com/Sudo-Ivan/fusionx/service/unread"
 )
 
+// drainTimeout is how long in-flight feed pulls, jobs and newsletter
+// deliveries may take to finish on shutdown before the database is closed.
+const drainTimeout = 20 * time.Second
+
 func main() {
+	showMigrations := flag.Bool("migrations", false, "print the schema migration status and exit without applying them")
+	flag.Parse()
+
 	l := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
@@ -41,7 +65,22 @@ func main() {
 		slog.Error("failed to load configuration", "error", err)
 		return
 	}
-	repo.Init(config.DB)
+	if *showMigrations {
+		if err := printMigrations(config.DBDriver, config.DB); err != nil {
+			slog.Error("failed to read migrations", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	repo.Init(config.DBDriver, config.DB)
+
+	if hash, err := server.NewConfig(repo.NewConfig(repo.DB), config.DemoMode).PasswordHash(); err != nil {
+		slog.Error("failed to load password from database", "error", err)
+		return
+	} else if hash != nil {
+		slog.Info("using the password set by the CLI")
+		config.PasswordHash = hash
+	}
 
 	if config.DemoMode && config.DemoModeFeeds != "" {
 		seeder := demo.NewFeedSeeder(repo.NewFeed(repo.DB), repo.NewGroup(repo.DB))
@@ -50,16 +89,147 @@ func main() {
 		}
 	}
 
-	go pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), server.NewConfig(repo.NewConfig(repo.DB), config.DemoMode)).Run()
+	secretBox, err := newSecretBox(config)
+	if err != nil {
+		slog.Error("failed to set up the secret key", "error", err)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	puller := pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), server.NewConfig(repo.NewConfig(repo.DB), config.DemoMode), repo.NewFetchLog(repo.DB))
+	go puller.Run(ctx)
+	go unread.NewCounter(repo.NewItem(repo.DB)).Run(ctx)
+	if secretBox != nil {
+		go integration.NewAutoSender(repo.NewIntegration(repo.DB), repo.NewItem(repo.DB), secretBox).Run(ctx)
+	}
+
+	if config.ArchiveBookmarks {
+		go archive.NewArchiver(archive.NewStore(archive.CacheDir), repo.NewItem(repo.DB)).Run(ctx)
+	}
+
+	var newsletterDomain string
+	// newsletterDone is closed when the newsletter server has stopped and
+	// the messages it was receiving are stored
+	newsletterDone := make(chan struct{})
+	if config.NewsletterAddr != "" {
+		newsletterDomain = config.NewsletterDomain
+		receiver := newsletter.NewReceiver(newsletterDomain, repo.NewFeed(repo.DB), repo.NewItem(repo.DB))
+		go func() {
+			defer close(newsletterDone)
+			if err := newsletter.NewServer(receiver).ListenAndServe(ctx, config.NewsletterAddr); err != nil {
+				slog.Error("newsletter server", "error", err)
+			}
+		}()
+	} else {
+		close(newsletterDone)
+	}
+
+	jobManager := jobs.NewManager(ctx, repo.NewJob(repo.DB), config.JobConcurrency)
+	if err := jobManager.Interrupt(); err != nil {
+		slog.Warn("failed to mark interrupted jobs", "error", err)
+	}
+
+	if config.MaintenanceInterval > 0 {
+		job := maintenance.NewJob(repo.NewMaintenance(repo.DB), repo.NewStats(repo.DB), config.PurgeAfter)
+		go job.Run(ctx, config.MaintenanceInterval)
+	}
+
+	if config.DigestSchedule != nil {
+		job := digest.NewJob(repo.NewDigest(repo.DB), repo.NewItem(repo.DB), func(ctx context.Context, msg *mail.Message) error {
+			return mail.Send(ctx, config.SMTP, msg)
+		}, config.Digest)
+		go job.Run(ctx, config.DigestSchedule)
+	}
+
+	if config.BackupDir != "" {
+		go backup.NewScheduler(config.BackupDir, config.BackupInterval, config.BackupKeep, favicon.CacheDir).Run(ctx)
+	}
 
-	api.Run(api.Params{
+	api.Run(ctx, api.Params{
 		Host:            config.Host,
 		Port:            config.Port,
 		PasswordHash:    config.PasswordHash,
 		UseSecureCookie: config.SecureCookie,
 		TLSCert:         config.TLSCert,
 		TLSKey:          config.TLSKey,
-		DBPath:          config.DB,
 		DemoMode:        config.DemoMode,
+		MetricsEnabled:  config.MetricsEnabled,
+		MetricsAddr:     config.MetricsAddr,
+		MetricsToken:    config.MetricsToken,
+		Puller:          puller,
+		Jobs:            jobManager,
+		PurgeAfter:      config.PurgeAfter,
+		PublicURL:       config.PublicURL,
+		SecretBox:       secretBox,
+
+		NewsletterDomain: newsletterDomain,
 	})
+
+	// api.Run also returns when the server fails to start, so make sure the
+	// scheduler is stopped either way.
+	stop()
+
+	slog.Info("waiting for in-flight feed pulls, jobs and newsletter deliveries")
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := pull.Drain(drainCtx); err != nil {
+		slog.Warn("feed pulls did not finish in time", "error", err)
+	}
+	if err := jobManager.Wait(drainCtx); err != nil {
+		slog.Warn("jobs did not finish in time", "error", err)
+	}
+	select {
+	case <-newsletterDone:
+	case <-drainCtx.Done():
+		slog.Warn("newsletter deliveries did not finish in time")
+	}
+
+	if err := repo.Close(); err != nil {
+		slog.Error("failed to close database", "error", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+// newSecretBox returns the box for the credentials of integrations. The key
+// is kept out of the database, so that a copy of the database alone can't
+// decrypt them.
+func newSecretBox(config conf.Conf) (*secret.Box, error) {
+	key := config.SecretKey
+	if key == "" {
+		var err error
+		if key, err = secret.LoadOrCreateKey(config.SecretKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	return secret.NewBox(key)
+}
+
+func printMigrations(driver, dsn string) error {
+	if err := repo.Open(driver, dsn); err != nil {
+		return err
+	}
+	defer repo.Close() // #nosec G104 - read only
+
+	states, err := repo.Migrations()
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending++
+			fmt.Printf("pending: %d %s\n", s.Version, s.Name)
+		}
+	}
+	current, err := repo.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version %d, latest known %d, %d pending\n", current, repo.LatestSchemaVersion(), pending)
+	if current > repo.LatestSchemaVersion() {
+		return repo.ErrSchemaTooNew
+	}
+	return nil
 }
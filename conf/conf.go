@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"time"
 
 	"github.com/Sudo-Ivan/fusionx/auth"
+	"github.com/Sudo-Ivan/fusionx/pkg/mail"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/digest"
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
@@ -22,11 +27,46 @@ type Conf struct {
 	Port          int
 	PasswordHash  *auth.HashedPassword
 	DB            string
+	DBDriver      string
 	SecureCookie  bool
 	TLSCert       string
 	TLSKey        string
 	DemoMode      bool
 	DemoModeFeeds string
+	// PublicURL is the address users reach the web UI at, for links in
+	// emails, published feeds and shared items.
+	PublicURL string
+	// SecretKey encrypts the credentials of integrations. When empty, the
+	// key is read from SecretKeyFile, which is created with a random key
+	// on first start.
+	SecretKey     string
+	SecretKeyFile string
+	// ArchiveBookmarks snapshots the pages of items when they are
+	// bookmarked.
+	ArchiveBookmarks bool
+	// NewsletterAddr is where newsletters are received over SMTP or LMTP,
+	// empty to disable them. NewsletterDomain is the domain of their
+	// addresses.
+	NewsletterAddr   string
+	NewsletterDomain string
+
+	MetricsEnabled bool
+	MetricsAddr    string
+	MetricsToken   string
+
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+
+	MaintenanceInterval time.Duration
+	PurgeAfter          time.Duration
+
+	JobConcurrency int
+
+	SMTP mail.Config
+	// DigestSchedule is nil when digests are disabled.
+	DigestSchedule *digest.Schedule
+	Digest         digest.Options
 }
 
 func Load() (Conf, error) {
@@ -43,11 +83,49 @@ func Load() (Conf, error) {
 		Port          int    `env:"PORT" envDefault:"8080"`
 		Password      string `env:"PASSWORD"`
 		DB            string `env:"DB" envDefault:"fusion.db"`
+		DBDriver      string `env:"DB_DRIVER"`
 		SecureCookie  bool   `env:"SECURE_COOKIE" envDefault:"false"`
 		TLSCert       string `env:"TLS_CERT"`
 		TLSKey        string `env:"TLS_KEY"`
 		DemoMode      bool   `env:"DEMO_MODE" envDefault:"false"`
 		DemoModeFeeds string `env:"DEMO_MODE_FEEDS"`
+		PublicURL     string `env:"PUBLIC_URL"`
+		SecretKey     string `env:"SECRET_KEY"`
+		SecretKeyFile string `env:"SECRET_KEY_FILE" envDefault:"secret.key"`
+
+		ArchiveBookmarks bool `env:"ARCHIVE_BOOKMARKS" envDefault:"false"`
+
+		NewsletterAddr   string `env:"NEWSLETTER_ADDR"`
+		NewsletterDomain string `env:"NEWSLETTER_DOMAIN"`
+
+		MetricsEnabled bool   `env:"METRICS_ENABLED" envDefault:"false"`
+		MetricsAddr    string `env:"METRICS_ADDR"`
+		MetricsToken   string `env:"METRICS_TOKEN"`
+
+		BackupDir      string        `env:"BACKUP_DIR"`
+		BackupInterval time.Duration `env:"BACKUP_INTERVAL" envDefault:"24h"`
+		BackupKeep     int           `env:"BACKUP_KEEP" envDefault:"7"`
+
+		MaintenanceInterval time.Duration `env:"MAINTENANCE_INTERVAL" envDefault:"24h"`
+		PurgeAfterDays      int           `env:"PURGE_AFTER_DAYS" envDefault:"30"`
+
+		JobConcurrency int `env:"JOB_CONCURRENCY" envDefault:"2"`
+
+		SMTPHost     string `env:"SMTP_HOST"`
+		SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
+		SMTPUsername string `env:"SMTP_USERNAME"`
+		SMTPPassword string `env:"SMTP_PASSWORD"`
+		SMTPTLS      string `env:"SMTP_TLS" envDefault:"starttls"`
+		SMTPFrom     string `env:"SMTP_FROM"`
+
+		DigestTo        []string `env:"DIGEST_TO"`
+		DigestTimes     string   `env:"DIGEST_TIMES" envDefault:"07:00"`
+		DigestDays      string   `env:"DIGEST_DAYS"`
+		DigestTimezone  string   `env:"DIGEST_TIMEZONE"`
+		DigestBookmarks bool     `env:"DIGEST_BOOKMARKS" envDefault:"false"`
+		DigestFeeds     []uint   `env:"DIGEST_FEEDS"`
+		DigestGroups    []uint   `env:"DIGEST_GROUPS"`
+		DigestMaxItems  int      `env:"DIGEST_MAX_ITEMS" envDefault:"100"`
 	}
 	if err := env.Parse(&conf); err != nil {
 		return Conf{}, err
@@ -63,6 +141,11 @@ func Load() (Conf, error) {
 		pwHash = &hash
 	}
 
+	dbDriver, err := repo.ResolveDriver(conf.DBDriver, conf.DB)
+	if err != nil {
+		return Conf{}, err
+	}
+
 	if (conf.TLSCert == "") != (conf.TLSKey == "") {
 		return Conf{}, errors.New("missing TLS cert or key file")
 	}
@@ -70,15 +153,107 @@ func Load() (Conf, error) {
 		conf.SecureCookie = true
 	}
 
+	if conf.BackupDir != "" {
+		if dbDriver != repo.DriverSQLite {
+			return Conf{}, errors.New("scheduled backups are only supported for SQLite")
+		}
+		if conf.BackupInterval <= 0 || conf.BackupKeep < 1 {
+			return Conf{}, errors.New("BACKUP_INTERVAL and BACKUP_KEEP must be positive")
+		}
+	}
+
+	if conf.MaintenanceInterval < 0 || conf.PurgeAfterDays < 0 {
+		return Conf{}, errors.New("MAINTENANCE_INTERVAL and PURGE_AFTER_DAYS must not be negative")
+	}
+
+	if conf.JobConcurrency < 1 {
+		return Conf{}, errors.New("JOB_CONCURRENCY must be positive")
+	}
+
+	if conf.MetricsEnabled && conf.MetricsAddr == "" && conf.MetricsToken == "" {
+		// the main server is usually public
+		return Conf{}, errors.New("serving metrics on the main server needs METRICS_TOKEN, or set METRICS_ADDR")
+	}
+
+	if conf.NewsletterAddr != "" && conf.NewsletterDomain == "" {
+		return Conf{}, errors.New("receiving newsletters needs NEWSLETTER_DOMAIN")
+	}
+
+	if !slices.Contains(mail.TLSModes, conf.SMTPTLS) {
+		return Conf{}, fmt.Errorf("SMTP_TLS must be one of %v", mail.TLSModes)
+	}
+	var digestSchedule *digest.Schedule
+	if len(conf.DigestTo) > 0 {
+		if conf.SMTPHost == "" || conf.SMTPFrom == "" {
+			return Conf{}, errors.New("digests need SMTP_HOST and SMTP_FROM")
+		}
+		if conf.DigestMaxItems < 1 {
+			return Conf{}, errors.New("DIGEST_MAX_ITEMS must be positive")
+		}
+		digestSchedule, err = digest.ParseSchedule(conf.DigestTimes, conf.DigestDays, conf.DigestTimezone)
+		if err != nil {
+			return Conf{}, fmt.Errorf("invalid digest schedule: %w", err)
+		}
+	}
+
 	return Conf{
 		Host:          conf.Host,
 		Port:          conf.Port,
 		PasswordHash:  pwHash,
 		DB:            conf.DB,
+		DBDriver:      dbDriver,
 		SecureCookie:  conf.SecureCookie,
 		TLSCert:       conf.TLSCert,
 		TLSKey:        conf.TLSKey,
 		DemoMode:      conf.DemoMode,
 		DemoModeFeeds: conf.DemoModeFeeds,
+		PublicURL:     conf.PublicURL,
+		SecretKey:     conf.SecretKey,
+		SecretKeyFile: conf.SecretKeyFile,
+
+		ArchiveBookmarks: conf.ArchiveBookmarks,
+
+		NewsletterAddr:   conf.NewsletterAddr,
+		NewsletterDomain: conf.NewsletterDomain,
+
+		MetricsEnabled: conf.MetricsEnabled,
+		MetricsAddr:    conf.MetricsAddr,
+		MetricsToken:   conf.MetricsToken,
+
+		BackupDir:      conf.BackupDir,
+		BackupInterval: conf.BackupInterval,
+		BackupKeep:     conf.BackupKeep,
+
+		MaintenanceInterval: conf.MaintenanceInterval,
+		PurgeAfter:          time.Duration(conf.PurgeAfterDays) * 24 * time.Hour,
+
+		JobConcurrency: conf.JobConcurrency,
+
+		SMTP: mail.Config{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			TLS:      conf.SMTPTLS,
+			From:     conf.SMTPFrom,
+		},
+		DigestSchedule: digestSchedule,
+		Digest: digest.Options{
+			To:        conf.DigestTo,
+			Bookmarks: conf.DigestBookmarks,
+			FeedIDs:   conf.DigestFeeds,
+			GroupIDs:  conf.DigestGroups,
+			MaxItems:  conf.DigestMaxItems,
+			Location:  location(digestSchedule),
+			AppURL:    conf.PublicURL,
+		},
 	}, nil
 }
+
+// location returns the time zone of the digest schedule.
+func location(schedule *digest.Schedule) *time.Location {
+	if schedule == nil {
+		return time.Local
+	}
+	return schedule.Location()
+}
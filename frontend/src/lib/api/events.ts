@@ -0,0 +1,52 @@
+import { invalidate } from '$app/navigation';
+import { globalState, setGlobalSmartFolders } from '$lib/state.svelte';
+import { allSmartFolders } from './smart_folder';
+
+// connectEvents follows the server's event stream and applies the changes to
+// the global state. It returns a function that closes the stream.
+export function connectEvents() {
+	const source = new EventSource('/api/events');
+
+	let connected = false;
+	source.addEventListener('open', () => {
+		// events sent while disconnected are lost, so reload
+		if (connected) {
+			invalidate('app:feeds');
+		}
+		connected = true;
+	});
+
+	source.addEventListener('unread_counts.changed', (e) => {
+		const { counts } = JSON.parse(e.data) as { counts: Record<string, number> };
+		for (const [id, count] of Object.entries(counts)) {
+			const feed = globalState.feeds.find((f) => f.id === Number(id));
+			if (feed) {
+				feed.unread_count = count;
+			}
+		}
+		// smart folders may overlap, so their counts are reloaded
+		if (globalState.smartFolders.length > 0) {
+			allSmartFolders()
+				.then(setGlobalSmartFolders)
+				.catch(() => {});
+		}
+	});
+
+	source.addEventListener('feed.fetch_failed', (e) => {
+		const { feed_id, error } = JSON.parse(e.data) as { feed_id: number; error: string };
+		const feed = globalState.feeds.find((f) => f.id === feed_id);
+		if (feed) {
+			feed.failure = error;
+		}
+	});
+
+	source.addEventListener('feed.fetch_finished', (e) => {
+		const { feed_id } = JSON.parse(e.data) as { feed_id: number };
+		const feed = globalState.feeds.find((f) => f.id === feed_id);
+		if (feed) {
+			feed.failure = '';
+		}
+	});
+
+	return () => source.close();
+}
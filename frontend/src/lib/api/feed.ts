@@ -1,5 +1,5 @@
 import { api } from './api';
-import type { Feed } from './model';
+import type { Feed, ItemIdentity, ItemMapping } from './model';
 
 export type FeedListFiler = {
 	have_unread?: boolean;
@@ -43,6 +43,8 @@ export type FeedCreateForm = {
 		name: string;
 		link: string;
 		request_options: FeedRequestOptions;
+		kind?: 'feed' | 'scrape' | 'json';
+		mapping?: ItemMapping;
 	}[];
 };
 
@@ -52,7 +54,39 @@ export async function createFeed(data: FeedCreateForm) {
 			timeout: 20000,
 			json: data
 		})
-		.json<{ ids: number[] }>();
+		.json<{ ids: number[]; job_id?: number }>();
+}
+
+export type PreviewItem = {
+	title: string;
+	link: string;
+	pub_date: Date | null;
+	content: string;
+};
+
+// previewFeed reads the items of a scraped page or a JSON API without saving
+// it.
+export async function previewFeed(
+	link: string,
+	kind: 'scrape' | 'json',
+	mapping: ItemMapping,
+	options: FeedRequestOptions
+) {
+	const resp = await api
+		.post('feeds/preview', {
+			timeout: 30000,
+			json: { link: link, kind: kind, mapping: mapping, request_options: options }
+		})
+		.json<{ items: PreviewItem[] }>();
+	return resp.items;
+}
+
+export async function createNewsletter(data: { name: string; group_id: number }) {
+	return await api
+		.post('feeds/newsletters', {
+			json: data
+		})
+		.json<{ id: number; address: string }>();
 }
 
 export type FeedUpdateForm = {
@@ -60,7 +94,10 @@ export type FeedUpdateForm = {
 	link?: string;
 	suspended?: boolean;
 	req_proxy?: string;
+	mark_unread_on_update?: boolean;
+	item_identity?: ItemIdentity;
 	group_id?: number;
+	mapping?: ItemMapping;
 };
 
 export async function updateFeed(id: number, data: FeedUpdateForm) {
@@ -73,12 +110,17 @@ export async function deleteFeed(id: number) {
 	return await api.delete('feeds/' + id);
 }
 
+// refreshFeeds returns the ID of the background job when refreshing all feeds.
 export async function refreshFeeds(options: { id?: number; all?: boolean }) {
-	return await api.post('feeds/refresh', {
+	const resp = await api.post('feeds/refresh', {
 		timeout: 20000,
 		json: {
 			id: options.id,
 			all: options.all
 		}
 	});
+	if (resp.status !== 202) {
+		return {};
+	}
+	return await resp.json<{ job_id?: number }>();
 }
@@ -0,0 +1,16 @@
+import { api } from './api';
+
+export type ImportFormat = 'miniflux' | 'freshrss' | 'greader' | 'ttrss';
+
+// importFromReader uploads the files exported by another reader and returns
+// the ID of the job that imports them. With history, the items that aren't
+// starred are imported too, marked read.
+export async function importFromReader(format: ImportFormat, files: FileList, history: boolean) {
+	const body = new FormData();
+	body.set('format', format);
+	body.set('history', String(history));
+	for (const f of files) {
+		body.append('files', f);
+	}
+	return await api.post('imports', { body }).json<{ job_id: number }>();
+}
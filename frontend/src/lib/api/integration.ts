@@ -0,0 +1,37 @@
+import { api } from './api';
+import type { Integration, IntegrationKind } from './model';
+
+export type IntegrationForm = Pick<Integration, 'name' | 'settings' | 'send_bookmarks'>;
+
+export async function allIntegrations() {
+	const resp = await api.get('integrations').json<{ integrations: Integration[] }>();
+	return resp.integrations;
+}
+
+export async function integrationKinds() {
+	const resp = await api.get('integrations/kinds').json<{ kinds: IntegrationKind[] }>();
+	return resp.kinds;
+}
+
+export async function createIntegration(data: IntegrationForm & { kind: string }) {
+	return await api
+		.post('integrations', {
+			json: data
+		})
+		.json<{ id: number }>();
+}
+
+// updateIntegration keeps the current values of empty secret settings
+export async function updateIntegration(id: number, data: IntegrationForm) {
+	return await api.patch('integrations/' + id, {
+		json: data
+	});
+}
+
+export async function deleteIntegration(id: number) {
+	return await api.delete('integrations/' + id);
+}
+
+export async function sendItem(itemId: number, integrationId: number) {
+	return await api.post('items/' + itemId + '/send/' + integrationId);
+}
@@ -11,6 +11,9 @@ export type ListFilter = {
 	group_id?: number;
 	unread?: boolean;
 	bookmark?: boolean;
+	smart_folder_id?: number;
+	collapse?: boolean;
+	sort?: 'newest' | 'oldest' | 'feed';
 };
 
 export async function listItems(options?: ListFilter) {
@@ -38,6 +41,10 @@ export function parseURLtoFilter(params: URLSearchParams, override?: ListFilter)
 	if (unread) filter.unread = unread === 'true';
 	const bookmark = params.get('bookmark');
 	if (bookmark) filter.bookmark = bookmark === 'true';
+	const sort = params.get('sort');
+	if (sort === 'newest' || sort === 'oldest' || sort === 'feed') filter.sort = sort;
+	const collapse = params.get('collapse');
+	if (collapse) filter.collapse = collapse === 'true';
 	return { ...filter, ...override };
 }
 
@@ -56,10 +63,34 @@ export async function getItem(id: number) {
 	return api.get('items/' + id).json<Item>();
 }
 
-export async function updateUnread(ids: number[], unread: boolean) {
+export type ItemRevision = {
+	id: number;
+	replaced_at: Date;
+	title: string;
+	content: string;
+	diff: string;
+};
+
+export async function getItemRevisions(id: number) {
+	return api.get('items/' + id + '/revisions').json<{ revisions: ItemRevision[] }>();
+}
+
+// cluster also updates the other items of the same story
+export async function updateUnread(ids: number[], unread: boolean, cluster = false) {
 	return api.patch('items/-/unread', {
 		json: {
 			ids: ids,
+			unread: unread,
+			cluster: cluster
+		}
+	});
+}
+
+// updateSmartFolderUnread updates all items of a smart folder
+export async function updateSmartFolderUnread(smartFolderID: number, unread: boolean) {
+	return api.patch('items/-/unread', {
+		json: {
+			smart_folder_id: smartFolderID,
 			unread: unread
 		}
 	});
@@ -72,3 +103,17 @@ export async function updateBookmark(id: number, bookmark: boolean) {
 		}
 	});
 }
+
+export type ExportFormat = 'json' | 'html' | 'markdown' | 'epub';
+
+// exportItems downloads the items matching the filter as a file
+export async function exportItems(format: ExportFormat, options?: ListFilter) {
+	options = JSON.parse(JSON.stringify({ ...options, page: undefined, page_size: undefined }));
+	const resp = await api.get('items/export', {
+		searchParams: { ...options, format: format },
+		timeout: false
+	});
+	const disposition = resp.headers.get('Content-Disposition') ?? '';
+	const filename = /filename="?([^";]+)"?/.exec(disposition)?.[1] ?? 'fusion-export';
+	return { filename: filename, blob: await resp.blob() };
+}
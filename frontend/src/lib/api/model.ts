@@ -3,14 +3,102 @@ export type Group = {
 	name: string;
 };
 
+// SmartFolder is a saved search. Conditions that are left out don't limit
+// the items.
+export type SmartFolder = {
+	id: number;
+	name: string;
+	keyword?: string;
+	feed_ids?: number[];
+	group_ids?: number[];
+	unread?: boolean;
+	bookmark?: boolean;
+	unread_count: number;
+};
+
+export type PublishedFeedSource = 'group' | 'bookmarks' | 'smart_folder';
+
+export type PublishedFeed = {
+	id: number;
+	name: string;
+	source: PublishedFeedSource;
+	// the group or smart folder, 0 for bookmarks
+	source_id: number;
+	token: string;
+	urls: Record<'atom' | 'rss' | 'json', string>;
+};
+
+export type ItemShare = {
+	id: number;
+	item_id: number;
+	// null once the item is deleted
+	item_title: string | null;
+	url: string;
+	expires_at: Date | null;
+	expired: boolean;
+	created_at: Date;
+};
+
+export type IntegrationField = {
+	name: string;
+	label: string;
+	// secret fields are never returned by the API
+	secret: boolean;
+	required: boolean;
+	default?: string;
+};
+
+export type IntegrationKind = {
+	name: string;
+	label: string;
+	fields: IntegrationField[];
+};
+
+export type Integration = {
+	id: number;
+	name: string;
+	kind: string;
+	// settings without the secret fields
+	settings: Record<string, string>;
+	send_bookmarks: boolean;
+};
+
+export type ItemIdentity = 'auto' | 'link' | 'content';
+
+// feeds are pulled from their link, newsletters receive their items by email,
+// scraped pages are read with the CSS selectors of their mapping and JSON APIs
+// with its paths
+export type FeedKind = 'feed' | 'newsletter' | 'scrape' | 'json';
+
+// ItemMapping tells where the items and their fields are in a scraped page or
+// a JSON response. A selector may end with "@attr" to read an attribute
+// instead of the text, a path is keys separated by dots such as "data.items".
+export type ItemMapping = {
+	items: string;
+	id?: string;
+	title: string;
+	link?: string;
+	date?: string;
+	content?: string;
+};
+
 export type Feed = {
 	id: number;
 	name: string;
 	link: string;
+	kind: FeedKind;
+	// the email address of a newsletter, null for other feeds or when
+	// newsletters are disabled
+	address: string | null;
+	// the selectors of a scraped page or the paths of a JSON API, null for
+	// other feeds
+	mapping: ItemMapping | null;
 	failure: string;
 	updated_at: Date;
 	suspended: boolean;
 	req_proxy: string;
+	mark_unread_on_update: boolean;
+	item_identity: ItemIdentity;
 	unread_count: number;
 	consecutive_failures?: number;
 	group: Group;
@@ -26,4 +114,9 @@ export type Item = {
 	pub_date: Date;
 	updated_at: Date;
 	feed: Pick<Feed, 'id' | 'name' | 'link'>;
+	cluster_id?: number;
+	// other feeds with the same story, only set when listing with collapse
+	also_in?: Pick<Feed, 'id' | 'name' | 'link'>[];
+	// whether the page was archived, only set when getting a single item
+	archived?: boolean;
 };
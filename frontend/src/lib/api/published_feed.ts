@@ -0,0 +1,32 @@
+import { api } from './api';
+import type { PublishedFeed } from './model';
+
+export type PublishedFeedForm = Pick<PublishedFeed, 'name' | 'source' | 'source_id'>;
+
+export async function allPublishedFeeds() {
+	const resp = await api.get('published_feeds').json<{ published_feeds: PublishedFeed[] }>();
+	return resp.published_feeds;
+}
+
+export async function createPublishedFeed(data: PublishedFeedForm) {
+	return await api
+		.post('published_feeds', {
+			json: data
+		})
+		.json<{ id: number; token: string }>();
+}
+
+export async function updatePublishedFeed(id: number, data: PublishedFeedForm) {
+	return await api.patch('published_feeds/' + id, {
+		json: data
+	});
+}
+
+// rotatePublishedFeedToken replaces the secret token, the old URLs stop working
+export async function rotatePublishedFeedToken(id: number) {
+	return await api.post('published_feeds/' + id + '/token').json<{ token: string }>();
+}
+
+export async function deletePublishedFeed(id: number) {
+	return await api.delete('published_feeds/' + id);
+}
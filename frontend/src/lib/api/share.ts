@@ -0,0 +1,21 @@
+import { api } from './api';
+import type { ItemShare } from './model';
+
+// createShare creates a public link to an item, expiresAt is optional
+export async function createShare(itemId: number, expiresAt?: Date) {
+	return await api
+		.post('items/' + itemId + '/share', {
+			json: { expires_at: expiresAt }
+		})
+		.json<ItemShare>();
+}
+
+export async function listShares(itemId?: number) {
+	const searchParams = itemId ? { item_id: itemId } : undefined;
+	const resp = await api.get('shares', { searchParams }).json<{ shares: ItemShare[] }>();
+	return resp.shares;
+}
+
+export async function revokeShare(id: number) {
+	return await api.delete('shares/' + id);
+}
@@ -0,0 +1,28 @@
+import { api } from './api';
+import type { SmartFolder } from './model';
+
+export type SmartFolderForm = Omit<SmartFolder, 'id' | 'unread_count'>;
+
+export async function allSmartFolders() {
+	const resp = await api.get('smart_folders').json<{ smart_folders: SmartFolder[] }>();
+	return resp.smart_folders;
+}
+
+export async function createSmartFolder(data: SmartFolderForm) {
+	return await api
+		.post('smart_folders', {
+			json: data
+		})
+		.json<{ id: number }>();
+}
+
+// updateSmartFolder replaces the whole definition of the folder
+export async function updateSmartFolder(id: number, data: SmartFolderForm) {
+	return await api.patch('smart_folders/' + id, {
+		json: data
+	});
+}
+
+export async function deleteSmartFolder(id: number) {
+	return await api.delete('smart_folders/' + id);
+}
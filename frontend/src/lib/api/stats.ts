@@ -8,6 +8,11 @@ export type Stats = {
 	database_size: number;
 	last_feed_update: Date | null;
 	failed_feeds: number;
+	last_maintenance: Date | null;
+	purged_rows: number;
+	reclaimed_bytes: number;
+	archived_pages: number;
+	archive_size: number;
 };
 
 export async function getStats(): Promise<Stats> {
@@ -1,8 +1,9 @@
-import { type Feed, type Group } from './api/model';
+import { type Feed, type Group, type SmartFolder } from './api/model';
 
 export const globalState = $state({
 	groups: [] as Group[],
 	feeds: [] as Feed[],
+	smartFolders: [] as SmartFolder[],
 	demoMode: false,
 	readingPaneMode: 'default' as 'default' | '3pane' | 'drawer'
 });
@@ -15,6 +16,10 @@ export function setGlobalGroups(groups: Group[]) {
 	globalState.groups = groups;
 }
 
+export function setGlobalSmartFolders(folders: SmartFolder[]) {
+	globalState.smartFolders = folders;
+}
+
 export function setDemoMode(demoMode: boolean) {
 	globalState.demoMode = demoMode;
 }
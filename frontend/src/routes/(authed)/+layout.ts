@@ -1,11 +1,18 @@
 import { getConfig, getAppConfig } from '$lib/api/config';
 import { listFeeds } from '$lib/api/feed';
 import { allGroups } from '$lib/api/group';
-import { setDemoMode, setGlobalFeeds, setGlobalGroups, setReadingPaneMode } from '$lib/state.svelte';
+import { allSmartFolders } from '$lib/api/smart_folder';
+import {
+	setDemoMode,
+	setGlobalFeeds,
+	setGlobalGroups,
+	setGlobalSmartFolders,
+	setReadingPaneMode
+} from '$lib/state.svelte';
 import type { LayoutLoad } from './$types';
 
 export const load: LayoutLoad = async ({ depends }) => {
-	depends('app:feeds', 'app:groups', 'app:config');
+	depends('app:feeds', 'app:groups', 'app:smart_folders', 'app:config');
 
 	await Promise.all([
 		getConfig().then((config) => {
@@ -23,6 +30,9 @@ export const load: LayoutLoad = async ({ depends }) => {
 		}),
 		listFeeds().then((feeds) => {
 			setGlobalFeeds(feeds);
+		}),
+		allSmartFolders().then((folders) => {
+			setGlobalSmartFolders(folders);
 		})
 	]);
 
@@ -0,0 +1,24 @@
+import { listItems, parseURLtoFilter } from '$lib/api/item';
+import { allSmartFolders } from '$lib/api/smart_folder';
+import { error } from '@sveltejs/kit';
+import type { PageLoad } from './$types';
+
+export const prerender = false;
+
+export const load: PageLoad = async ({ url, params, depends }) => {
+	depends('app:page');
+
+	const id = parseInt(params.id);
+	const folder = allSmartFolders().then((folders) => {
+		const folder = folders.find((f) => f.id === id);
+		if (!folder) {
+			error(404, 'Smart folder not found');
+		}
+		return folder;
+	});
+	const filter = parseURLtoFilter(url.searchParams, {
+		smart_folder_id: id
+	});
+	const items = listItems(filter);
+	return { folder, items: items };
+};
@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL files used by versioned schema
+// migrations. The migrations themselves are registered in the repo package.
+//
+// A file named NNNN_name.sql is used for every driver, unless a file named
+// NNNN_name.<driver>.sql exists for the current one.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// DigestRun records an attempt to send the email digest.
+type DigestRun struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	// Watermark is the ID of the newest item the digest covered. The next
+	// digest only includes newer items, so it's only advanced by runs that
+	// succeeded.
+	Watermark uint `gorm:"watermark;default:0"`
+	// BookmarkWatermark is the bookmark time the digest of bookmarks
+	// covered, as items are bookmarked in any order.
+	BookmarkWatermark *time.Time `gorm:"bookmark_watermark"`
+	// Items is the number of items in the digest.
+	Items int `gorm:"items;default:0"`
+	// Error is the error message if the run failed.
+	Error *string `gorm:"error;default:''"`
+}
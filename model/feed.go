@@ -12,6 +12,29 @@ type FeedRequestOptions struct {
 	// TODO: headers, cookie, etc.
 }
 
+// Feed kinds. Feeds are pulled from their link, newsletters receive their
+// items by email, scraped pages are HTML pages whose items are found with
+// the CSS selectors of their Mapping, and JSON feeds are JSON APIs whose
+// items are found with the paths of their Mapping.
+const (
+	FeedKindFeed       = "feed"
+	FeedKindNewsletter = "newsletter"
+	FeedKindScrape     = "scrape"
+	FeedKindJSON       = "json"
+)
+
+// ItemMapping tells where the items and their fields are in the source of a
+// feed that isn't RSS, Atom or JSON Feed. Items is relative to the page, the
+// other fields to an item, and empty fields are left out.
+type ItemMapping struct {
+	Items   string `json:"items"`
+	ID      string `json:"id,omitempty"`
+	Title   string `json:"title"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
 type Feed struct {
 	ID        uint `gorm:"primarykey"`
 	CreatedAt time.Time
@@ -20,6 +43,12 @@ type Feed struct {
 
 	Name *string `gorm:"name;not null"`
 	Link *string `gorm:"link;not null;uniqueIndex:idx_link"`
+	// Kind is one of the FeedKind constants. The link of a newsletter is
+	// "newsletter:" followed by the local part of its email address.
+	Kind *string `gorm:"kind;default:'feed'"`
+	// Mapping finds the items of scraped pages and JSON APIs, nil for other
+	// kinds.
+	Mapping *ItemMapping `gorm:"mapping;serializer:json"`
 	// LastBuild is the last time the content of the feed changed
 	LastBuild *time.Time `gorm:"last_build"`
 	// Failure is the error message for the last fetch.
@@ -31,6 +60,12 @@ type Feed struct {
 	FaviconPath *string `gorm:"favicon_path"`
 
 	Suspended *bool `gorm:"suspended;default:false"`
+	// MarkUnreadOnUpdate marks items unread again when the publisher changes
+	// their title or content.
+	MarkUnreadOnUpdate *bool `gorm:"mark_unread_on_update;default:false"`
+	// ItemIdentity is the strategy that tells items apart, see
+	// client.IdentityAuto and friends.
+	ItemIdentity *string `gorm:"item_identity;default:'auto'"`
 
 	FeedRequestOptions
 
@@ -43,3 +78,12 @@ type Feed struct {
 func (f Feed) IsSuspended() bool {
 	return f.Suspended != nil && *f.Suspended
 }
+
+func (f Feed) IsNewsletter() bool {
+	return f.Kind != nil && *f.Kind == FeedKindNewsletter
+}
+
+// HasMapping reports whether the items of f are found with a Mapping.
+func (f Feed) HasMapping() bool {
+	return f.Kind != nil && (*f.Kind == FeedKindScrape || *f.Kind == FeedKindJSON)
+}
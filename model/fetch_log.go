@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// FetchLog records the outcome of a single attempt to fetch a feed. Logs are
+// append-only and pruned per feed, so they don't use soft deletion.
+type FetchLog struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	FeedID uint `gorm:"feed_id;not null;index"`
+	// StatusCode is the HTTP status code, or 0 if no response was received.
+	StatusCode int `gorm:"status_code;default:0"`
+	// Duration is the time the fetch took, in milliseconds.
+	Duration int64 `gorm:"duration;default:0"`
+	// Size is the number of bytes read from the response body.
+	Size int64 `gorm:"size;default:0"`
+	// ItemsParsed is the number of items found in the feed.
+	ItemsParsed int `gorm:"items_parsed;default:0"`
+	// ItemsNew is the number of items that were not stored before.
+	ItemsNew int `gorm:"items_new;default:0"`
+	// ItemsUpdated is the number of stored items whose title or content
+	// changed.
+	ItemsUpdated int `gorm:"items_updated;default:0"`
+	// Error is the error message if the fetch failed.
+	Error *string `gorm:"error;default:''"`
+}
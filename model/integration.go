@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// Integration is a service that items can be sent to, such as a read-it-later
+// app or a chat.
+type Integration struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_integration_name"`
+
+	Name *string `gorm:"name;not null;uniqueIndex:idx_integration_name"`
+	Kind string  `gorm:"kind;not null"`
+	// Settings are the encrypted JSON settings of the kind, including
+	// credentials.
+	Settings string `gorm:"settings;not null"`
+	// SendBookmarks sends every newly bookmarked item.
+	SendBookmarks *bool `gorm:"send_bookmarks;default:false"`
+}
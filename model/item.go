@@ -9,7 +9,7 @@ import (
 type Item struct {
 	ID        uint `gorm:"primarykey"`
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UpdatedAt time.Time             `gorm:"index"`
 	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_guid"`
 
 	Title    *string    `gorm:"title"`
@@ -19,6 +19,16 @@ type Item struct {
 	PubDate  *time.Time `gorm:"pub_date"`
 	Unread   *bool      `gorm:"unread;default:true;index"`
 	Bookmark *bool      `gorm:"bookmark;default:false;index"`
+	// BookmarkedAt is when the item was bookmarked, nil if it isn't.
+	BookmarkedAt *time.Time `gorm:"bookmarked_at;index"`
+
+	// CanonicalLink and TitleHash find the same story in other feeds.
+	// TitleHash is a simhash of the title, 0 if the title is too short.
+	CanonicalLink *string `gorm:"canonical_link;index"`
+	TitleHash     int64   `gorm:"title_hash"`
+	// ClusterID is the ID of the first item of the story's cluster, nil
+	// if no other feed has the story.
+	ClusterID *uint `gorm:"cluster_id;index"`
 
 	FeedID uint `gorm:"feed_id;uniqueIndex:idx_guid"`
 	Feed   Feed
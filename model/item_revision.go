@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// ItemRevision is a previous version of an item, kept when the publisher
+// changed its title or content. Revisions are removed with their item, so
+// they don't use soft deletion.
+type ItemRevision struct {
+	ID uint `gorm:"primarykey"`
+	// CreatedAt is when the item was changed, i.e. when this version was
+	// replaced.
+	CreatedAt time.Time
+
+	ItemID  uint    `gorm:"item_id;not null;index"`
+	Title   *string `gorm:"title"`
+	Content *string `gorm:"content"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// ItemShare makes an item readable without logging in by anyone who knows
+// Token. Revoking a share deletes it.
+type ItemShare struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+
+	Token string `gorm:"token;not null;uniqueIndex"`
+	// ExpiresAt is nil for shares that don't expire.
+	ExpiresAt *time.Time `gorm:"expires_at"`
+
+	// Item is only loaded, there's no foreign key, so that items can be
+	// purged without their shares getting in the way.
+	ItemID uint `gorm:"item_id;index"`
+	Item   Item `gorm:"constraint:-"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// Job statuses. A job is queued until a slot is free, and ends in one of
+// the last three.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job records a background operation, such as refreshing all feeds.
+type Job struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Kind   string `gorm:"kind;index"`
+	Status string `gorm:"status;index"`
+	// Done and Total are the progress in steps, e.g. pulled feeds. Total
+	// is 0 when unknown.
+	Done  int `gorm:"done;default:0"`
+	Total int `gorm:"total;default:0"`
+	// Error is the error message if the job failed or was canceled.
+	Error      *string `gorm:"error"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// MaintenanceRun records a run of the database maintenance job.
+type MaintenanceRun struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	// Duration is the time the run took, in milliseconds.
+	Duration int64 `gorm:"duration;default:0"`
+	// PurgedRows is the number of soft-deleted rows that were removed.
+	PurgedRows int64 `gorm:"purged_rows;default:0"`
+	// ReclaimedBytes is how much the database shrank.
+	ReclaimedBytes int64 `gorm:"reclaimed_bytes;default:0"`
+	// Error is the error message if the run failed.
+	Error *string `gorm:"error;default:''"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// Sources of a published feed.
+const (
+	PublishedSourceGroup       = "group"
+	PublishedSourceBookmarks   = "bookmarks"
+	PublishedSourceSmartFolder = "smart_folder"
+)
+
+// PublishedFeed renders the items of a group, the bookmarks or a smart
+// folder as a feed for other readers. Anyone who knows Token can read it.
+type PublishedFeed struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+
+	Name  *string `gorm:"name;not null"`
+	Token string  `gorm:"token;not null;uniqueIndex"`
+
+	Source string `gorm:"source;not null"`
+	// SourceID is the ID of the group or smart folder, 0 for bookmarks.
+	SourceID uint `gorm:"source_id"`
+}
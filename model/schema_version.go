@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// SchemaVersion records a schema migration that was applied to the database.
+type SchemaVersion struct {
+	Version   uint   `gorm:"primarykey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
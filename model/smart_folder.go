@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// SmartFolder is a saved search. It lists the items matching all of its
+// conditions, like a group lists the items of its feeds.
+type SmartFolder struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_smart_folder_name"`
+
+	Name *string `gorm:"name;not null;uniqueIndex:idx_smart_folder_name"`
+
+	Keyword *string `gorm:"keyword"`
+	// FeedIDs and GroupIDs limit the items to those of any of the feeds or
+	// of feeds in any of the groups. Empty means all feeds.
+	FeedIDs  []uint `gorm:"feed_ids;serializer:json"`
+	GroupIDs []uint `gorm:"group_ids;serializer:json"`
+	Unread   *bool  `gorm:"unread"`
+	Bookmark *bool  `gorm:"bookmark"`
+}
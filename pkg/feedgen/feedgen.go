@@ -0,0 +1,310 @@
+// Package feedgen writes feeds as Atom, RSS 2.0 or JSON Feed 1.1.
+package feedgen
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Output formats.
+const (
+	FormatAtom = "atom"
+	FormatRSS  = "rss"
+	FormatJSON = "json"
+)
+
+// Formats are the valid values for the format of Write.
+var Formats = []string{FormatAtom, FormatRSS, FormatJSON}
+
+// generator is the name of the program in the generated feeds.
+const generator = "FusionX"
+
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the web page of the feed, SelfURL the address the feed is
+	// served at. Both are absolute.
+	Link    string
+	SelfURL string
+	Updated time.Time
+	Items   []*Item
+}
+
+type Item struct {
+	// ID is a unique and permanent IRI of the item.
+	ID          string
+	Title       string
+	Link        string
+	ContentHTML string
+	// Published and Updated are optional. Updated falls back to Published,
+	// then to Feed.Updated in formats that require it.
+	Published time.Time
+	Updated   time.Time
+	// SourceTitle and SourceURL name the feed the item was taken from.
+	SourceTitle string
+	SourceURL   string
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	default:
+		return "application/feed+json; charset=utf-8"
+	}
+}
+
+// Write writes f to w in format.
+func Write(w io.Writer, format string, f *Feed) error {
+	switch format {
+	case FormatAtom:
+		return writeXML(w, atom(f))
+	case FormatRSS:
+		return writeXML(w, rss(f))
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(jsonFeed(f))
+	default:
+		return fmt.Errorf("unknown feed format %q", format)
+	}
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (i *Item) updated(feed *Feed) time.Time {
+	switch {
+	case !i.Updated.IsZero():
+		return i.Updated
+	case !i.Published.IsZero():
+		return i.Published
+	default:
+		return feed.Updated
+	}
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomSource struct {
+	Title string    `xml:"title"`
+	Link  *atomLink `xml:"link"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author"`
+	Content   *atomText   `xml:"content"`
+	Source    *atomSource `xml:"source"`
+}
+
+type atomFeed struct {
+	XMLName   xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle,omitempty"`
+	Links     []atomLink   `xml:"link"`
+	Updated   string       `xml:"updated"`
+	Author    atomPerson   `xml:"author"`
+	Generator string       `xml:"generator"`
+	Entries   []*atomEntry `xml:"entry"`
+}
+
+func atom(f *Feed) *atomFeed {
+	res := &atomFeed{
+		ID:       f.SelfURL,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.Link},
+		},
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		// entries without an author of their own inherit the feed's
+		Author:    atomPerson{Name: generator},
+		Generator: generator,
+	}
+	for _, i := range f.Items {
+		e := &atomEntry{
+			ID:      i.ID,
+			Title:   i.Title,
+			Updated: i.updated(f).UTC().Format(time.RFC3339),
+			Content: &atomText{Type: "html", Body: i.ContentHTML},
+		}
+		if i.Link != "" {
+			e.Links = append(e.Links, atomLink{Rel: "alternate", Href: i.Link})
+		}
+		if !i.Published.IsZero() {
+			e.Published = i.Published.UTC().Format(time.RFC3339)
+		}
+		if i.SourceTitle != "" {
+			e.Author = &atomPerson{Name: i.SourceTitle}
+			e.Source = &atomSource{Title: i.SourceTitle}
+			if i.SourceURL != "" {
+				e.Source.Link = &atomLink{Rel: "self", Href: i.SourceURL}
+			}
+		}
+		res.Entries = append(res.Entries, e)
+	}
+	return res
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Body        string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL  string `xml:"url,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link,omitempty"`
+	GUID        rssGUID    `xml:"guid"`
+	PubDate     string     `xml:"pubDate,omitempty"`
+	Description string     `xml:"description"`
+	Source      *rssSource `xml:"source"`
+}
+
+type rssChannel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	// the name space prefix is declared on the rss element
+	Self          atomLink   `xml:"atom:link"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Generator     string     `xml:"generator"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func rss(f *Feed) *rssFeed {
+	description := f.Description
+	if description == "" {
+		// required by RSS
+		description = f.Title
+	}
+	res := &rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   description,
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Generator:     generator,
+		},
+	}
+	for _, i := range f.Items {
+		v := &rssItem{
+			Title:       i.Title,
+			Link:        i.Link,
+			GUID:        rssGUID{IsPermaLink: "false", Body: i.ID},
+			Description: i.ContentHTML,
+		}
+		if !i.Published.IsZero() {
+			v.PubDate = i.Published.UTC().Format(time.RFC1123Z)
+		}
+		if i.SourceTitle != "" && i.SourceURL != "" {
+			v.Source = &rssSource{URL: i.SourceURL, Body: i.SourceTitle}
+		}
+		res.Channel.Items = append(res.Channel.Items, v)
+	}
+	return res
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonItem struct {
+	ID            string        `json:"id"`
+	URL           string        `json:"url,omitempty"`
+	Title         string        `json:"title,omitempty"`
+	ContentHTML   string        `json:"content_html"`
+	DatePublished string        `json:"date_published,omitempty"`
+	DateModified  string        `json:"date_modified,omitempty"`
+	Authors       []*jsonAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedDoc struct {
+	Version     string      `json:"version"`
+	Title       string      `json:"title"`
+	HomePageURL string      `json:"home_page_url,omitempty"`
+	FeedURL     string      `json:"feed_url"`
+	Description string      `json:"description,omitempty"`
+	Items       []*jsonItem `json:"items"`
+}
+
+func jsonFeed(f *Feed) *jsonFeedDoc {
+	res := &jsonFeedDoc{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.SelfURL,
+		Description: f.Description,
+		Items:       make([]*jsonItem, 0, len(f.Items)),
+	}
+	for _, i := range f.Items {
+		v := &jsonItem{
+			ID:          i.ID,
+			URL:         i.Link,
+			Title:       i.Title,
+			ContentHTML: i.ContentHTML,
+		}
+		if !i.Published.IsZero() {
+			v.DatePublished = i.Published.UTC().Format(time.RFC3339)
+		}
+		if !i.Updated.IsZero() {
+			v.DateModified = i.Updated.UTC().Format(time.RFC3339)
+		}
+		if i.SourceTitle != "" {
+			v.Authors = []*jsonAuthor{{Name: i.SourceTitle}}
+		}
+		res.Items = append(res.Items, v)
+	}
+	return res
+}
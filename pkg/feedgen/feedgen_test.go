@@ -0,0 +1,68 @@
+package feedgen_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/feedgen"
+)
+
+func TestWrite(t *testing.T) {
+	published := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	feed := &feedgen.Feed{
+		Title:   "Team picks",
+		Link:    "https://fusion.example.com",
+		SelfURL: "https://fusion.example.com/api/public/feeds/secret/atom",
+		Updated: published.Add(time.Hour),
+		Items: []*feedgen.Item{
+			{
+				ID:          "urn:fusionx:item:2",
+				Title:       "Go 1.23 & generics",
+				Link:        "https://go.dev/blog/1",
+				ContentHTML: `<p>Iterators <b>are</b> here</p>`,
+				Published:   published,
+				SourceTitle: "Go blog",
+				SourceURL:   "https://go.dev/blog/feed.atom",
+			},
+			// no date, link or source
+			{ID: "urn:fusionx:item:1", Title: "Untitled"},
+		},
+	}
+
+	for _, tc := range []struct {
+		format   string
+		feedType string
+	}{
+		{feedgen.FormatAtom, "atom"},
+		{feedgen.FormatRSS, "rss"},
+		{feedgen.FormatJSON, "json"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, feedgen.Write(&buf, tc.format, feed))
+
+			parsed, err := gofeed.NewParser().ParseString(buf.String())
+			require.NoError(t, err, buf.String())
+			assert.Equal(t, tc.feedType, parsed.FeedType)
+			assert.Equal(t, "Team picks", parsed.Title)
+			assert.Equal(t, feed.SelfURL, parsed.FeedLink)
+			require.Len(t, parsed.Items, 2)
+
+			item := parsed.Items[0]
+			assert.Equal(t, "urn:fusionx:item:2", item.GUID)
+			assert.Equal(t, "Go 1.23 & generics", item.Title)
+			assert.Equal(t, "https://go.dev/blog/1", item.Link)
+			assert.Contains(t, item.Content+item.Description, "<b>are</b>")
+			require.NotNil(t, item.PublishedParsed)
+			assert.True(t, published.Equal(*item.PublishedParsed))
+			assert.Equal(t, "urn:fusionx:item:1", parsed.Items[1].GUID)
+		})
+	}
+
+	assert.Error(t, feedgen.Write(&bytes.Buffer{}, "yaml", feed))
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+)
+
+// ErrPrivateAddress is returned by PublicRequest for hosts that aren't on the
+// public internet.
+var ErrPrivateAddress = errors.New("refusing to connect to a private address")
+
+var publicClient = newClient(func(transport *http.Transport) {
+	// a proxy would connect on our behalf, past the address check
+	transport.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkPublicAddress,
+	}
+	transport.DialContext = dialer.DialContext
+})
+
+// PublicRequest is FusionRequest for links found in feed content, such as
+// images and pages to archive. It refuses to connect to loopback, private
+// and link-local addresses, also after redirects, so that those links can't
+// reach services next to fusion. The address is checked after the name is
+// resolved, when connecting.
+func PublicRequest(ctx context.Context, link string) (*http.Response, error) {
+	return FusionRequestWithRequestSender(ctx, publicClient.Do, link, model.FeedRequestOptions{})
+}
+
+func checkPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// IsPublicAddr reports whether ip is a public unicast address.
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
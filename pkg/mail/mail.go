@@ -0,0 +1,200 @@
+// Package mail sends multipart emails over SMTP.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of an SMTP connection.
+const (
+	// TLSStartTLS upgrades a plain connection and fails if the server
+	// doesn't support it. Usually port 587.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects with TLS right away. Usually port 465.
+	TLSImplicit = "tls"
+	// TLSNone never encrypts, e.g. for a local relay or a test sink.
+	TLSNone = "none"
+)
+
+// TLSModes are the valid values for Config.TLS.
+var TLSModes = []string{TLSStartTLS, TLSImplicit, TLSNone}
+
+// timeout limits the whole SMTP conversation.
+const timeout = time.Minute
+
+// Config is the SMTP server to send through.
+type Config struct {
+	Host string
+	Port int
+	// Username and Password are used for PLAIN authentication if Username
+	// isn't empty. net/smtp refuses to send them unencrypted except to
+	// localhost.
+	Username string
+	Password string
+	TLS      string
+	// From is the sender address, optionally with a name.
+	From string
+}
+
+// Message is an email with a plain text and an HTML version.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Send delivers msg to all its recipients.
+func Send(ctx context.Context, cfg Config, msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("no recipients")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, v := range msg.To {
+		addr, err := mail.ParseAddress(v)
+		if err != nil {
+			return fmt.Errorf("invalid recipient: %w", err)
+		}
+		to = append(to, addr)
+	}
+	data, err := encode(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tlsConfig := &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	var conn net.Conn
+	if cfg.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("the SMTP server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// encode returns msg as a MIME message with CRLF line endings.
+func encode(from *mail.Address, to []*mail.Address, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, addr.String())
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	header := &bytes.Buffer{}
+	for _, h := range [][2]string{
+		{"From", from.String()},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + body.Boundary()},
+	} {
+		fmt.Fprintf(header, "%s: %s\r\n", h[0], h[1])
+	}
+	header.WriteString("\r\n")
+
+	// the last part is the preferred one
+	for _, part := range [][2]string{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part[0]},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// line breaks are written as CRLF
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part[1])); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return append(header.Bytes(), buf.Bytes()...), nil
+}
@@ -0,0 +1,142 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fmail "github.com/Sudo-Ivan/fusionx/pkg/mail"
+)
+
+// received is a message accepted by the sink.
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSink starts a minimal SMTP server without STARTTLS or AUTH on a
+// random port, and returns the port and the received messages.
+func startSink(t *testing.T) (int, <-chan received) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		reply("220 sink ready")
+		var msg received
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case cmd == "EHLO" || cmd == "HELO":
+				reply("250 sink")
+			case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 ok")
+			case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				msg.data = data.String()
+				messages <- msg
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, messages
+}
+
+func TestSend(t *testing.T) {
+	port, messages := startSink(t)
+	cfg := fmail.Config{Host: "127.0.0.1", Port: port, TLS: fmail.TLSNone, From: "Fusion <fusion@example.com>"}
+	msg := &fmail.Message{
+		To:      []string{"a@example.com", "B <b@example.com>"},
+		Subject: "Digest – 3 new items",
+		Text:    "first line\nsecond line",
+		HTML:    "<p>hello</p>",
+	}
+	require.NoError(t, fmail.Send(context.Background(), cfg, msg))
+
+	got := <-messages
+	assert.Equal(t, "fusion@example.com", got.from)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, got.to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	assert.Equal(t, `"B" <b@example.com>`, strings.Split(parsed.Header.Get("To"), ", ")[1])
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	// multipart.Reader decodes quoted-printable parts
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "first line\r\nsecond line"},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		part, err := parts.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, want.contentType, part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, want.body, string(body))
+	}
+}
+
+func TestSendRequiresStartTLS(t *testing.T) {
+	port, _ := startSink(t)
+	cfg := fmail.Config{Host: "127.0.0.1", Port: port, TLS: fmail.TLSStartTLS, From: "fusion@example.com"}
+	err := fmail.Send(context.Background(), cfg, &fmail.Message{To: []string{"a@example.com"}})
+	assert.ErrorContains(t, err, "STARTTLS")
+}
+
+func TestSendInvalidAddress(t *testing.T) {
+	cfg := fmail.Config{Host: "127.0.0.1", Port: 1, TLS: fmail.TLSNone, From: "fusion@example.com"}
+	err := fmail.Send(context.Background(), cfg, &fmail.Message{To: []string{"not an address"}})
+	assert.ErrorContains(t, err, "invalid recipient")
+	assert.ErrorContains(t, fmail.Send(context.Background(), cfg, &fmail.Message{}), "no recipients")
+}
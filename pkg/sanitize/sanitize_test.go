@@ -0,0 +1,69 @@
+package sanitize_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/sanitize"
+)
+
+func TestHTML(t *testing.T) {
+	base, err := url.Parse("https://blog.example.com/posts/1")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain text",
+			content: "a < b & c",
+			want:    "a &lt; b &amp; c",
+		},
+		{
+			name:    "allowed markup",
+			content: `<p>Hello <b>world</b><br><img src="/a.png" alt="A"></p>`,
+			want:    `<p>Hello <b>world</b><br/><img src="https://blog.example.com/a.png" alt="A"/></p>`,
+		},
+		{
+			name:    "scripts and styles",
+			content: `<p onclick="x()" style="color:red">ok</p><script>alert(1)</script><style>p{}</style>`,
+			want:    `<p>ok</p>`,
+		},
+		{
+			name:    "unknown elements keep their text",
+			content: `<article><section>text <font>inside</font></section></article>`,
+			want:    `text inside`,
+		},
+		{
+			name:    "links",
+			content: `<a href="../2">next</a><a href="javascript:alert(1)">bad</a>`,
+			want: `<a href="https://blog.example.com/2" target="_blank" rel="noopener noreferrer nofollow">next</a>` +
+				`<a target="_blank" rel="noopener noreferrer nofollow">bad</a>`,
+		},
+		{
+			name:    "embedded content",
+			content: `<iframe src="https://evil.example.com"></iframe><video src="v.mp4" autoplay></video>`,
+			want:    `<video src="https://blog.example.com/posts/v.mp4" controls=""></video>`,
+		},
+		{
+			name:    "comments",
+			content: `a<!-- hidden -->b`,
+			want:    `ab`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := sanitize.HTML(tc.content, base)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	got, err := sanitize.HTML(`<img src="/a.png">`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `<img/>`, got, "relative URLs can't be resolved without a base")
+}
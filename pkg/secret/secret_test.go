@@ -0,0 +1,59 @@
+package secret_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
+)
+
+func TestBox(t *testing.T) {
+	box, err := secret.NewBox("correct horse")
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte(`{"token":"abc"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "abc")
+	again, err := box.Seal([]byte(`{"token":"abc"}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces should be random")
+
+	plaintext, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, `{"token":"abc"}`, string(plaintext))
+
+	other, err := secret.NewBox("battery staple")
+	require.NoError(t, err)
+	_, err = other.Open(sealed)
+	assert.ErrorIs(t, err, secret.ErrOpen)
+
+	for _, bad := range []string{"", "v1:", "v1:!!!", `{"token":"abc"}`, sealed[:len(sealed)-4] + "AAAA"} {
+		_, err = box.Open(bad)
+		assert.ErrorIs(t, err, secret.ErrOpen, bad)
+	}
+
+	_, err = secret.NewBox("")
+	assert.Error(t, err)
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "secret.key")
+	key, err := secret.LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Len(t, key, 64)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	again, err := secret.LoadOrCreateKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "the key should be kept")
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = secret.LoadOrCreateKey(path)
+	assert.ErrorContains(t, err, "empty")
+}
@@ -0,0 +1,81 @@
+// Package simhash computes locality sensitive hashes of short texts, so that
+// near-identical texts have hashes that differ in only a few bits.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// MinWords is the minimum number of significant words a title needs to get
+// a hash. Shorter titles such as "Weekly update" are too generic to tell
+// whether two items are about the same story.
+const MinWords = 3
+
+// stopWords are left out because they carry little meaning and often differ
+// between two headlines of the same story.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "have": true,
+	"in": true, "is": true, "it": true, "its": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"will": true, "with": true,
+}
+
+// Title returns the simhash of a title, or 0 if it has fewer than MinWords
+// significant words.
+//
+// The hash is built from word shingles: every significant word and every
+// pair of adjacent significant words. The result is returned as int64 so it
+// can be stored in any SQL database.
+func Title(title string) int64 {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	significant := words[:0]
+	for _, w := range words {
+		if !stopWords[w] {
+			significant = append(significant, w)
+		}
+	}
+	if len(significant) < MinWords {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	for i, w := range significant {
+		add(w)
+		if i > 0 {
+			add(significant[i-1] + " " + w)
+		}
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << i
+		}
+	}
+	// #nosec G115 - the bits are reinterpreted, not converted
+	return int64(hash)
+}
+
+// Distance returns the number of bits in which two hashes differ.
+func Distance(a, b int64) int {
+	// #nosec G115 - the bits are reinterpreted, not converted
+	return bits.OnesCount64(uint64(a ^ b))
+}
@@ -0,0 +1,54 @@
+package simhash_test
+
+import (
+	"testing"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/simhash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTitle(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		a, b        string
+		similar     bool
+	}{
+		{
+			description: "same title",
+			a:           "Kubernetes 1.34 released with new scheduler",
+			b:           "Kubernetes 1.34 released with new scheduler",
+			similar:     true,
+		},
+		{
+			description: "case, punctuation and stop words are ignored",
+			a:           "Apple announces iPhone 17 with new camera",
+			b:           "Apple announces the iPhone 17 – with a new camera!",
+			similar:     true,
+		},
+		{
+			description: "different stories",
+			a:           "Apple announces iPhone 17 with new camera",
+			b:           "Google releases Android 16 beta",
+		},
+		{
+			description: "same words in a different order",
+			a:           "Rust beats Go in new benchmark",
+			b:           "Go beats Rust in new benchmark",
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			d := simhash.Distance(simhash.Title(tt.a), simhash.Title(tt.b))
+			if tt.similar {
+				assert.LessOrEqual(t, d, 3)
+			} else {
+				assert.Greater(t, d, 3)
+			}
+		})
+	}
+}
+
+func TestTitleTooShort(t *testing.T) {
+	assert.Zero(t, simhash.Title("Weekly update"))
+	assert.Zero(t, simhash.Title("The state of the art"))
+	assert.NotZero(t, simhash.Title("Weekly update for March"))
+}
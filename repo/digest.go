@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewDigest(db *gorm.DB) *Digest {
+	return &Digest{
+		db: db,
+	}
+}
+
+type Digest struct {
+	db *gorm.DB
+}
+
+// Watermark returns the watermark of the last successful digest, or 0 if
+// none was sent yet.
+func (d Digest) Watermark() (uint, error) {
+	var watermark uint
+	err := d.db.Model(&model.DigestRun{}).Where("error = '' OR error IS NULL").
+		Select("COALESCE(MAX(watermark), 0)").Scan(&watermark).Error
+	return watermark, err
+}
+
+// BookmarkWatermark returns the bookmark watermark of the last successful
+// digest, or the zero time if none was sent yet.
+func (d Digest) BookmarkWatermark() (time.Time, error) {
+	var run model.DigestRun
+	err := d.db.Where("(error = '' OR error IS NULL) AND bookmark_watermark IS NOT NULL").
+		Order("bookmark_watermark DESC").Limit(1).Find(&run).Error
+	if err != nil || run.BookmarkWatermark == nil {
+		return time.Time{}, err
+	}
+	return *run.BookmarkWatermark, nil
+}
+
+func (d Digest) CreateRun(run *model.DigestRun) error {
+	return d.db.Create(run).Error
+}
+
+// Prune keeps the latest keep runs and deletes the older ones, except for
+// the successful runs that hold the watermarks.
+func (d Digest) Prune(keep int) error {
+	succeeded := func() *gorm.DB {
+		return d.db.Model(&model.DigestRun{}).Select("id").Where("error = '' OR error IS NULL").Limit(1)
+	}
+	err := d.db.Where(
+		"id NOT IN (?)",
+		d.db.Model(&model.DigestRun{}).Select("id").Order("id desc").Limit(keep),
+	).Where(
+		"id NOT IN (?)", succeeded().Order("watermark desc, id desc"),
+	).Where(
+		"id NOT IN (?)", succeeded().Where("bookmark_watermark IS NOT NULL").Order("bookmark_watermark desc"),
+	).Delete(&model.DigestRun{}).Error
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
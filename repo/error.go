@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("resource not exists")
 	ErrDuplicatedKey = errors.New("exists duplicated key(s)")
+	ErrUnsupported   = errors.New("not supported by the database driver")
+	ErrInvalidCursor = errors.New("invalid or expired cursor")
+	ErrSyncExpired   = errors.New("sync token is older than the purged deletions")
 )
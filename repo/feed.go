@@ -28,13 +28,15 @@ func (f Feed) List(filter *FeedListFilter) ([]*model.Feed, error) {
 	var res []*model.Feed
 	db := f.db.Model(&model.Feed{}).Joins("Group")
 	if filter != nil {
+		// use EXISTS rather than joining items and grouping by feeds.id, as
+		// PostgreSQL rejects selecting the joined group's columns then.
 		if filter.HaveUnread != nil && *filter.HaveUnread {
-			db = db.Joins("inner join items on feeds.id = items.feed_id and items.unread = true").
-				Group("feeds.id")
+			db = db.Where("EXISTS (?)", f.db.Model(&model.Item{}).Select("1").
+				Where("items.feed_id = feeds.id AND items.unread = ?", true))
 		}
 		if filter.HaveBookmark != nil && *filter.HaveBookmark {
-			db = db.Joins("inner join items on feeds.id = items.feed_id and items.bookmark = true").
-				Group("feeds.id")
+			db = db.Where("EXISTS (?)", f.db.Model(&model.Item{}).Select("1").
+				Where("items.feed_id = feeds.id AND items.bookmark = ?", true))
 		}
 	}
 
@@ -56,7 +58,7 @@ func (f Feed) List(filter *FeedListFilter) ([]*model.Feed, error) {
 	err = f.db.Model(&model.Item{}).
 		Select("feed_id, count(*) as count").
 		Where("feed_id in ?", ids).
-		Where("unread = true").
+		Where("unread = ?", true).
 		Group("feed_id").
 		Find(&itemUnreadCount).Error
 	if err != nil {
@@ -80,6 +82,12 @@ func (f Feed) Get(id uint) (*model.Feed, error) {
 	return &res, err
 }
 
+func (f Feed) GetByLink(link string) (*model.Feed, error) {
+	var res model.Feed
+	err := f.db.Model(&model.Feed{}).Where("link = ?", link).First(&res).Error
+	return &res, err
+}
+
 func (f Feed) FindByFaviconHash(faviconHash string) ([]*model.Feed, error) {
 	var res []*model.Feed
 	err := f.db.Model(&model.Feed{}).Where("link IS NOT NULL").Find(&res).Error
@@ -89,7 +97,7 @@ func (f Feed) FindByFaviconHash(faviconHash string) ([]*model.Feed, error) {
 func (f Feed) Create(data []*model.Feed) error {
 	return f.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "link"}, {Name: "deleted_at"}},
-		DoUpdates: clause.AssignmentColumns([]string{"name", "link", "req_proxy", "group_id"}),
+		DoUpdates: clause.AssignmentColumns([]string{"name", "link", "kind", "mapping", "req_proxy", "group_id"}),
 	}).Create(data).Error
 }
 
@@ -102,6 +110,9 @@ func (f Feed) Delete(id uint) error {
 		if err := tx.Model(&model.Item{}).Where("feed_id = ?", id).Delete(&model.Item{}).Error; err != nil && !errors.Is(err, ErrNotFound) {
 			return err
 		}
+		if err := tx.Where("feed_id = ?", id).Delete(&model.FetchLog{}).Error; err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
 		return tx.Delete(&model.Feed{}, id).Error
 	})
 }
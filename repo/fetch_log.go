@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"errors"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewFetchLog(db *gorm.DB) *FetchLog {
+	return &FetchLog{
+		db: db,
+	}
+}
+
+type FetchLog struct {
+	db *gorm.DB
+}
+
+func (f FetchLog) List(feedID uint, limit int) ([]*model.FetchLog, error) {
+	var res []*model.FetchLog
+	err := f.db.Model(&model.FetchLog{}).Where("feed_id = ?", feedID).
+		Order("id desc").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (f FetchLog) Create(log *model.FetchLog) error {
+	return f.db.Create(log).Error
+}
+
+// Prune keeps the latest keep logs of the feed and deletes the rest.
+func (f FetchLog) Prune(feedID uint, keep int) error {
+	err := f.db.Where("feed_id = ?", feedID).Where(
+		"id NOT IN (?)",
+		f.db.Model(&model.FetchLog{}).Select("id").Where("feed_id = ?", feedID).
+			Order("id desc").Limit(keep),
+	).Delete(&model.FetchLog{}).Error
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
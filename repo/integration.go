@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewIntegration(db *gorm.DB) *Integration {
+	return &Integration{
+		db: db,
+	}
+}
+
+type Integration struct {
+	db *gorm.DB
+}
+
+func (i Integration) All() ([]*model.Integration, error) {
+	var res []*model.Integration
+	err := i.db.Order("name").Find(&res).Error
+	return res, err
+}
+
+// SendingBookmarks returns the integrations that new bookmarks are sent to.
+func (i Integration) SendingBookmarks() ([]*model.Integration, error) {
+	var res []*model.Integration
+	err := i.db.Where("send_bookmarks = ?", true).Order("id").Find(&res).Error
+	return res, err
+}
+
+func (i Integration) Get(id uint) (*model.Integration, error) {
+	var res model.Integration
+	err := i.db.First(&res, id).Error
+	return &res, err
+}
+
+func (i Integration) Create(integration *model.Integration) error {
+	return i.db.Create(integration).Error
+}
+
+// Update replaces the name, settings and options of an integration. The
+// kind can't be changed.
+func (i Integration) Update(id uint, integration *model.Integration) error {
+	return i.db.Model(&model.Integration{}).Where("id = ?", id).
+		Select("name", "settings", "send_bookmarks").Updates(integration).Error
+}
+
+func (i Integration) Delete(id uint) error {
+	return i.db.Delete(&model.Integration{}, id).Error
+}
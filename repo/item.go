@@ -1,9 +1,17 @@
 package repo
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/simhash"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -25,36 +33,371 @@ type ItemFilter struct {
 	GroupID  *uint
 	Unread   *bool
 	Bookmark *bool
+	// FeedIDs and GroupIDs limit the items to those of any of the feeds or
+	// of feeds in any of the groups.
+	FeedIDs  []uint
+	GroupIDs []uint
+	// AfterID and UntilID limit the items to a range of IDs, which grow
+	// with the time items were stored. Zero means no limit.
+	AfterID uint
+	UntilID uint
+	// BookmarkedAfter and BookmarkedUntil limit the items to those
+	// bookmarked in a time range. Nil means no limit.
+	BookmarkedAfter *time.Time
+	BookmarkedUntil *time.Time
+	// Within further limits the items to those matching another filter,
+	// such as the conditions of a smart folder.
+	Within *ItemFilter
+	// Collapse lists only the first matching item of each cluster.
+	Collapse bool
+	// Sort is the order of the results, ItemSortNewest if empty.
+	Sort ItemSort
+}
+
+// ItemSort is an order of item lists.
+type ItemSort string
+
+const (
+	ItemSortNewest ItemSort = "newest"
+	ItemSortOldest ItemSort = "oldest"
+	// ItemSortFeed sorts by feed name, newest first within a feed.
+	ItemSortFeed ItemSort = "feed"
+)
+
+// sortKey is a column of an item order. value returns the column's value in
+// a cursor, nil for NULL.
+type sortKey struct {
+	expr  string
+	desc  bool
+	value func(c itemCursor) any
+}
+
+func (s ItemSort) keys() []sortKey {
+	// NULLs sort first in descending order in PostgreSQL but last in
+	// SQLite, so put items without a date last explicitly.
+	undated := sortKey{expr: "CASE WHEN items.pub_date IS NULL THEN 1 ELSE 0 END", value: func(c itemCursor) any {
+		if c.PubDate == nil {
+			return 1
+		}
+		return 0
+	}}
+	pubDate := func(desc bool) sortKey {
+		return sortKey{expr: "items.pub_date", desc: desc, value: func(c itemCursor) any {
+			if c.PubDate == nil {
+				return nil
+			}
+			return *c.PubDate
+		}}
+	}
+	id := func(desc bool) sortKey {
+		return sortKey{expr: "items.id", desc: desc, value: func(c itemCursor) any { return c.ID }}
+	}
+
+	switch s {
+	case ItemSortOldest:
+		return []sortKey{undated, pubDate(false), id(false)}
+	case ItemSortFeed:
+		return []sortKey{
+			{expr: "COALESCE(feeds.name, '')", value: func(c itemCursor) any { return c.FeedName }},
+			{expr: "items.feed_id", value: func(c itemCursor) any { return c.FeedID }},
+			undated, pubDate(true), id(true),
+		}
+	default:
+		return []sortKey{undated, pubDate(true), id(true)}
+	}
+}
+
+func (s ItemSort) order() string {
+	keys := s.keys()
+	cols := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.desc {
+			cols = append(cols, k.expr+" DESC")
+		} else {
+			cols = append(cols, k.expr)
+		}
+	}
+	return strings.Join(cols, ", ")
+}
+
+// after returns the condition for rows that come after the cursor.
+func (s ItemSort) after(c itemCursor) (string, []any) {
+	keys := s.keys()
+	terms := make([]string, 0, len(keys))
+	args := make([]any, 0)
+	for i, k := range keys {
+		v := k.value(c)
+		// within a group of equal preceding keys, nothing sorts beyond
+		// NULL
+		if v == nil {
+			continue
+		}
+		parts := make([]string, 0, i+1)
+		for _, prev := range keys[:i] {
+			if pv := prev.value(c); pv == nil {
+				parts = append(parts, prev.expr+" IS NULL")
+			} else {
+				parts = append(parts, prev.expr+" = ?")
+				args = append(args, pv)
+			}
+		}
+		op := " > ?"
+		if k.desc {
+			op = " < ?"
+		}
+		parts = append(parts, k.expr+op)
+		args = append(args, v)
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// itemCursor is the position after the last item of a page. It holds the
+// values of all sort keys so that the next page starts right after it, no
+// matter which items were added in the meantime.
+type itemCursor struct {
+	Sort     ItemSort   `json:"s"`
+	PubDate  *time.Time `json:"d,omitempty"`
+	FeedName string     `json:"n,omitempty"`
+	FeedID   uint       `json:"f,omitempty"`
+	ID       uint       `json:"i"`
+}
+
+func newItemCursor(sort ItemSort, item *model.Item) itemCursor {
+	return itemCursor{
+		Sort:     sort,
+		PubDate:  item.PubDate,
+		FeedName: ptr.From(item.Feed.Name),
+		FeedID:   item.FeedID,
+		ID:       item.ID,
+	}
 }
 
 func (i Item) List(filter ItemFilter, page, pageSize int) ([]*model.Item, int, error) {
 	var total int64
 	var res []*model.Item
+	db := i.filter(filter)
+	err := db.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Preload("Feed").Order(filter.Sort.order()).
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&res).Error
+	return res, int(total), err
+}
+
+// ListAfter returns up to limit items that come after cursor, and the cursor
+// of the next page. An empty cursor starts at the first item, an empty next
+// cursor means there are no more items. Unlike List, pages don't shift when
+// items are added or removed.
+func (i Item) ListAfter(filter ItemFilter, cursor string, limit int) ([]*model.Item, string, error) {
+	db := i.filter(filter)
+	if cursor != "" {
+		var c itemCursor
+		if err := decodeToken(cursor, &c); err != nil || c.Sort != filter.Sort {
+			return nil, "", ErrInvalidCursor
+		}
+		cond, args := filter.Sort.after(c)
+		db = db.Where(cond, args...)
+	}
+
+	var res []*model.Item
+	if err := db.Preload("Feed").Order(filter.Sort.order()).Limit(limit + 1).Find(&res).Error; err != nil {
+		return nil, "", err
+	}
+	if len(res) <= limit {
+		return res, "", nil
+	}
+	res = res[:limit]
+	next, err := encodeToken(newItemCursor(filter.Sort, res[len(res)-1]))
+	return res, next, err
+}
+
+// syncMargin is how long a write may take between setting updated_at and
+// committing. Changes are returned again for this long, so that a client
+// doesn't miss them.
+const syncMargin = 5 * time.Second
+
+// changeToken is the position of a client in the list of changes.
+type changeToken struct {
+	UpdatedAt time.Time `json:"t"`
+	ID        uint      `json:"i"`
+}
+
+// ItemChanges are the changes since a sync token.
+type ItemChanges struct {
+	// Items are the items that were added or changed, e.g. marked read or
+	// updated by the publisher.
+	Items []*model.Item
+	// Deleted are the IDs of deleted items.
+	Deleted []uint
+	// Next is the token for the next call.
+	Next string
+	// HasMore reports whether there are more changes than the limit.
+	HasMore bool
+}
+
+// Changes returns up to limit items that changed since the token, oldest
+// change first, and the IDs of items deleted since then. An empty token
+// returns all items. An item may be returned again by the next call, clients
+// should apply changes by ID.
+//
+// Deleted items are only known until they're purged, so a token from before
+// the last purge returns ErrSyncExpired and the client has to start over.
+func (i Item) Changes(since string, limit int) (*ItemChanges, error) {
+	var token changeToken
+	if since != "" {
+		if err := decodeToken(since, &token); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		horizon, err := syncHorizon(i.db)
+		if err != nil {
+			return nil, err
+		}
+		if token.UpdatedAt.Add(-syncMargin).Before(horizon) {
+			return nil, ErrSyncExpired
+		}
+	}
+	// taken before the queries, so nothing written meanwhile is skipped
+	now := time.Now()
+
+	res := &ItemChanges{Deleted: make([]uint, 0)}
+	err := i.db.Preload("Feed").
+		Where("items.updated_at > ? OR (items.updated_at = ? AND items.id > ?)", token.UpdatedAt, token.UpdatedAt, token.ID).
+		Order("items.updated_at, items.id").Limit(limit + 1).Find(&res.Items).Error
+	if err != nil {
+		return nil, err
+	}
+	if since != "" {
+		err := i.db.Unscoped().Model(&model.Item{}).
+			Where("deleted_at >= ?", token.UpdatedAt.Add(-syncMargin).Unix()).Pluck("id", &res.Deleted).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	next := changeToken{UpdatedAt: now.Add(-syncMargin)}
+	if len(res.Items) > limit {
+		res.Items = res.Items[:limit]
+		res.HasMore = true
+		last := res.Items[len(res.Items)-1]
+		next = changeToken{UpdatedAt: last.UpdatedAt, ID: last.ID}
+	}
+	res.Next, err = encodeToken(next)
+	return res, err
+}
+
+// syncHorizonKey is the config key of the time before which deleted items
+// were purged, in Unix seconds.
+const syncHorizonKey = "sync_horizon"
+
+// syncHorizon returns the time before which deleted items were purged, the
+// zero time if none were.
+func syncHorizon(db *gorm.DB) (time.Time, error) {
+	var values []string
+	err := db.Model(&model.Config{}).Where("key = ?", syncHorizonKey).Pluck("value", &values).Error
+	if err != nil || len(values) == 0 {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func encodeToken(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeToken(token string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (i Item) filter(filter ItemFilter) *gorm.DB {
 	db := i.db.Model(&model.Item{}).Joins("JOIN feeds ON feeds.id = items.feed_id")
 	if filter.Keyword != nil {
-		expr := "%" + *filter.Keyword + "%"
-		db = db.Where("title LIKE ? OR content LIKE ?", expr, expr)
+		// LIKE is case-insensitive in SQLite but not in PostgreSQL
+		expr := "%" + strings.ToLower(*filter.Keyword) + "%"
+		db = db.Where("LOWER(items.title) LIKE ? OR LOWER(items.content) LIKE ?", expr, expr)
 	}
 	if filter.FeedID != nil {
-		db = db.Where("feed_id = ?", *filter.FeedID)
+		db = db.Where("items.feed_id = ?", *filter.FeedID)
 	}
 	if filter.GroupID != nil {
 		db = db.Where("feeds.group_id = ?", *filter.GroupID)
 	}
+	switch {
+	case len(filter.FeedIDs) > 0 && len(filter.GroupIDs) > 0:
+		db = db.Where("items.feed_id IN ? OR feeds.group_id IN ?", filter.FeedIDs, filter.GroupIDs)
+	case len(filter.FeedIDs) > 0:
+		db = db.Where("items.feed_id IN ?", filter.FeedIDs)
+	case len(filter.GroupIDs) > 0:
+		db = db.Where("feeds.group_id IN ?", filter.GroupIDs)
+	}
+	if filter.AfterID != 0 {
+		db = db.Where("items.id > ?", filter.AfterID)
+	}
+	if filter.UntilID != 0 {
+		db = db.Where("items.id <= ?", filter.UntilID)
+	}
+	if filter.BookmarkedAfter != nil {
+		db = db.Where("items.bookmarked_at > ?", *filter.BookmarkedAfter)
+	}
+	if filter.BookmarkedUntil != nil {
+		db = db.Where("items.bookmarked_at <= ?", *filter.BookmarkedUntil)
+	}
 	if filter.Unread != nil {
-		db = db.Where("unread = ?", *filter.Unread)
+		db = db.Where("items.unread = ?", *filter.Unread)
 	}
 	if filter.Bookmark != nil {
-		db = db.Where("bookmark = ?", *filter.Bookmark)
+		db = db.Where("items.bookmark = ?", *filter.Bookmark)
 	}
-	err := db.Count(&total).Error
-	if err != nil {
-		return nil, 0, err
+	if filter.Within != nil {
+		db = db.Where("items.id IN (?)", i.filter(*filter.Within).Select("items.id"))
 	}
+	if filter.Collapse {
+		// a cluster is represented by its first matching item
+		collapsed := filter
+		collapsed.Collapse = false
+		db = db.Where("items.id IN (?)", i.filter(collapsed).Select("MIN(items.id)").
+			Group("COALESCE(items.cluster_id, items.id)"))
+	}
+	return db
+}
 
-	err = db.Preload("Feed").Order("items.pub_date desc, items.created_at desc").
-		Offset((page - 1) * pageSize).Limit(pageSize).Find(&res).Error
-	return res, int(total), err
+// Count returns the number of matching items.
+func (i Item) Count(filter ItemFilter) (int, error) {
+	var total int64
+	err := i.filter(filter).Count(&total).Error
+	return int(total), err
+}
+
+// LastID returns the highest ID of the matching items, or 0 if none match.
+func (i Item) LastID(filter ItemFilter) (uint, error) {
+	var id uint
+	err := i.filter(filter).Select("COALESCE(MAX(items.id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// ClusterMembers returns the items of the given clusters with their feeds,
+// oldest first.
+func (i Item) ClusterMembers(clusterIDs []uint) ([]*model.Item, error) {
+	res := make([]*model.Item, 0)
+	if len(clusterIDs) == 0 {
+		return res, nil
+	}
+	err := i.db.Joins("Feed").Where("items.cluster_id IN ?", clusterIDs).Order("items.id").Find(&res).Error
+	return res, err
 }
 
 func (i Item) Get(id uint) (*model.Item, error) {
@@ -63,16 +406,361 @@ func (i Item) Get(id uint) (*model.Item, error) {
 	return &res, err
 }
 
-func (i Item) Insert(items []*model.Item) error {
-	// limit batchSize to fix 'too many SQL variable' error
+// insertBatch is the number of items inserted by one statement. It keeps the
+// number of SQL variables below the limits of SQLite and PostgreSQL.
+const insertBatch = 100
+
+// Insert stores items that don't exist yet and returns the number of rows
+// actually inserted.
+func (i Item) Insert(items []*model.Item) (int, error) {
 	now := time.Now()
 	for _, i := range items {
 		i.CreatedAt = now
 		i.UpdatedAt = now
 	}
-	return i.db.Clauses(clause.OnConflict{
+	res := i.db.Clauses(clause.OnConflict{
 		DoNothing: true,
-	}).CreateInBatches(items, 5).Error
+	}).CreateInBatches(items, insertBatch)
+	return int(res.RowsAffected), res.Error
+}
+
+// ItemSaveResult is the number of items stored or changed by Save.
+type ItemSaveResult struct {
+	New     int
+	Updated int
+}
+
+// Save stores new items and updates stored items whose title or content
+// changed, keeping the previous version as a revision. Changed items are
+// marked unread again if markUnread is set. Stored items keyed by the
+// canonical link of a new item take its GUID. All items must belong to the
+// same feed.
+func (i Item) Save(items []*model.Item, markUnread bool) (ItemSaveResult, error) {
+	var res ItemSaveResult
+	if len(items) == 0 {
+		return res, nil
+	}
+
+	err := i.db.Transaction(func(tx *gorm.DB) error {
+		// a feed may list the same GUID twice, only look at the first one
+		// so that the two versions don't replace each other on every pull
+		incoming := make(map[string]*model.Item, len(items))
+		guids := make([]string, 0, len(items))
+		for _, item := range items {
+			if item.GUID == nil {
+				continue
+			}
+			if _, ok := incoming[*item.GUID]; !ok {
+				incoming[*item.GUID] = item
+				guids = append(guids, *item.GUID)
+			}
+		}
+
+		stored := make([]*model.Item, 0, len(guids))
+		// limit the number of SQL variables per query
+		for chunk := range slices.Chunk(guids, 500) {
+			var found []*model.Item
+			if err := tx.Where("feed_id = ? AND guid IN ?", items[0].FeedID, chunk).Find(&found).Error; err != nil {
+				return err
+			}
+			stored = append(stored, found...)
+		}
+
+		for _, old := range stored {
+			item := incoming[*old.GUID]
+			delete(incoming, *old.GUID)
+			if ptr.From(item.Title) == ptr.From(old.Title) && ptr.From(item.Content) == ptr.From(old.Content) {
+				continue
+			}
+
+			if err := tx.Create(&model.ItemRevision{
+				ItemID:  old.ID,
+				Title:   old.Title,
+				Content: old.Content,
+			}).Error; err != nil {
+				return err
+			}
+			updates := map[string]any{"title": item.Title, "content": item.Content}
+			if markUnread {
+				updates["unread"] = true
+			}
+			if err := tx.Model(&model.Item{}).Where("id = ?", old.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			res.Updated++
+		}
+
+		if err := adoptLinkKeyed(tx, items[0].FeedID, incoming); err != nil {
+			return err
+		}
+
+		// items without a GUID can't be matched, Insert skips them if
+		// they conflict
+		newItems := make([]*model.Item, 0, len(incoming))
+		for _, item := range items {
+			if item.GUID == nil || incoming[*item.GUID] == item {
+				newItems = append(newItems, item)
+			}
+		}
+		var err error
+		res.New, err = Item{db: tx}.Insert(newItems)
+		if err != nil {
+			return err
+		}
+		return cluster(tx, newItems)
+	})
+	return res, err
+}
+
+// adoptLinkKeyed gives stored items that are keyed by their link the GUID of
+// the incoming item with that link, so that the feed doesn't add them again.
+// Those are items imported from other readers, keyed by their canonical link,
+// and items without a GUID stored before links were normalized, keyed by the
+// link as the feed listed it. Adopted items are removed from incoming and
+// otherwise left unchanged.
+func adoptLinkKeyed(tx *gorm.DB, feedID uint, incoming map[string]*model.Item) error {
+	byLink := make(map[string]*model.Item, len(incoming))
+	links := make([]string, 0, len(incoming))
+	byRawLink := make(map[string]*model.Item, len(incoming))
+	rawLinks := make([]string, 0, len(incoming))
+	for guid, item := range incoming {
+		link := ptr.From(item.CanonicalLink)
+		if link == "" {
+			continue
+		}
+		if link == guid {
+			// the GUID is the normalized link, the stored item may be
+			// keyed by the raw one
+			if raw := ptr.From(item.Link); raw != "" {
+				if _, ok := byRawLink[raw]; !ok {
+					byRawLink[raw] = item
+					rawLinks = append(rawLinks, raw)
+				}
+			}
+			continue
+		}
+		if _, ok := byLink[link]; !ok {
+			byLink[link] = item
+			links = append(links, link)
+		}
+	}
+
+	for chunk := range slices.Chunk(links, 500) {
+		var found []*model.Item
+		if err := tx.Where("feed_id = ? AND guid IN ?", feedID, chunk).Find(&found).Error; err != nil {
+			return err
+		}
+		for _, old := range found {
+			item := byLink[*old.GUID]
+			if err := tx.Model(&model.Item{}).Where("id = ?", old.ID).Update("guid", item.GUID).Error; err != nil {
+				return err
+			}
+			delete(incoming, *item.GUID)
+		}
+	}
+
+	// items.link holds the resolved link, the GUID is the link as listed,
+	// which was relative if the link is
+	for chunk := range slices.Chunk(rawLinks, 500) {
+		var found []*model.Item
+		if err := tx.Where("feed_id = ? AND link IN ?", feedID, chunk).Find(&found).Error; err != nil {
+			return err
+		}
+		for _, old := range found {
+			guid, link := ptr.From(old.GUID), ptr.From(old.Link)
+			if guid != link && !strings.HasPrefix(guid, "/") {
+				// a real GUID, another item with the same link
+				continue
+			}
+			item := byRawLink[link]
+			if incoming[*item.GUID] != item {
+				// adopted by another stored item with the same link
+				continue
+			}
+			if err := tx.Model(&model.Item{}).Where("id = ?", old.ID).Update("guid", item.GUID).Error; err != nil {
+				return err
+			}
+			delete(incoming, *item.GUID)
+		}
+	}
+	return nil
+}
+
+const (
+	// clusterWindow is how far back to look for the same story in other
+	// feeds.
+	clusterWindow = 72 * time.Hour
+	// clusterMaxDistance is the number of bits in which the title hashes
+	// of the same story may differ.
+	clusterMaxDistance = 3
+)
+
+// titleHashBands are the four 16 bit bands of items.title_hash, which are
+// indexed. Hashes that differ in fewer bits than there are bands share at
+// least one band, so the indexes find the titles within clusterMaxDistance.
+var titleHashBands = [...]string{
+	"(title_hash & 65535)",
+	"((title_hash >> 16) & 65535)",
+	"((title_hash >> 32) & 65535)",
+	"((title_hash >> 48) & 65535)",
+}
+
+// titleHashBand returns band i of a title hash, as in titleHashBands.
+func titleHashBand(hash int64, i int) int64 {
+	return (hash >> (16 * i)) & 65535
+}
+
+// cluster adds new items of a feed to the cluster of the same story in other
+// feeds. Items are the same story if their canonical links are equal or
+// their titles are near-identical. A cluster is created when the matching
+// item isn't in one yet.
+func cluster(tx *gorm.DB, items []*model.Item) error {
+	pending := make([]*model.Item, 0, len(items))
+	for _, item := range items {
+		// items skipped by Insert have no ID
+		if item.ID != 0 && (ptr.From(item.CanonicalLink) != "" || item.TitleHash != 0) {
+			pending = append(pending, item)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// only the items with one of the canonical links or a title hash that
+	// shares a band are loaded, through their indexes
+	var links []string
+	var bands [len(titleHashBands)][]int64
+	for _, item := range pending {
+		if link := ptr.From(item.CanonicalLink); link != "" {
+			links = append(links, link)
+		}
+		if item.TitleHash != 0 {
+			for i := range bands {
+				bands[i] = append(bands[i], titleHashBand(item.TitleHash, i))
+			}
+		}
+	}
+	var conds []string
+	var args []any
+	if len(links) > 0 {
+		conds = append(conds, "canonical_link IN ?")
+		args = append(args, links)
+	}
+	if len(bands[0]) > 0 {
+		for i, band := range titleHashBands {
+			conds = append(conds, band+" IN ?")
+			args = append(args, bands[i])
+		}
+	}
+
+	var candidates []*model.Item
+	err := tx.Select("id", "feed_id", "canonical_link", "title_hash", "cluster_id").
+		Where("feed_id <> ? AND created_at >= ?", pending[0].FeedID, time.Now().Add(-clusterWindow)).
+		Where("("+strings.Join(conds, " OR ")+")", args...).
+		Order("id").Find(&candidates).Error
+	if err != nil {
+		return err
+	}
+
+	for _, item := range pending {
+		match := findSameStory(item, candidates)
+		if match == nil {
+			continue
+		}
+		if match.ClusterID == nil {
+			match.ClusterID = ptr.To(match.ID)
+			if err := tx.Model(&model.Item{}).Where("id = ?", match.ID).Update("cluster_id", match.ClusterID).Error; err != nil {
+				return err
+			}
+		}
+		item.ClusterID = match.ClusterID
+		if err := tx.Model(&model.Item{}).Where("id = ?", item.ID).Update("cluster_id", item.ClusterID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findSameStory returns the oldest candidate with the same canonical link as
+// item, or else the oldest one with a near-identical title.
+func findSameStory(item *model.Item, candidates []*model.Item) *model.Item {
+	if link := ptr.From(item.CanonicalLink); link != "" {
+		for _, c := range candidates {
+			if ptr.From(c.CanonicalLink) == link {
+				return c
+			}
+		}
+	}
+	if item.TitleHash != 0 {
+		for _, c := range candidates {
+			if c.TitleHash != 0 && simhash.Distance(c.TitleHash, item.TitleHash) <= clusterMaxDistance {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// Revisions returns the previous versions of an item, newest first.
+func (i Item) Revisions(id uint) ([]*model.ItemRevision, error) {
+	res := make([]*model.ItemRevision, 0)
+	err := i.db.Where("item_id = ?", id).Order("id DESC").Find(&res).Error
+	return res, err
+}
+
+// BookmarkedIDs returns the IDs of all bookmarked items, oldest first.
+func (i Item) BookmarkedIDs() ([]uint, error) {
+	ids := make([]uint, 0)
+	err := i.db.Model(&model.Item{}).Where("bookmark = ?", true).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ListByFeed returns all items of a feed, oldest first.
+func (i Item) ListByFeed(feedID uint) ([]*model.Item, error) {
+	res := make([]*model.Item, 0)
+	err := i.db.Where("feed_id = ?", feedID).Order("id").Find(&res).Error
+	return res, err
+}
+
+// ListByKeys returns the items of a feed with one of the GUIDs or canonical
+// links.
+func (i Item) ListByKeys(feedID uint, guids, links []string) ([]*model.Item, error) {
+	res := make([]*model.Item, 0)
+	if len(guids) == 0 && len(links) == 0 {
+		return res, nil
+	}
+	query := i.db.Where("guid IN ?", guids)
+	if len(links) > 0 {
+		query = query.Or("canonical_link IN ?", links)
+	}
+	err := i.db.Where("feed_id = ?", feedID).Where(query).Find(&res).Error
+	return res, err
+}
+
+// Merge deletes the duplicates of keep and saves the GUID, unread and
+// bookmark state, including the bookmark time, of keep. The duplicates are
+// soft deleted, so that Changes reports them, and purged later. Shares of
+// the duplicates are moved to keep, so their links keep working.
+func (i Item) Merge(keep *model.Item, duplicates []uint) error {
+	return i.db.Transaction(func(tx *gorm.DB) error {
+		if len(duplicates) > 0 {
+			if err := tx.Where("item_id IN ?", duplicates).Delete(&model.ItemRevision{}).Error; err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err := tx.Unscoped().Model(&model.ItemShare{}).Where("item_id IN ?", duplicates).Update("item_id", keep.ID).Error; err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err := tx.Where("id IN ?", duplicates).Delete(&model.Item{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.Item{}).Where("id = ?", keep.ID).Updates(map[string]any{
+			"guid":          keep.GUID,
+			"unread":        keep.Unread,
+			"bookmark":      keep.Bookmark,
+			"bookmarked_at": keep.BookmarkedAt,
+		}).Error
+	})
 }
 
 func (i Item) Update(id uint, item *model.Item) error {
@@ -87,6 +775,47 @@ func (i Item) UpdateUnread(ids []uint, unread *bool) error {
 	return i.db.Model(&model.Item{}).Where("id IN ?", ids).Update("unread", unread).Error
 }
 
+// UpdateUnreadWhere is UpdateUnread for all items matching the filter.
+func (i Item) UpdateUnreadWhere(filter ItemFilter, unread *bool) error {
+	return i.db.Model(&model.Item{}).Where("id IN (?)", i.filter(filter).Select("items.id")).
+		Update("unread", unread).Error
+}
+
+// UpdateClusterUnread is UpdateUnread, but also updates the other items in
+// the clusters of the given items.
+func (i Item) UpdateClusterUnread(ids []uint, unread *bool) error {
+	clusters := i.db.Model(&model.Item{}).Select("cluster_id").Where("id IN ? AND cluster_id IS NOT NULL", ids)
+	return i.db.Model(&model.Item{}).Where("id IN ? OR cluster_id IN (?)", ids, clusters).Update("unread", unread).Error
+}
+
+// UnreadCounts returns the number of unread items by feed ID. Feeds without
+// unread items are left out.
+func (i Item) UnreadCounts() (map[uint]int, error) {
+	var rows []struct {
+		FeedID uint
+		Count  int
+	}
+	err := i.db.Model(&model.Item{}).Select("feed_id, COUNT(*) AS count").
+		Where("unread = ?", true).Group("feed_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		res[r.FeedID] = r.Count
+	}
+	return res, nil
+}
+
+// UpdateBookmark sets the bookmark state of an item. The bookmark time is
+// kept when the item already was bookmarked.
 func (i Item) UpdateBookmark(id uint, bookmark *bool) error {
-	return i.db.Model(&model.Item{}).Where("id = ?", id).Update("bookmark", bookmark).Error
+	var bookmarkedAt any
+	if ptr.From(bookmark) {
+		bookmarkedAt = gorm.Expr("CASE WHEN bookmark = ? THEN bookmarked_at ELSE ? END", true, time.Now())
+	}
+	return i.db.Model(&model.Item{}).Where("id = ?", id).Updates(map[string]any{
+		"bookmark":      bookmark,
+		"bookmarked_at": bookmarkedAt,
+	}).Error
 }
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewItemShare(db *gorm.DB) *ItemShare {
+	return &ItemShare{
+		db: db,
+	}
+}
+
+type ItemShare struct {
+	db *gorm.DB
+}
+
+// List returns the shares of an item, or of all items if itemID is nil,
+// newest first. Shares of deleted items have an empty Item.
+func (s ItemShare) List(itemID *uint) ([]*model.ItemShare, error) {
+	db := s.db.Preload("Item")
+	if itemID != nil {
+		db = db.Where("item_id = ?", *itemID)
+	}
+	var res []*model.ItemShare
+	err := db.Order("id DESC").Find(&res).Error
+	return res, err
+}
+
+// GetByToken returns a share with its item and the item's feed, including
+// expired shares.
+func (s ItemShare) GetByToken(token string) (*model.ItemShare, error) {
+	var res model.ItemShare
+	err := s.db.Preload("Item.Feed").Where("token = ?", token).First(&res).Error
+	return &res, err
+}
+
+func (s ItemShare) Create(share *model.ItemShare) error {
+	return s.db.Create(share).Error
+}
+
+func (s ItemShare) Delete(id uint) error {
+	return s.db.Delete(&model.ItemShare{}, id).Error
+}
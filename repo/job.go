@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+
+	"gorm.io/gorm"
+)
+
+func NewJob(db *gorm.DB) *Job {
+	return &Job{
+		db: db,
+	}
+}
+
+type Job struct {
+	db *gorm.DB
+}
+
+// List returns the latest jobs, newest first.
+func (j Job) List(limit int) ([]*model.Job, error) {
+	res := make([]*model.Job, 0)
+	err := j.db.Order("id desc").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (j Job) Get(id uint) (*model.Job, error) {
+	var res model.Job
+	err := j.db.First(&res, id).Error
+	return &res, err
+}
+
+func (j Job) Create(job *model.Job) error {
+	return j.db.Create(job).Error
+}
+
+func (j Job) Update(id uint, job *model.Job) error {
+	return j.db.Model(&model.Job{}).Where("id = ?", id).Updates(job).Error
+}
+
+// Interrupt fails the jobs that were queued or running when the server
+// stopped.
+func (j Job) Interrupt() error {
+	err := j.db.Model(&model.Job{}).Where("status IN ?", []string{model.JobQueued, model.JobRunning}).
+		Updates(&model.Job{
+			Status:     model.JobFailed,
+			Error:      ptr.To("interrupted by a restart"),
+			FinishedAt: ptr.To(time.Now()),
+		}).Error
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Prune keeps the latest keep jobs and deletes the older ones that ended.
+func (j Job) Prune(keep int) error {
+	err := j.db.Where("status NOT IN ?", []string{model.JobQueued, model.JobRunning}).Where(
+		"id NOT IN (?)",
+		j.db.Model(&model.Job{}).Select("id").Order("id desc").Limit(keep),
+	).Delete(&model.Job{}).Error
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
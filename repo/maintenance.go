@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"gorm.io/gorm"
+)
+
+func NewMaintenance(db *gorm.DB) *Maintenance {
+	return &Maintenance{
+		db: db,
+	}
+}
+
+type Maintenance struct {
+	db *gorm.DB
+}
+
+// Purge hard-deletes feeds, groups and items that were soft deleted before
+// the given time, and returns the number of removed rows. Revisions and
+// shares of the purged items are removed as well, but not counted. When
+// items are purged, before becomes the sync horizon, see Item.Changes.
+func (m Maintenance) Purge(before time.Time) (int64, error) {
+	var purged int64
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		purgedItems := tx.Unscoped().Model(&model.Item{}).Select("id").
+			Where("deleted_at > 0 AND deleted_at < ?", before.Unix())
+		if err := tx.Where("item_id IN (?)", purgedItems).Delete(&model.ItemRevision{}).Error; err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if err := tx.Unscoped().Where("item_id IN (?)", purgedItems).Delete(&model.ItemShare{}).Error; err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		for _, table := range []any{&model.Item{}, &model.Feed{}, &model.Group{}} {
+			result := tx.Unscoped().Where("deleted_at > 0 AND deleted_at < ?", before.Unix()).Delete(table)
+			if result.Error != nil && !errors.Is(result.Error, ErrNotFound) {
+				return result.Error
+			}
+			purged += result.RowsAffected
+			if _, ok := table.(*model.Item); ok && result.RowsAffected > 0 {
+				horizon := strconv.FormatInt(before.Unix(), 10)
+				if err := NewConfig(tx).Set(syncHorizonKey, horizon); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// Optimize returns free pages to the filesystem and updates the statistics
+// used by the query planner.
+//
+// SQLite databases are switched to incremental auto-vacuum on the first run,
+// which needs a full VACUUM once. After that, only the free pages are
+// released.
+func (m Maintenance) Optimize() error {
+	if m.db.Dialector.Name() == DriverPostgres {
+		return m.db.Exec("VACUUM ANALYZE").Error
+	}
+
+	// the auto-vacuum mode only changes with a VACUUM on the same connection
+	return m.db.Connection(func(conn *gorm.DB) error {
+		var autoVacuum int
+		if err := conn.Raw("PRAGMA auto_vacuum").Scan(&autoVacuum).Error; err != nil {
+			return err
+		}
+		// 2 is INCREMENTAL
+		if autoVacuum != 2 {
+			if err := conn.Exec("PRAGMA auto_vacuum = INCREMENTAL").Error; err != nil {
+				return err
+			}
+			if err := conn.Exec("VACUUM").Error; err != nil {
+				return err
+			}
+		} else if err := conn.Exec("PRAGMA incremental_vacuum").Error; err != nil {
+			return err
+		}
+		if err := conn.Exec("ANALYZE").Error; err != nil {
+			return err
+		}
+		// move the changes out of the WAL, so that the file sizes reflect them
+		return conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error
+	})
+}
+
+func (m Maintenance) CreateRun(run *model.MaintenanceRun) error {
+	return m.db.Create(run).Error
+}
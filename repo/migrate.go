@@ -0,0 +1,330 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/migrations"
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+// migration is a versioned schema change. Each migration runs in its own
+// transaction together with the insert into the schema_version table, so it
+// is either fully applied and recorded or not applied at all.
+//
+// A migration either runs Go code (up) or an embedded SQL file (file). skip
+// reports whether the change is already present, which is the case for
+// databases created by the AutoMigrate based setup of older versions.
+type migration struct {
+	version uint
+	name    string
+	up      func(tx *gorm.DB) error
+	file    string
+	skip    func(tx *gorm.DB) bool
+}
+
+// migrationList is the ordered list of all migrations. Versions must be
+// unique and increasing. Never edit or remove a migration once released, add
+// a new one instead.
+//
+// Migrations use the frozen structs of migrate_schema.go instead of the
+// models, so that they create the same schema however the models change.
+// create_tables is the baseline of the versioned schema. Databases created by
+// the AutoMigrate based setup of older versions may already have later
+// columns, so migrations that add columns skip the ones that exist.
+var migrationList = []migration{
+	{version: 1, name: "dedup_feed_links", up: dedupFeedLinks},
+	{version: 2, name: "create_tables", up: createTables(
+		&feedV2{}, &groupV2{}, &itemV2{}, &configV2{}, &fetchLogV2{},
+	)},
+	{version: 3, name: "add_favicon_path", file: "0003_add_favicon_path.sql",
+		skip: hasColumn(&feedV2{}, "favicon_path")},
+	{version: 4, name: "create_maintenance_runs", up: createTables(&maintenanceRunV4{})},
+	{version: 5, name: "create_item_revisions", up: createTables(&itemRevisionV5{})},
+	{version: 6, name: "add_feed_mark_unread_on_update", up: addColumns(&feedV6{}, "MarkUnreadOnUpdate")},
+	{version: 7, name: "add_fetch_log_items_updated", up: addColumns(&fetchLogV7{}, "ItemsUpdated")},
+	{version: 8, name: "add_feed_item_identity", up: addColumns(&feedV8{}, "ItemIdentity")},
+	{version: 9, name: "add_item_clusters", up: chain(
+		addColumns(&itemV9{}, "CanonicalLink", "TitleHash", "ClusterID"),
+		addIndexes(&itemV9{}, "CanonicalLink", "ClusterID"),
+	)},
+	{version: 10, name: "create_jobs", up: createTables(&jobV10{})},
+	{version: 11, name: "create_digest_runs", up: createTables(&digestRunV11{})},
+	{version: 12, name: "create_smart_folders", up: createTables(&smartFolderV12{})},
+	{version: 13, name: "create_published_feeds", up: createTables(&publishedFeedV13{})},
+	{version: 14, name: "create_item_shares", up: createTables(&itemShareV14{})},
+	{version: 15, name: "create_integrations", up: createTables(&integrationV15{})},
+	{version: 16, name: "add_feed_kind", up: addColumns(&feedV16{}, "Kind")},
+	{version: 17, name: "add_feed_mapping", up: addColumns(&feedV17{}, "Mapping")},
+	{version: 18, name: "drop_item_share_item_fk", up: chain(
+		dropConstraint(&itemShareV14{}, "fk_item_shares_item"),
+		// rebuilding the table on SQLite drops its indexes
+		addIndexes(&itemShareV14{}, "Token", "ItemID"),
+	)},
+	{version: 19, name: "add_item_updated_at_index", up: addIndexes(&itemV19{}, "UpdatedAt")},
+	{version: 20, name: "add_item_title_hash_band_indexes", up: execAll(
+		"CREATE INDEX IF NOT EXISTS idx_items_title_hash_band0 ON items ((title_hash & 65535))",
+		"CREATE INDEX IF NOT EXISTS idx_items_title_hash_band1 ON items (((title_hash >> 16) & 65535))",
+		"CREATE INDEX IF NOT EXISTS idx_items_title_hash_band2 ON items (((title_hash >> 32) & 65535))",
+		"CREATE INDEX IF NOT EXISTS idx_items_title_hash_band3 ON items (((title_hash >> 48) & 65535))",
+	)},
+	{version: 21, name: "add_bookmark_times", up: chain(
+		addColumns(&itemV21{}, "BookmarkedAt"),
+		addIndexes(&itemV21{}, "BookmarkedAt"),
+		addColumns(&digestRunV21{}, "BookmarkWatermark"),
+		// the bookmark times are unknown, the last update is the closest,
+		// and the digests so far covered the bookmarks until they ran
+		execAll(
+			"UPDATE items SET bookmarked_at = updated_at WHERE bookmark = true AND bookmarked_at IS NULL",
+			"UPDATE digest_runs SET bookmark_watermark = created_at WHERE bookmark_watermark IS NULL",
+		),
+	)},
+}
+
+// MigrationState is the state of a single migration.
+type MigrationState struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// version of fusion.
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of fusion supports")
+
+// LatestSchemaVersion returns the version of the last known migration.
+func LatestSchemaVersion() uint {
+	return migrationList[len(migrationList)-1].version
+}
+
+// SchemaVersion returns the highest migration version applied to DB, or 0
+// for a database that has never been migrated.
+func SchemaVersion() (uint, error) {
+	if !DB.Migrator().HasTable(&model.SchemaVersion{}) {
+		return 0, nil
+	}
+	var version uint
+	err := DB.Model(&model.SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Migrations returns the state of all known migrations in order. Migrations
+// that haven't been applied have a nil AppliedAt.
+func Migrations() ([]MigrationState, error) {
+	applied := make([]model.SchemaVersion, 0)
+	if DB.Migrator().HasTable(&model.SchemaVersion{}) {
+		if err := DB.Order("version").Find(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	appliedAt := make(map[uint]time.Time, len(applied))
+	for _, v := range applied {
+		appliedAt[v.Version] = v.AppliedAt
+	}
+
+	states := make([]MigrationState, 0, len(migrationList))
+	for _, m := range migrationList {
+		state := MigrationState{Version: m.version, Name: m.name}
+		if t, ok := appliedAt[m.version]; ok {
+			state.AppliedAt = &t
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// Migrate applies all pending migrations in order. It refuses to touch a
+// database whose schema is newer than LatestSchemaVersion.
+func Migrate() error {
+	if err := DB.Migrator().AutoMigrate(&model.SchemaVersion{}); err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if latest := LatestSchemaVersion(); current > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	states, err := Migrations()
+	if err != nil {
+		return err
+	}
+	for i, m := range migrationList {
+		if states[i].AppliedAt != nil {
+			continue
+		}
+		if err := applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d %s: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(m migration) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if m.skip == nil || !m.skip(tx) {
+			log.Printf("apply migration %d %s", m.version, m.name)
+			var err error
+			if m.file != "" {
+				err = execFile(tx, m.file)
+			} else {
+				err = m.up(tx)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&model.SchemaVersion{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+}
+
+// execFile runs an embedded SQL file, preferring the variant for the current
+// driver, e.g. 0004_foo.postgres.sql over 0004_foo.sql.
+func execFile(tx *gorm.DB, name string) error {
+	variant := strings.TrimSuffix(name, ".sql") + "." + tx.Dialector.Name() + ".sql"
+	data, err := fs.ReadFile(migrations.FS, variant)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = fs.ReadFile(migrations.FS, name)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Exec(string(data)).Error
+}
+
+// createTables creates the tables of models, or adds missing columns and
+// indexes to existing ones.
+func createTables(models ...any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Migrator().AutoMigrate(models...)
+	}
+}
+
+// addColumns adds the columns for the given fields of model that don't exist
+// yet.
+func addColumns(model any, fields ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, f := range fields {
+			if tx.Migrator().HasColumn(model, f) {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(model, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addIndexes creates the indexes declared on the given fields of model that
+// don't exist yet.
+func addIndexes(model any, fields ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, f := range fields {
+			if tx.Migrator().HasIndex(model, f) {
+				continue
+			}
+			if err := tx.Migrator().CreateIndex(model, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropConstraint drops the named constraint of the table of model if it
+// exists. SQLite rebuilds the table without its indexes.
+func dropConstraint(model any, name string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if !tx.Migrator().HasConstraint(model, name) {
+			return nil
+		}
+		return tx.Migrator().DropConstraint(model, name)
+	}
+}
+
+// execAll runs SQL statements in order.
+func execAll(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, s := range statements {
+			if err := tx.Exec(s).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// chain runs steps in order.
+func chain(steps ...func(tx *gorm.DB) error) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, step := range steps {
+			if err := step(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func hasColumn(model any, name string) func(tx *gorm.DB) bool {
+	return func(tx *gorm.DB) bool {
+		return tx.Migrator().HasColumn(model, name)
+	}
+}
+
+// dedupFeedLinks deletes duplicate feeds before create_tables adds the unique
+// index on Feed.Link, which was introduced after v0.8.7.
+func dedupFeedLinks(tx *gorm.DB) error {
+	// skip when it's the first launch
+	if !tx.Migrator().HasTable(&feedV2{}) || !tx.Migrator().HasTable(&itemV2{}) {
+		return nil
+	}
+
+	// query duplicate feeds
+	dupFeeds := make([]feedV2, 0)
+	err := tx.Model(&feedV2{}).Where(
+		"link IN (?)",
+		tx.Model(&feedV2{}).Select("link").Group("link").
+			Having("count(link) > 1"),
+	).Order("link, id").Find(&dupFeeds).Error
+	if err != nil {
+		return err
+	}
+
+	// filter out feeds that will be deleted.
+	// we've queried with order, so the first one is the one we should keep.
+	distinct := map[string]uint{}
+	deleteIDs := make([]uint, 0, len(dupFeeds))
+	for _, f := range dupFeeds {
+		if _, ok := distinct[*f.Link]; !ok {
+			distinct[*f.Link] = f.ID
+			continue
+		}
+		deleteIDs = append(deleteIDs, f.ID)
+		log.Println("delete duplicate feed: ", f.ID, *f.Name, *f.Link)
+	}
+
+	if len(deleteIDs) > 0 {
+		// **hard** delete duplicate feeds and their items
+		err = tx.Where("id IN ?", deleteIDs).Unscoped().Delete(&feedV2{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("feed_id IN ?", deleteIDs).Unscoped().Delete(&itemV2{}).Error
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package repo
+
+import (
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+)
+
+// The structs below freeze the schema each migration creates, so that a
+// migration keeps doing the same thing when the models in package model
+// change. Don't edit them, a change to the schema needs a new migration with
+// its own structs.
+
+// feedV2 is the feeds table of version 2.
+type feedV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_link"`
+
+	Name                *string    `gorm:"name;not null"`
+	Link                *string    `gorm:"link;not null;uniqueIndex:idx_link"`
+	LastBuild           *time.Time `gorm:"last_build"`
+	Failure             *string    `gorm:"failure;default:''"`
+	ConsecutiveFailures uint       `gorm:"consecutive_failures;default:0"`
+	FaviconPath         *string    `gorm:"favicon_path"`
+	Suspended           *bool      `gorm:"suspended;default:false"`
+	ReqProxy            *string    `gorm:"req_proxy"`
+
+	GroupID uint
+	Group   groupV2
+}
+
+func (feedV2) TableName() string {
+	return "feeds"
+}
+
+// groupV2 is the groups table of version 2.
+type groupV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_name"`
+
+	Name *string `gorm:"name;not null;uniqueIndex:idx_name"`
+}
+
+func (groupV2) TableName() string {
+	return "groups"
+}
+
+// itemV2 is the items table of version 2.
+type itemV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_guid"`
+
+	Title    *string    `gorm:"title"`
+	GUID     *string    `gorm:"guid;uniqueIndex:idx_guid"`
+	Link     *string    `gorm:"link"`
+	Content  *string    `gorm:"content"`
+	PubDate  *time.Time `gorm:"pub_date"`
+	Unread   *bool      `gorm:"unread;default:true;index"`
+	Bookmark *bool      `gorm:"bookmark;default:false;index"`
+
+	FeedID uint `gorm:"feed_id;uniqueIndex:idx_guid"`
+	Feed   feedV2
+}
+
+func (itemV2) TableName() string {
+	return "items"
+}
+
+// configV2 is the configs table of version 2.
+type configV2 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Key   string `gorm:"key;uniqueIndex;not null"`
+	Value string `gorm:"value;not null"`
+}
+
+func (configV2) TableName() string {
+	return "configs"
+}
+
+// fetchLogV2 is the fetch_logs table of version 2.
+type fetchLogV2 struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	FeedID      uint    `gorm:"feed_id;not null;index"`
+	StatusCode  int     `gorm:"status_code;default:0"`
+	Duration    int64   `gorm:"duration;default:0"`
+	Size        int64   `gorm:"size;default:0"`
+	ItemsParsed int     `gorm:"items_parsed;default:0"`
+	ItemsNew    int     `gorm:"items_new;default:0"`
+	Error       *string `gorm:"error;default:''"`
+}
+
+func (fetchLogV2) TableName() string {
+	return "fetch_logs"
+}
+
+// maintenanceRunV4 is the maintenance_runs table of version 4.
+type maintenanceRunV4 struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	Duration       int64   `gorm:"duration;default:0"`
+	PurgedRows     int64   `gorm:"purged_rows;default:0"`
+	ReclaimedBytes int64   `gorm:"reclaimed_bytes;default:0"`
+	Error          *string `gorm:"error;default:''"`
+}
+
+func (maintenanceRunV4) TableName() string {
+	return "maintenance_runs"
+}
+
+// itemRevisionV5 is the item_revisions table of version 5.
+type itemRevisionV5 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	ItemID  uint    `gorm:"item_id;not null;index"`
+	Title   *string `gorm:"title"`
+	Content *string `gorm:"content"`
+}
+
+func (itemRevisionV5) TableName() string {
+	return "item_revisions"
+}
+
+// feedV6 is the column of feeds added by version 6.
+type feedV6 struct {
+	MarkUnreadOnUpdate *bool `gorm:"mark_unread_on_update;default:false"`
+}
+
+func (feedV6) TableName() string {
+	return "feeds"
+}
+
+// fetchLogV7 is the column of fetch_logs added by version 7.
+type fetchLogV7 struct {
+	ItemsUpdated int `gorm:"items_updated;default:0"`
+}
+
+func (fetchLogV7) TableName() string {
+	return "fetch_logs"
+}
+
+// feedV8 is the column of feeds added by version 8.
+type feedV8 struct {
+	ItemIdentity *string `gorm:"item_identity;default:'auto'"`
+}
+
+func (feedV8) TableName() string {
+	return "feeds"
+}
+
+// itemV9 is the columns of items added by version 9.
+type itemV9 struct {
+	CanonicalLink *string `gorm:"canonical_link;index"`
+	TitleHash     int64   `gorm:"title_hash"`
+	ClusterID     *uint   `gorm:"cluster_id;index"`
+}
+
+func (itemV9) TableName() string {
+	return "items"
+}
+
+// jobV10 is the jobs table of version 10.
+type jobV10 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Kind       string  `gorm:"kind;index"`
+	Status     string  `gorm:"status;index"`
+	Done       int     `gorm:"done;default:0"`
+	Total      int     `gorm:"total;default:0"`
+	Error      *string `gorm:"error"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+func (jobV10) TableName() string {
+	return "jobs"
+}
+
+// digestRunV11 is the digest_runs table of version 11.
+type digestRunV11 struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+
+	Watermark uint    `gorm:"watermark;default:0"`
+	Items     int     `gorm:"items;default:0"`
+	Error     *string `gorm:"error;default:''"`
+}
+
+func (digestRunV11) TableName() string {
+	return "digest_runs"
+}
+
+// smartFolderV12 is the smart_folders table of version 12.
+type smartFolderV12 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_smart_folder_name"`
+
+	Name     *string `gorm:"name;not null;uniqueIndex:idx_smart_folder_name"`
+	Keyword  *string `gorm:"keyword"`
+	FeedIDs  []uint  `gorm:"feed_ids;serializer:json"`
+	GroupIDs []uint  `gorm:"group_ids;serializer:json"`
+	Unread   *bool   `gorm:"unread"`
+	Bookmark *bool   `gorm:"bookmark"`
+}
+
+func (smartFolderV12) TableName() string {
+	return "smart_folders"
+}
+
+// publishedFeedV13 is the published_feeds table of version 13.
+type publishedFeedV13 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+
+	Name     *string `gorm:"name;not null"`
+	Token    string  `gorm:"token;not null;uniqueIndex"`
+	Source   string  `gorm:"source;not null"`
+	SourceID uint    `gorm:"source_id"`
+}
+
+func (publishedFeedV13) TableName() string {
+	return "published_feeds"
+}
+
+// itemShareV14 is the item_shares table of version 14, with the foreign key
+// on item_id that version 18 drops.
+type itemShareV14 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+
+	Token     string     `gorm:"token;not null;uniqueIndex"`
+	ExpiresAt *time.Time `gorm:"expires_at"`
+
+	ItemID uint `gorm:"item_id;index"`
+	Item   shareItemV14
+}
+
+func (itemShareV14) TableName() string {
+	return "item_shares"
+}
+
+// shareItemV14 is the part of the items table the foreign key of
+// itemShareV14 refers to.
+type shareItemV14 struct {
+	ID uint `gorm:"primarykey"`
+}
+
+func (shareItemV14) TableName() string {
+	return "items"
+}
+
+// integrationV15 is the integrations table of version 15.
+type integrationV15 struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt `gorm:"uniqueIndex:idx_integration_name"`
+
+	Name          *string `gorm:"name;not null;uniqueIndex:idx_integration_name"`
+	Kind          string  `gorm:"kind;not null"`
+	Settings      string  `gorm:"settings;not null"`
+	SendBookmarks *bool   `gorm:"send_bookmarks;default:false"`
+}
+
+func (integrationV15) TableName() string {
+	return "integrations"
+}
+
+// feedV16 is the column of feeds added by version 16.
+type feedV16 struct {
+	Kind *string `gorm:"kind;default:'feed'"`
+}
+
+func (feedV16) TableName() string {
+	return "feeds"
+}
+
+// feedV17 is the column of feeds added by version 17.
+type feedV17 struct {
+	Mapping *itemMappingV17 `gorm:"mapping;serializer:json"`
+}
+
+func (feedV17) TableName() string {
+	return "feeds"
+}
+
+// itemMappingV17 is the JSON stored in feeds.mapping by version 17.
+type itemMappingV17 struct {
+	Items   string `json:"items"`
+	ID      string `json:"id,omitempty"`
+	Title   string `json:"title"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// itemV19 is the index of items added by version 19, for Item.Changes.
+type itemV19 struct {
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+func (itemV19) TableName() string {
+	return "items"
+}
+
+// itemV21 is the column of items added by version 21.
+type itemV21 struct {
+	BookmarkedAt *time.Time `gorm:"bookmarked_at;index"`
+}
+
+func (itemV21) TableName() string {
+	return "items"
+}
+
+// digestRunV21 is the column of digest_runs added by version 21.
+type digestRunV21 struct {
+	BookmarkWatermark *time.Time `gorm:"bookmark_watermark"`
+}
+
+func (digestRunV21) TableName() string {
+	return "digest_runs"
+}
@@ -0,0 +1,153 @@
+package repo_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+)
+
+// legacyFeed is the feeds table as created by AutoMigrate before v0.8.7:
+// without the unique index on link and without favicon_path.
+type legacyFeed struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+	Name      *string
+	Link      *string
+	GroupID   uint
+}
+
+func (legacyFeed) TableName() string {
+	return "feeds"
+}
+
+func TestMigrateFresh(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		version, err := repo.SchemaVersion()
+		require.NoError(t, err)
+		assert.Equal(t, repo.LatestSchemaVersion(), version)
+
+		states, err := repo.Migrations()
+		require.NoError(t, err)
+		for _, s := range states {
+			assert.NotNil(t, s.AppliedAt, "migration %d %s should be applied", s.Version, s.Name)
+		}
+
+		require.NoError(t, repo.Migrate(), "migrating again should be a no-op")
+
+		// the migrations freeze their own schema, which must keep up with
+		// the models
+		for _, m := range []any{
+			&model.Feed{}, &model.Group{}, &model.Item{}, &model.Config{}, &model.FetchLog{},
+			&model.MaintenanceRun{}, &model.ItemRevision{}, &model.Job{}, &model.DigestRun{},
+			&model.SmartFolder{}, &model.PublishedFeed{}, &model.ItemShare{}, &model.Integration{},
+		} {
+			s, err := schema.Parse(m, &sync.Map{}, repo.DB.NamingStrategy)
+			require.NoError(t, err)
+			for _, f := range s.Fields {
+				if f.DBName != "" {
+					assert.True(t, repo.DB.Migrator().HasColumn(m, f.DBName), "%s.%s should exist", s.Table, f.DBName)
+				}
+			}
+		}
+	})
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	require.NoError(t, repo.Open(repo.DriverSQLite, filepath.Join(t.TempDir(), "fusion.db")))
+	t.Cleanup(func() { repo.Close() })
+
+	require.NoError(t, repo.DB.AutoMigrate(&legacyFeed{}, &model.Item{}))
+	feeds := []*legacyFeed{
+		{Name: ptr.To("a"), Link: ptr.To("https://example.com/feed"), GroupID: 1},
+		{Name: ptr.To("b"), Link: ptr.To("https://example.com/feed"), GroupID: 1},
+		{Name: ptr.To("c"), Link: ptr.To("https://example.org/feed"), GroupID: 1},
+	}
+	require.NoError(t, repo.DB.Create(feeds).Error)
+	require.NoError(t, repo.DB.Create(&model.Item{FeedID: feeds[1].ID, GUID: ptr.To("1")}).Error)
+
+	states, err := repo.Migrations()
+	require.NoError(t, err)
+	for _, s := range states {
+		assert.Nil(t, s.AppliedAt, "migration %d %s should be pending", s.Version, s.Name)
+	}
+
+	require.NoError(t, repo.Migrate())
+
+	var ids []uint
+	require.NoError(t, repo.DB.Model(&model.Feed{}).Order("id").Pluck("id", &ids).Error)
+	assert.Equal(t, []uint{feeds[0].ID, feeds[2].ID}, ids, "the newer duplicate feed should be deleted")
+	var items int64
+	require.NoError(t, repo.DB.Model(&model.Item{}).Unscoped().Count(&items).Error)
+	assert.Zero(t, items, "items of deleted feeds should be deleted")
+	assert.True(t, repo.DB.Migrator().HasColumn(&model.Feed{}, "favicon_path"))
+
+	version, err := repo.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, repo.LatestSchemaVersion(), version)
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		require.NoError(t, repo.DB.Create(&model.SchemaVersion{
+			Version: repo.LatestSchemaVersion() + 1,
+			Name:    "from_the_future",
+		}).Error)
+
+		assert.ErrorIs(t, repo.Migrate(), repo.ErrSchemaTooNew)
+	})
+}
+
+// legacyItemShare is the item_shares table as created by version 14, with a
+// foreign key on item_id.
+type legacyItemShare struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt soft_delete.DeletedAt
+	Token     string `gorm:"token;not null;uniqueIndex"`
+	ExpiresAt *time.Time
+	ItemID    uint `gorm:"item_id;index"`
+	Item      model.Item
+}
+
+func (legacyItemShare) TableName() string {
+	return "item_shares"
+}
+
+func TestMigrateDropItemShareConstraint(t *testing.T) {
+	forEachDriver(t, func(t *testing.T) {
+		// go back to a database migrated by version 17
+		require.NoError(t, repo.DB.Migrator().DropTable("item_shares"))
+		require.NoError(t, repo.DB.AutoMigrate(&legacyItemShare{}))
+		require.NoError(t, repo.DB.Where("version > ?", 17).Delete(&model.SchemaVersion{}).Error)
+		require.True(t, repo.DB.Migrator().HasConstraint(&model.ItemShare{}, "fk_item_shares_item"))
+
+		item := &model.Item{FeedID: 1, GUID: ptr.To("1")}
+		require.NoError(t, repo.DB.Create(item).Error)
+		require.NoError(t, repo.DB.Create(&legacyItemShare{Token: "kept", ItemID: item.ID}).Error)
+
+		require.NoError(t, repo.Migrate())
+		assert.False(t, repo.DB.Migrator().HasConstraint(&model.ItemShare{}, "fk_item_shares_item"))
+		share, err := repo.NewItemShare(repo.DB).GetByToken("kept")
+		require.NoError(t, err)
+		assert.Equal(t, item.ID, share.ItemID)
+
+		assert.True(t, repo.DB.Migrator().HasIndex(&model.ItemShare{}, "Token"), "SQLite keeps the indexes")
+		assert.True(t, repo.DB.Migrator().HasIndex(&model.ItemShare{}, "ItemID"))
+
+		// a shared item can be deleted for good
+		require.NoError(t, repo.DB.Unscoped().Delete(&model.Item{}, item.ID).Error)
+	})
+}
@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewPublishedFeed(db *gorm.DB) *PublishedFeed {
+	return &PublishedFeed{
+		db: db,
+	}
+}
+
+type PublishedFeed struct {
+	db *gorm.DB
+}
+
+func (p PublishedFeed) All() ([]*model.PublishedFeed, error) {
+	var res []*model.PublishedFeed
+	err := p.db.Order("name").Find(&res).Error
+	return res, err
+}
+
+func (p PublishedFeed) Get(id uint) (*model.PublishedFeed, error) {
+	var res model.PublishedFeed
+	err := p.db.First(&res, id).Error
+	return &res, err
+}
+
+func (p PublishedFeed) GetByToken(token string) (*model.PublishedFeed, error) {
+	var res model.PublishedFeed
+	err := p.db.Where("token = ?", token).First(&res).Error
+	return &res, err
+}
+
+func (p PublishedFeed) Create(feed *model.PublishedFeed) error {
+	return p.db.Create(feed).Error
+}
+
+// Update replaces the name and source of a published feed. The token is
+// kept.
+func (p PublishedFeed) Update(id uint, feed *model.PublishedFeed) error {
+	return p.db.Model(&model.PublishedFeed{}).Where("id = ?", id).
+		Select("name", "source", "source_id").Updates(feed).Error
+}
+
+func (p PublishedFeed) UpdateToken(id uint, token string) error {
+	return p.db.Model(&model.PublishedFeed{}).Where("id = ?", id).Update("token", token).Error
+}
+
+func (p PublishedFeed) Delete(id uint) error {
+	return p.db.Delete(&model.PublishedFeed{}, id).Error
+}
@@ -1,84 +1,131 @@
 package repo
 
 import (
+	"context"
 	"errors"
-	"log"
+	"fmt"
+	"strings"
 
 	"github.com/Sudo-Ivan/fusionx/model"
 
 	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func Init(dbPath string) {
+// Logger is the logger used by DB. It must be set before Init.
+var Logger = logger.Default
+
+// Supported database drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// ResolveDriver returns the driver to use for dsn. An empty driver is
+// detected from the DSN: PostgreSQL URLs and key/value strings select
+// postgres, anything else is treated as an SQLite file path.
+func ResolveDriver(driver, dsn string) (string, error) {
+	switch driver {
+	case DriverSQLite, DriverPostgres:
+		return driver, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported database driver %q", driver)
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") ||
+		strings.Contains(dsn, "host=") || strings.Contains(dsn, "dbname=") {
+		return DriverPostgres, nil
+	}
+	return DriverSQLite, nil
+}
+
+// Init opens the database, applies pending migrations and seeds the default
+// data. It panics on failure.
+func Init(driver, dsn string) {
+	if err := Open(driver, dsn); err != nil {
+		panic(err)
+	}
+	if err := Migrate(); err != nil {
+		panic(err)
+	}
+	seed()
+	registerCallback()
+}
+
+// Open connects DB to the database without migrating it.
+func Open(driver, dsn string) error {
+	driver, err := ResolveDriver(driver, dsn)
+	if err != nil {
+		return err
+	}
+
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		dialector = sqlite.Open(sqliteDSN(dsn))
+	}
+
 	conn, err := gorm.Open(
-		sqlite.Open(dbPath),
-		&gorm.Config{TranslateError: true},
+		dialector,
+		&gorm.Config{TranslateError: true, Logger: Logger},
 	)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	DB = conn
-
-	migrage()
-	registerCallback()
+	return nil
 }
 
-func migrage() {
-	// The verison after v0.8.7 will add a unique index to Feed.Link.
-	// We must delete any duplicate feeds before AutoMigrate applies the
-	// new unique constraint.
-	err := DB.Transaction(func(tx *gorm.DB) error {
-		// skip when it's the first launch
-		if !tx.Migrator().HasTable(&model.Feed{}) || !tx.Migrator().HasTable(&model.Item{}) {
-			return nil
-		}
+// sqliteDSN adds the connection settings for concurrent use to dsn:
+//   - WAL lets readers continue while a pull or the API writes, and makes
+//     writes cheaper with synchronous=NORMAL.
+//   - busy_timeout makes SQLite wait for locks held by other connections,
+//     e.g. the CLI and the server writing at the same time, instead of
+//     failing immediately with "database is locked".
+func sqliteDSN(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+}
 
-		// query duplicate feeds
-		dupFeeds := make([]model.Feed, 0)
-		err := tx.Model(&model.Feed{}).Where(
-			"link IN (?)",
-			tx.Model(&model.Feed{}).Select("link").Group("link").
-				Having("count(link) > 1"),
-		).Order("link, id").Find(&dupFeeds).Error
-		if err != nil {
-			return err
-		}
+// Driver returns the driver of DB.
+func Driver() string {
+	return DB.Dialector.Name()
+}
 
-		// filter out feeds that will be deleted.
-		// we've queried with order, so the first one is the one we should keep.
-		distinct := map[string]uint{}
-		deleteIDs := make([]uint, 0, len(dupFeeds))
-		for _, f := range dupFeeds {
-			if _, ok := distinct[*f.Link]; !ok {
-				distinct[*f.Link] = f.ID
-				continue
-			}
-			deleteIDs = append(deleteIDs, f.ID)
-			log.Println("delete duplicate feed: ", f.ID, *f.Name, *f.Link)
-		}
+// Vacuum rebuilds the database file to reclaim unused space.
+func Vacuum() error {
+	return DB.Exec("VACUUM").Error
+}
 
-		if len(deleteIDs) > 0 {
-			// **hard** delete duplicate feeds and their items
-			err = tx.Where("id IN ?", deleteIDs).Unscoped().Delete(&model.Feed{}).Error
-			if err != nil {
-				return err
-			}
-			return tx.Where("feed_id IN ?", deleteIDs).Unscoped().Delete(&model.Item{}).Error
-		}
-		return nil
-	})
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	sqlDB, err := DB.DB()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return sqlDB.PingContext(ctx)
+}
 
-	// FIX: gorm not auto drop index and change 'not null'
-	if err := DB.AutoMigrate(&model.Feed{}, &model.Group{}, &model.Item{}, &model.Config{}); err != nil {
-		panic(err)
+// Close closes the database connection.
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.Close()
+}
 
+func seed() {
 	defaultGroup := "Default"
 	if err := DB.Model(&model.Group{}).Where("id = ?", 1).
 		FirstOrCreate(&model.Group{ID: 1, Name: &defaultGroup}).Error; err != nil {
@@ -0,0 +1,44 @@
+package repo
+
+import (
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"gorm.io/gorm"
+)
+
+func NewSmartFolder(db *gorm.DB) *SmartFolder {
+	return &SmartFolder{
+		db: db,
+	}
+}
+
+type SmartFolder struct {
+	db *gorm.DB
+}
+
+func (s SmartFolder) All() ([]*model.SmartFolder, error) {
+	var res []*model.SmartFolder
+	err := s.db.Order("name").Find(&res).Error
+	return res, err
+}
+
+func (s SmartFolder) Get(id uint) (*model.SmartFolder, error) {
+	var res model.SmartFolder
+	err := s.db.First(&res, id).Error
+	return &res, err
+}
+
+func (s SmartFolder) Create(folder *model.SmartFolder) error {
+	return s.db.Create(folder).Error
+}
+
+// Update replaces the name and all conditions of a smart folder, so that
+// conditions can be removed.
+func (s SmartFolder) Update(id uint, folder *model.SmartFolder) error {
+	return s.db.Model(&model.SmartFolder{}).Where("id = ?", id).
+		Select("name", "keyword", "feed_ids", "group_ids", "unread", "bookmark").Updates(folder).Error
+}
+
+func (s SmartFolder) Delete(id uint) error {
+	return s.db.Delete(&model.SmartFolder{}, id).Error
+}
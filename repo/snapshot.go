@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Snapshot writes a consistent copy of the database to path while other
+// connections may keep writing. path must not exist. Only SQLite is
+// supported, use pg_dump for PostgreSQL.
+func Snapshot(path string) error {
+	if Driver() != DriverSQLite {
+		return fmt.Errorf("snapshot: %w", ErrUnsupported)
+	}
+	return DB.Exec("VACUUM INTO ?", path).Error
+}
+
+// CheckSnapshot checks the integrity of the SQLite database at path and
+// returns its schema version. It fails if the schema is newer than
+// LatestSchemaVersion.
+func CheckSnapshot(path string) (uint, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return 0, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return 0, err
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	if !db.Migrator().HasTable(&model.SchemaVersion{}) {
+		return 0, fmt.Errorf("%s is not a fusion database", path)
+	}
+	var version uint
+	err = db.Model(&model.SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+	if latest := LatestSchemaVersion(); version > latest {
+		return 0, fmt.Errorf("%w: backup is at version %d, latest known is %d", ErrSchemaTooNew, version, latest)
+	}
+	return version, nil
+}
@@ -34,6 +34,19 @@ func (s Stats) GetTotalUnreadItems() (int, error) {
 	return int(count), err
 }
 
+// GetDatabaseSize returns the size of the database in bytes.
+func (s Stats) GetDatabaseSize() (int64, error) {
+	var size int64
+	var err error
+	switch s.db.Dialector.Name() {
+	case DriverPostgres:
+		err = s.db.Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+	default:
+		err = s.db.Raw("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size).Error
+	}
+	return size, err
+}
+
 func (s Stats) GetTotalGroups() (int, error) {
 	var count int64
 	err := s.db.Model(&model.Group{}).Count(&count).Error
@@ -58,3 +71,70 @@ func (s Stats) GetFailedFeeds() (int, error) {
 	err := s.db.Model(&model.Feed{}).Where("failure != '' AND failure IS NOT NULL").Count(&count).Error
 	return int(count), err
 }
+
+// FeedStateCounts is the number of feeds in each state. A suspended feed is
+// only counted as suspended, even if its last fetch failed.
+type FeedStateCounts struct {
+	OK        int
+	Failing   int
+	Suspended int
+}
+
+func (s Stats) GetFeedStates() (FeedStateCounts, error) {
+	var rows []struct {
+		State string `gorm:"state"`
+		Count int64  `gorm:"count"`
+	}
+	err := s.db.Model(&model.Feed{}).
+		Select("CASE WHEN suspended = ? THEN 'suspended' "+
+			"WHEN failure != '' AND failure IS NOT NULL THEN 'failing' "+
+			"ELSE 'ok' END AS state, count(*) AS count", true).
+		Group("state").Find(&rows).Error
+	if err != nil {
+		return FeedStateCounts{}, err
+	}
+
+	var res FeedStateCounts
+	for _, r := range rows {
+		switch r.State {
+		case "suspended":
+			res.Suspended = int(r.Count)
+		case "failing":
+			res.Failing = int(r.Count)
+		default:
+			res.OK = int(r.Count)
+		}
+	}
+	return res, nil
+}
+
+// MaintenanceSummary sums up all runs of the maintenance job.
+type MaintenanceSummary struct {
+	LastRun        *time.Time
+	PurgedRows     int64
+	ReclaimedBytes int64
+}
+
+func (s Stats) GetMaintenanceSummary() (MaintenanceSummary, error) {
+	var last model.MaintenanceRun
+	err := s.db.Model(&model.MaintenanceRun{}).Order("id DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotFound) {
+		return MaintenanceSummary{}, nil
+	}
+	if err != nil {
+		return MaintenanceSummary{}, err
+	}
+
+	var totals struct {
+		PurgedRows     int64 `gorm:"purged_rows"`
+		ReclaimedBytes int64 `gorm:"reclaimed_bytes"`
+	}
+	err = s.db.Model(&model.MaintenanceRun{}).
+		Select("COALESCE(SUM(purged_rows), 0) AS purged_rows, COALESCE(SUM(reclaimed_bytes), 0) AS reclaimed_bytes").
+		Scan(&totals).Error
+	return MaintenanceSummary{
+		LastRun:        &last.CreatedAt,
+		PurgedRows:     totals.PurgedRows,
+		ReclaimedBytes: totals.ReclaimedBytes,
+	}, err
+}
@@ -50,6 +50,10 @@ build_backend() {
     -ldflags '-extldflags "-static"' \
     -o ./build/fusion \
     ./cmd/server/*
+  CGO_ENABLED=0 GOOS=${target_os} GOARCH=${target_arch} go build \
+    -ldflags '-extldflags "-static"' \
+    -o ./build/fusionx \
+    ./cmd/fusionx
 }
 
 build() {
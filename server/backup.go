@@ -0,0 +1,32 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/backup"
+)
+
+type Backup struct {
+	demoMode bool
+}
+
+func NewBackup(demoMode bool) *Backup {
+	return &Backup{
+		demoMode: demoMode,
+	}
+}
+
+// Snapshot takes a consistent copy of the database for an archive. The
+// caller must close it.
+func (b Backup) Snapshot() (*backup.Snapshot, error) {
+	if b.demoMode {
+		return nil, NewBizError(errors.New("backup in demo mode"), http.StatusForbidden, "backups are disabled in demo mode")
+	}
+	snapshot, err := backup.NewSnapshot()
+	if errors.Is(err, repo.ErrUnsupported) {
+		return nil, NewBizError(err, http.StatusNotImplemented, "backups are only supported for SQLite, use pg_dump for PostgreSQL")
+	}
+	return snapshot, err
+}
@@ -2,7 +2,12 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"time"
+
+	"github.com/Sudo-Ivan/fusionx/auth"
+	"github.com/Sudo-Ivan/fusionx/repo"
 )
 
 const (
@@ -11,6 +16,10 @@ const (
 
 	ConfigKeyReadingPaneMode = "reading_pane_mode"
 	DefaultReadingPaneMode   = "default" // "default", "3pane", "drawer"
+
+	// ConfigKeyPasswordHash stores a password set from the CLI. It takes
+	// precedence over the PASSWORD environment variable.
+	ConfigKeyPasswordHash = "password_hash"
 )
 
 type ConfigRepo interface {
@@ -82,3 +91,40 @@ func (c *Config) Update(ctx context.Context, req *ReqConfigUpdate) error {
 func (c *Config) GetFeedRefreshInterval() (time.Duration, error) {
 	return c.repo.GetDuration(ConfigKeyFeedRefreshInterval, DefaultFeedRefreshInterval)
 }
+
+// PasswordHash returns the password hash stored in the database, or nil if
+// none was set.
+func (c *Config) PasswordHash() (*auth.HashedPassword, error) {
+	value, err := c.repo.Get(ConfigKeyPasswordHash)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	hash, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	hp := auth.NewHashedPassword(hash)
+	return &hp, nil
+}
+
+// SetPassword stores the hash of password in the database. An empty password
+// removes the stored one, so that the PASSWORD environment variable applies
+// again.
+func (c *Config) SetPassword(password string) error {
+	if password == "" {
+		return c.repo.Set(ConfigKeyPasswordHash, "")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return c.repo.Set(ConfigKeyPasswordHash, hex.EncodeToString(hash.Bytes()))
+}
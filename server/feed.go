@@ -9,8 +9,11 @@ import (
 
 	"github.com/0x2E/feedfinder"
 	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
 	"github.com/Sudo-Ivan/fusionx/repo"
 	"github.com/Sudo-Ivan/fusionx/service/favicon"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+	"github.com/Sudo-Ivan/fusionx/service/newsletter"
 	"github.com/Sudo-Ivan/fusionx/service/pull"
 	"github.com/Sudo-Ivan/fusionx/service/pull/client"
 )
@@ -23,15 +26,28 @@ type FeedRepo interface {
 	Delete(id uint) error
 }
 
+type FetchLogRepo interface {
+	List(feedID uint, limit int) ([]*model.FetchLog, error)
+}
+
 type Feed struct {
-	repo        FeedRepo
-	faviconSvc  *favicon.Service
+	repo         FeedRepo
+	fetchLogRepo FetchLogRepo
+	faviconSvc   *favicon.Service
+	// jobs runs refreshes of all feeds and creates of several feeds
+	jobs *jobs.Manager
+	// newsletterDomain is the domain of the addresses of newsletters, empty
+	// if they are disabled.
+	newsletterDomain string
 }
 
-func NewFeed(repo FeedRepo) *Feed {
+func NewFeed(repo FeedRepo, fetchLogRepo FetchLogRepo, jobs *jobs.Manager, newsletterDomain string) *Feed {
 	return &Feed{
-		repo:       repo,
-		faviconSvc: favicon.NewService("./cache/favicons"),
+		repo:             repo,
+		fetchLogRepo:     fetchLogRepo,
+		faviconSvc:       favicon.NewService(favicon.CacheDir),
+		jobs:             jobs,
+		newsletterDomain: newsletterDomain,
 	}
 }
 
@@ -51,9 +67,14 @@ func (f Feed) List(ctx context.Context, req *ReqFeedList) (*RespFeedList, error)
 			ID:                  v.ID,
 			Name:                v.Name,
 			Link:                v.Link,
+			Kind:                v.Kind,
+			Address:             f.address(v),
+			Mapping:             newItemMappingForm(v.Mapping),
 			Failure:             v.Failure,
 			Suspended:           v.Suspended,
 			ReqProxy:            v.ReqProxy,
+			MarkUnreadOnUpdate:  v.MarkUnreadOnUpdate,
+			ItemIdentity:        v.ItemIdentity,
 			UpdatedAt:           v.UpdatedAt,
 			UnreadCount:         v.UnreadCount,
 			ConsecutiveFailures: v.ConsecutiveFailures,
@@ -75,26 +96,75 @@ func (f Feed) Get(ctx context.Context, req *ReqFeedGet) (*RespFeedGet, error) {
 		ID:                  data.ID,
 		Name:                data.Name,
 		Link:                data.Link,
+		Kind:                data.Kind,
+		Address:             f.address(data),
+		Mapping:             newItemMappingForm(data.Mapping),
 		Failure:             data.Failure,
 		Suspended:           data.Suspended,
 		ReqProxy:            data.ReqProxy,
+		MarkUnreadOnUpdate:  data.MarkUnreadOnUpdate,
+		ItemIdentity:        data.ItemIdentity,
 		UpdatedAt:           data.UpdatedAt,
 		ConsecutiveFailures: data.ConsecutiveFailures,
 		Group:               GroupForm{ID: data.GroupID, Name: data.Group.Name},
 	}, nil
 }
 
+func (f Feed) History(ctx context.Context, req *ReqFeedHistory) (*RespFeedHistory, error) {
+	// make sure the feed exists, so that a missing feed is a 404 rather than
+	// an empty history
+	if _, err := f.repo.Get(req.ID); err != nil {
+		return nil, err
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+	data, err := f.fetchLogRepo.List(req.ID, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*FetchLogForm, 0, len(data))
+	for _, v := range data {
+		history = append(history, &FetchLogForm{
+			ID:           v.ID,
+			CreatedAt:    v.CreatedAt,
+			StatusCode:   v.StatusCode,
+			Duration:     v.Duration,
+			Size:         v.Size,
+			ItemsParsed:  v.ItemsParsed,
+			ItemsNew:     v.ItemsNew,
+			ItemsUpdated: v.ItemsUpdated,
+			Error:        v.Error,
+		})
+	}
+	return &RespFeedHistory{
+		History: history,
+	}, nil
+}
+
 func (f Feed) Create(ctx context.Context, req *ReqFeedCreate) (*RespFeedCreate, error) {
 	feeds := make([]*model.Feed, 0, len(req.Feeds))
 	for _, r := range req.Feeds {
-		feeds = append(feeds, &model.Feed{
+		feed := &model.Feed{
 			Name: r.Name,
 			Link: r.Link,
+			Kind: ptr.To(model.FeedKindFeed),
 			FeedRequestOptions: model.FeedRequestOptions{
 				ReqProxy: r.RequestOptions.Proxy,
 			},
 			GroupID: req.GroupID,
-		})
+		}
+		if r.Kind == model.FeedKindScrape || r.Kind == model.FeedKindJSON {
+			mapping, err := newItemMapping(r.Kind, r.Mapping)
+			if err != nil {
+				return nil, err
+			}
+			feed.Kind = &r.Kind
+			feed.Mapping = mapping
+		}
+		feeds = append(feeds, feed)
 	}
 
 	if err := f.repo.Create(feeds); err != nil {
@@ -111,43 +181,148 @@ func (f Feed) Create(ctx context.Context, req *ReqFeedCreate) (*RespFeedCreate,
 		IDs: ids,
 	}
 
-	puller := pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), nil)
-	
-	// Cache favicons for all feeds
-	go func() {
+	puller := pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), nil, repo.NewFetchLog(repo.DB))
+	if len(feeds) == 1 {
+		go f.cacheFavicon(feeds[0])
+		return resp, puller.PullOne(ctx, feeds[0].ID)
+	}
+
+	job, err := f.jobs.Submit(JobImport, func(ctx context.Context, p *jobs.Progress) error {
+		p.SetTotal(len(feeds))
+		routinePool := make(chan struct{}, 10)
+		wg := sync.WaitGroup{}
 		for _, feed := range feeds {
-			if feed.Link != nil {
-				if faviconPath, err := f.faviconSvc.GetFaviconPath(*feed.Link); err == nil {
-					// Update feed with favicon path
-					// #nosec G104 - favicon update is non-critical, error can be ignored
-					_ = f.repo.Update(feed.ID, &model.Feed{FaviconPath: &faviconPath})
-				}
+			select {
+			case routinePool <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return ctx.Err()
 			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					p.Add(1)
+					<-routinePool
+					wg.Done()
+				}()
+				f.cacheFavicon(feed)
+				// #nosec G104 - Feed pull errors are logged by puller and recorded in the feed
+				puller.PullOne(ctx, feed.ID)
+			}()
 		}
-	}()
-	
-	if len(feeds) > 1 {
-		go func() {
-			routinePool := make(chan struct{}, 10)
-			defer close(routinePool)
-			wg := sync.WaitGroup{}
-			for _, feed := range feeds {
-				routinePool <- struct{}{}
-				wg.Add(1)
-					go func() {
-						// NOTE: do not use the incoming ctx, as it will be Done() automatically
-						// by api timeout middleware
-						// #nosec G104 - Feed pull errors are logged by puller, don't block feed creation
-						puller.PullOne(context.Background(), feed.ID)
-						<-routinePool
-						wg.Done()
-					}()
-			}
-			wg.Wait()
-		}()
-		return resp, nil
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.JobID = &job.ID
+	return resp, nil
+}
+
+// CreateNewsletter creates a newsletter feed with a new email address.
+func (f Feed) CreateNewsletter(ctx context.Context, req *ReqNewsletterCreate) (*RespNewsletterCreate, error) {
+	if f.newsletterDomain == "" {
+		return nil, NewBizError(errors.New("newsletters are disabled"), http.StatusBadRequest,
+			"newsletters are disabled, set NEWSLETTER_ADDR and NEWSLETTER_DOMAIN to receive them")
+	}
+	feed := &model.Feed{
+		Name:    &req.Name,
+		Link:    ptr.To(newsletter.NewLink()),
+		Kind:    ptr.To(model.FeedKindNewsletter),
+		GroupID: req.GroupID,
+	}
+	if err := f.repo.Create([]*model.Feed{feed}); err != nil {
+		return nil, err
+	}
+	return &RespNewsletterCreate{
+		ID:      feed.ID,
+		Address: newsletter.Address(feed, f.newsletterDomain),
+	}, nil
+}
+
+// address returns the email address of a newsletter.
+func (f Feed) address(feed *model.Feed) *string {
+	if !feed.IsNewsletter() || f.newsletterDomain == "" {
+		return nil
+	}
+	return ptr.To(newsletter.Address(feed, f.newsletterDomain))
+}
+
+// Preview reads the items of a scraped page without saving them.
+func (f Feed) Preview(ctx context.Context, req *ReqFeedPreview) (*RespFeedPreview, error) {
+	mapping, err := newItemMapping(req.Kind, &req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	fetchItems := client.NewScraper(*mapping).FetchItems
+	if req.Kind == model.FeedKindJSON {
+		fetchItems = client.NewJSONClient(*mapping).FetchItems
+	}
+	options := model.FeedRequestOptions{ReqProxy: req.RequestOptions.Proxy}
+	result, err := fetchItems(ctx, req.Link, options)
+	if err != nil {
+		return nil, NewBizError(err, http.StatusBadRequest, "failed to read the page: "+err.Error())
+	}
+
+	items := make([]PreviewItem, 0, len(result.Items))
+	for _, v := range result.Items {
+		items = append(items, PreviewItem{
+			Title:   v.Title,
+			Link:    v.Link,
+			PubDate: v.PubDate,
+			Content: v.Content,
+		})
+	}
+	return &RespFeedPreview{Items: items}, nil
+}
+
+// newItemMapping checks the selectors of a scraped page, or the paths of a
+// JSON API.
+func newItemMapping(kind string, form *ItemMappingForm) (*model.ItemMapping, error) {
+	if form == nil {
+		return nil, NewBizError(errors.New("missing mapping"), http.StatusBadRequest, "scraped pages and JSON APIs need a mapping")
+	}
+	mapping := &model.ItemMapping{
+		Items:   form.Items,
+		ID:      form.ID,
+		Title:   form.Title,
+		Link:    form.Link,
+		Date:    form.Date,
+		Content: form.Content,
+	}
+	validate := client.ValidateScrapeMapping
+	if kind == model.FeedKindJSON {
+		validate = client.ValidateJSONMapping
+	}
+	if err := validate(*mapping); err != nil {
+		return nil, NewBizError(err, http.StatusBadRequest, err.Error())
+	}
+	return mapping, nil
+}
+
+func newItemMappingForm(mapping *model.ItemMapping) *ItemMappingForm {
+	if mapping == nil {
+		return nil
+	}
+	return &ItemMappingForm{
+		Items:   mapping.Items,
+		ID:      mapping.ID,
+		Title:   mapping.Title,
+		Link:    mapping.Link,
+		Date:    mapping.Date,
+		Content: mapping.Content,
+	}
+}
+
+func (f Feed) cacheFavicon(feed *model.Feed) {
+	if feed.Link == nil {
+		return
+	}
+	if faviconPath, err := f.faviconSvc.GetFaviconPath(*feed.Link); err == nil {
+		// #nosec G104 - favicon update is non-critical, error can be ignored
+		_ = f.repo.Update(feed.ID, &model.Feed{FaviconPath: &faviconPath})
 	}
-	return resp, puller.PullOne(ctx, feeds[0].ID)
 }
 
 func (f Feed) CheckValidity(ctx context.Context, req *ReqFeedCheckValidity) (*RespFeedCheckValidity, error) {
@@ -186,9 +361,11 @@ func (f Feed) CheckValidity(ctx context.Context, req *ReqFeedCheckValidity) (*Re
 
 func (f Feed) Update(ctx context.Context, req *ReqFeedUpdate) error {
 	data := &model.Feed{
-		Name:      req.Name,
-		Link:      req.Link,
-		Suspended: req.Suspended,
+		Name:               req.Name,
+		Link:               req.Link,
+		Suspended:          req.Suspended,
+		MarkUnreadOnUpdate: req.MarkUnreadOnUpdate,
+		ItemIdentity:       req.ItemIdentity,
 		FeedRequestOptions: model.FeedRequestOptions{
 			ReqProxy: req.ReqProxy,
 		},
@@ -196,10 +373,40 @@ func (f Feed) Update(ctx context.Context, req *ReqFeedUpdate) error {
 	if req.GroupID != nil {
 		data.GroupID = *req.GroupID
 	}
+
+	identityChanged := false
+	if req.ItemIdentity != nil || req.Mapping != nil {
+		old, err := f.repo.Get(req.ID)
+		if err != nil {
+			return err
+		}
+		if req.ItemIdentity != nil {
+			identityChanged = ptr.From(old.ItemIdentity) != *req.ItemIdentity
+		}
+		if req.Mapping != nil {
+			if !old.HasMapping() {
+				return NewBizError(errors.New("feed has no mapping"), http.StatusBadRequest, "only scraped pages and JSON APIs have a mapping")
+			}
+			if data.Mapping, err = newItemMapping(ptr.From(old.Kind), req.Mapping); err != nil {
+				return err
+			}
+		}
+	}
+
 	err := f.repo.Update(req.ID, data)
 	if errors.Is(err, repo.ErrDuplicatedKey) {
 		err = NewBizError(err, http.StatusBadRequest, "link is not allowed to be the same as other feeds")
 	}
+	if err != nil || !identityChanged {
+		return err
+	}
+
+	// re-key the stored items, otherwise the next pull adds them again
+	feed, err := f.repo.Get(req.ID)
+	if err != nil {
+		return err
+	}
+	_, err = pull.RepairItemIdentity(repo.NewItem(repo.DB), feed)
 	return err
 }
 
@@ -207,15 +414,21 @@ func (f Feed) Delete(ctx context.Context, req *ReqFeedDelete) error {
 	return f.repo.Delete(req.ID)
 }
 
-func (f Feed) Refresh(ctx context.Context, req *ReqFeedRefresh) error {
-	pull := pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), nil)
+// Refresh pulls one feed, or starts a job that pulls all feeds.
+func (f Feed) Refresh(ctx context.Context, req *ReqFeedRefresh) (*RespFeedRefresh, error) {
+	puller := pull.NewPuller(repo.NewFeed(repo.DB), repo.NewItem(repo.DB), nil, repo.NewFetchLog(repo.DB))
 	if req.ID != nil {
-		return pull.PullOne(ctx, *req.ID)
+		return &RespFeedRefresh{}, puller.PullOne(ctx, *req.ID)
+	}
+	if req.All == nil || !*req.All {
+		return &RespFeedRefresh{}, nil
 	}
-	if req.All != nil && *req.All {
-		// NOTE: do not use the incoming ctx, as it will be Done() automatically
-		// by api timeout middleware
-		go pull.PullAll(context.Background(), true)
+
+	job, err := f.jobs.Submit(JobRefresh, func(ctx context.Context, p *jobs.Progress) error {
+		return puller.PullAll(ctx, true, p)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &RespFeedRefresh{JobID: &job.ID}, nil
 }
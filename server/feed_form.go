@@ -6,13 +6,33 @@ type FeedForm struct {
 	ID                  uint      `json:"id"`
 	Name                *string   `json:"name"`
 	Link                *string   `json:"link"`
+	Kind                *string   `json:"kind"`
 	Failure             *string   `json:"failure"`
 	Suspended           *bool     `json:"suspended"`
 	ReqProxy            *string   `json:"req_proxy"`
+	MarkUnreadOnUpdate  *bool     `json:"mark_unread_on_update"`
+	ItemIdentity        *string   `json:"item_identity"`
 	UpdatedAt           time.Time `json:"updated_at"`
 	UnreadCount         int       `json:"unread_count"`
 	ConsecutiveFailures uint      `json:"consecutive_failures"`
 	Group               GroupForm `json:"group"`
+	// Address is the email address of a newsletter, nil for other feeds or
+	// when newsletters are disabled.
+	Address *string `json:"address"`
+	// Mapping finds the items of a scraped page or a JSON API, nil for other
+	// feeds.
+	Mapping *ItemMappingForm `json:"mapping"`
+}
+
+// ItemMappingForm holds the CSS selectors of a scraped page, see
+// client.Scraper, or the paths of a JSON API, see client.JSONClient.
+type ItemMappingForm struct {
+	Items   string `json:"items"`
+	ID      string `json:"id"`
+	Title   string `json:"title" validate:"required"`
+	Link    string `json:"link"`
+	Date    string `json:"date"`
+	Content string `json:"content"`
 }
 
 type ReqFeedList struct {
@@ -53,21 +73,61 @@ type ReqFeedCreate struct {
 		Name           *string            `json:"name" validate:"required"`
 		Link           *string            `json:"link" validate:"required"`
 		RequestOptions FeedRequestOptions `json:"request_options"`
+		// Kind is "feed" if empty, scraped pages and JSON APIs need a
+		// Mapping.
+		Kind    string           `json:"kind" validate:"omitempty,oneof=feed scrape json"`
+		Mapping *ItemMappingForm `json:"mapping"`
 	} `json:"feeds" validate:"required"`
 	GroupID uint `json:"group_id" validate:"required"`
 }
 
 type RespFeedCreate struct {
 	IDs []uint `json:"ids"`
+	// JobID is the job that pulls the feeds when several were created.
+	JobID *uint `json:"job_id,omitempty"`
+}
+
+// ReqFeedPreview reads the items of a source that isn't a feed, to check
+// its mapping before saving it.
+type ReqFeedPreview struct {
+	Link           string             `json:"link" validate:"required"`
+	Kind           string             `json:"kind" validate:"required,oneof=scrape json"`
+	Mapping        ItemMappingForm    `json:"mapping"`
+	RequestOptions FeedRequestOptions `json:"request_options"`
+}
+
+type PreviewItem struct {
+	Title   *string    `json:"title"`
+	Link    *string    `json:"link"`
+	PubDate *time.Time `json:"pub_date"`
+	Content *string    `json:"content"`
+}
+
+type RespFeedPreview struct {
+	Items []PreviewItem `json:"items"`
+}
+
+type ReqNewsletterCreate struct {
+	Name    string `json:"name" validate:"required"`
+	GroupID uint   `json:"group_id" validate:"required"`
+}
+
+type RespNewsletterCreate struct {
+	ID      uint   `json:"id"`
+	Address string `json:"address"`
 }
 
 type ReqFeedUpdate struct {
-	ID        uint    `param:"id" validate:"required"`
-	Name      *string `json:"name"`
-	Link      *string `json:"link"`
-	Suspended *bool   `json:"suspended"`
-	ReqProxy  *string `json:"req_proxy"`
-	GroupID   *uint   `json:"group_id"`
+	ID                 uint    `param:"id" validate:"required"`
+	Name               *string `json:"name"`
+	Link               *string `json:"link"`
+	Suspended          *bool   `json:"suspended"`
+	ReqProxy           *string `json:"req_proxy"`
+	MarkUnreadOnUpdate *bool   `json:"mark_unread_on_update"`
+	ItemIdentity       *string `json:"item_identity" validate:"omitempty,oneof=auto link content"`
+	GroupID            *uint   `json:"group_id"`
+	// Mapping is only allowed for scraped pages and JSON APIs.
+	Mapping *ItemMappingForm `json:"mapping"`
 }
 
 type ReqFeedDelete struct {
@@ -78,3 +138,29 @@ type ReqFeedRefresh struct {
 	ID  *uint `json:"id"`
 	All *bool `json:"all"`
 }
+
+type RespFeedRefresh struct {
+	// JobID is the job that pulls all feeds.
+	JobID *uint `json:"job_id,omitempty"`
+}
+
+type FetchLogForm struct {
+	ID           uint      `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	StatusCode   int       `json:"status_code"`
+	Duration     int64     `json:"duration_ms"`
+	Size         int64     `json:"bytes"`
+	ItemsParsed  int       `json:"items_parsed"`
+	ItemsNew     int       `json:"items_new"`
+	ItemsUpdated int       `json:"items_updated"`
+	Error        *string   `json:"error"`
+}
+
+type ReqFeedHistory struct {
+	ID    uint `param:"id" validate:"required"`
+	Limit int  `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type RespFeedHistory struct {
+	History []*FetchLogForm `json:"history"`
+}
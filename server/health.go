@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// HealthCheckFn reports an error when a component is unhealthy.
+type HealthCheckFn func(ctx context.Context) error
+
+type Health struct {
+	// shutdown is done once the server starts shutting down.
+	shutdown context.Context
+	database HealthCheckFn
+	puller   HealthCheckFn
+}
+
+func NewHealth(shutdown context.Context, database, puller HealthCheckFn) *Health {
+	return &Health{
+		shutdown: shutdown,
+		database: database,
+		puller:   puller,
+	}
+}
+
+// Live reports whether the process is working. A stuck puller can only be
+// recovered by a restart, so it's part of liveness.
+func (h Health) Live(ctx context.Context) (*RespHealth, int) {
+	return h.check(ctx, map[string]HealthCheckFn{
+		"puller": h.puller,
+	})
+}
+
+// Ready reports whether the server can handle requests.
+func (h Health) Ready(ctx context.Context) (*RespHealth, int) {
+	if h.shutdown.Err() != nil {
+		return &RespHealth{Status: "shutting down", Checks: map[string]string{}}, http.StatusServiceUnavailable
+	}
+	return h.check(ctx, map[string]HealthCheckFn{
+		"database": h.database,
+		"puller":   h.puller,
+	})
+}
+
+func (h Health) check(ctx context.Context, checks map[string]HealthCheckFn) (*RespHealth, int) {
+	resp := &RespHealth{
+		Status: "ok",
+		Checks: make(map[string]string, len(checks)),
+	}
+	code := http.StatusOK
+	for name, fn := range checks {
+		if fn == nil {
+			continue
+		}
+		if err := fn(ctx); err != nil {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	return resp, code
+}
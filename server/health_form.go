@@ -0,0 +1,6 @@
+package server
+
+type RespHealth struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
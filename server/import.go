@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sudo-Ivan/fusionx/service/importer"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+)
+
+type Import struct {
+	importer *importer.Importer
+	jobs     *jobs.Manager
+}
+
+func NewImport(feedRepo importer.FeedRepo, groupRepo importer.GroupRepo, itemRepo importer.ItemRepo, manager *jobs.Manager) *Import {
+	return &Import{
+		importer: importer.NewImporter(feedRepo, groupRepo, itemRepo),
+		jobs:     manager,
+	}
+}
+
+// Create parses the files exported by another reader and starts a job that
+// imports them.
+func (i Import) Create(ctx context.Context, req *ReqImportCreate) (*RespImportCreate, error) {
+	data, err := importer.Parse(req.Format, req.Files)
+	if err != nil {
+		return nil, NewBizError(err, http.StatusBadRequest, err.Error())
+	}
+	opts := importer.Options{History: req.History}
+	job, err := i.jobs.Submit(JobImport, func(ctx context.Context, p *jobs.Progress) error {
+		res, err := i.importer.Run(ctx, data, opts, p)
+		slog.Info("imported from another reader", "format", req.Format, "groups", res.Groups,
+			"feeds", res.Feeds, "bookmarks", res.Bookmarks, "history", res.History)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RespImportCreate{JobID: job.ID}, nil
+}
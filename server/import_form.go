@@ -0,0 +1,17 @@
+package server
+
+import "github.com/Sudo-Ivan/fusionx/service/importer"
+
+type ReqImportCreate struct {
+	Format string `form:"format" validate:"required,oneof=miniflux freshrss greader ttrss"`
+	// History also imports the items that aren't starred, marked read.
+	History bool `form:"history"`
+	// Files are the uploaded exports.
+	Files []importer.File `form:"-" json:"-"`
+}
+
+// RespImportCreate has the ID of the import job. The job's progress is in
+// entries.
+type RespImportCreate struct {
+	JobID uint `json:"job_id"`
+}
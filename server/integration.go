@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/integration"
+)
+
+type IntegrationRepo interface {
+	All() ([]*model.Integration, error)
+	Get(id uint) (*model.Integration, error)
+	Create(integration *model.Integration) error
+	Update(id uint, integration *model.Integration) error
+	Delete(id uint) error
+}
+
+type IntegrationItemRepo interface {
+	Get(id uint) (*model.Item, error)
+}
+
+var errNoSecretKey = errors.New("no secret key")
+
+type Integration struct {
+	repo     IntegrationRepo
+	itemRepo IntegrationItemRepo
+	// box is nil when there is neither a password nor a secret key.
+	box *secret.Box
+}
+
+func NewIntegration(repo IntegrationRepo, itemRepo IntegrationItemRepo, box *secret.Box) *Integration {
+	return &Integration{
+		repo:     repo,
+		itemRepo: itemRepo,
+		box:      box,
+	}
+}
+
+func (i Integration) Kinds(ctx context.Context) *RespIntegrationKinds {
+	return &RespIntegrationKinds{Kinds: integration.Kinds}
+}
+
+func (i Integration) All(ctx context.Context) (*RespIntegrationAll, error) {
+	data, err := i.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*IntegrationForm, 0, len(data))
+	for _, v := range data {
+		form := &IntegrationForm{
+			ID:            v.ID,
+			Name:          v.Name,
+			Kind:          v.Kind,
+			Settings:      integration.Settings{},
+			SendBookmarks: v.SendBookmarks,
+		}
+		// integrations whose settings can't be decrypted are still listed,
+		// so that they can be fixed or deleted
+		if kind, err := integration.FindKind(v.Kind); err == nil && i.box != nil {
+			if s, err := integration.Unseal(i.box, v); err == nil {
+				form.Settings = kind.Public(s)
+			}
+		}
+		res = append(res, form)
+	}
+	return &RespIntegrationAll{Integrations: res}, nil
+}
+
+func (i Integration) Create(ctx context.Context, req *ReqIntegrationCreate) (*RespIntegrationCreate, error) {
+	settings, err := i.seal(req.Kind, req.Settings)
+	if err != nil {
+		return nil, err
+	}
+	in := &model.Integration{
+		Name:          req.Name,
+		Kind:          req.Kind,
+		Settings:      settings,
+		SendBookmarks: req.SendBookmarks,
+	}
+	if err := i.repo.Create(in); err != nil {
+		if errors.Is(err, repo.ErrDuplicatedKey) {
+			err = NewBizError(err, http.StatusBadRequest, "name is not allowed to be the same as other integrations")
+		}
+		return nil, err
+	}
+	return &RespIntegrationCreate{ID: in.ID}, nil
+}
+
+func (i Integration) Update(ctx context.Context, req *ReqIntegrationUpdate) error {
+	old, err := i.repo.Get(req.ID)
+	if err != nil {
+		return err
+	}
+	kind, err := integration.FindKind(old.Kind)
+	if err != nil {
+		return err
+	}
+
+	s := make(integration.Settings, len(req.Settings))
+	for k, v := range req.Settings {
+		s[k] = v
+	}
+	// the API never returns secrets, so empty ones mean unchanged. If the old
+	// settings can't be decrypted, the secrets have to be entered again.
+	if i.box != nil {
+		if oldSettings, err := integration.Unseal(i.box, old); err == nil {
+			for _, f := range kind.Fields {
+				if f.Secret && s[f.Name] == "" {
+					s[f.Name] = oldSettings[f.Name]
+				}
+			}
+		}
+	}
+
+	settings, err := i.seal(old.Kind, s)
+	if err != nil {
+		return err
+	}
+	err = i.repo.Update(req.ID, &model.Integration{
+		Name:          req.Name,
+		Settings:      settings,
+		SendBookmarks: req.SendBookmarks,
+	})
+	if errors.Is(err, repo.ErrDuplicatedKey) {
+		err = NewBizError(err, http.StatusBadRequest, "name is not allowed to be the same as other integrations")
+	}
+	return err
+}
+
+func (i Integration) Delete(ctx context.Context, req *ReqIntegrationDelete) error {
+	return i.repo.Delete(req.ID)
+}
+
+// Send sends an item to an integration.
+func (i Integration) Send(ctx context.Context, req *ReqItemSend) error {
+	if i.box == nil {
+		return i.noSecretKey()
+	}
+	in, err := i.repo.Get(req.Integration)
+	if err != nil {
+		return err
+	}
+	item, err := i.itemRepo.Get(req.ID)
+	if err != nil {
+		return err
+	}
+	sender, err := integration.Open(i.box, in)
+	if err != nil {
+		return NewBizError(err, http.StatusBadRequest, err.Error())
+	}
+	if err := sender.Send(ctx, integration.NewEntry(item)); err != nil {
+		return NewBizError(err, http.StatusBadGateway, err.Error())
+	}
+	return nil
+}
+
+// seal checks the settings of a kind and encrypts them.
+func (i Integration) seal(kind string, s integration.Settings) (string, error) {
+	if i.box == nil {
+		return "", i.noSecretKey()
+	}
+	if _, err := integration.New(kind, s); err != nil {
+		if errors.Is(err, integration.ErrUnknownKind) {
+			return "", NewBizError(err, http.StatusBadRequest, "unknown integration kind")
+		}
+		return "", NewBizError(err, http.StatusBadRequest, err.Error())
+	}
+	// New succeeded, so the kind exists
+	k, _ := integration.FindKind(kind)
+	known := make(integration.Settings, len(k.Fields))
+	for _, f := range k.Fields {
+		if v := strings.TrimSpace(s[f.Name]); v != "" {
+			known[f.Name] = v
+		}
+	}
+	return integration.Seal(i.box, known)
+}
+
+func (i Integration) noSecretKey() error {
+	return NewBizError(errNoSecretKey, http.StatusBadRequest, "integrations need a secret key, set SECRET_KEY or SECRET_KEY_FILE")
+}
@@ -0,0 +1,49 @@
+package server
+
+import "github.com/Sudo-Ivan/fusionx/service/integration"
+
+type IntegrationForm struct {
+	ID   uint    `json:"id"`
+	Name *string `json:"name"`
+	Kind string  `json:"kind"`
+	// Settings don't include secret fields.
+	Settings      integration.Settings `json:"settings"`
+	SendBookmarks *bool                `json:"send_bookmarks"`
+}
+
+type RespIntegrationAll struct {
+	Integrations []*IntegrationForm `json:"integrations"`
+}
+
+type RespIntegrationKinds struct {
+	Kinds []*integration.Kind `json:"kinds"`
+}
+
+type ReqIntegrationCreate struct {
+	Name          *string              `json:"name" validate:"required"`
+	Kind          string               `json:"kind" validate:"required"`
+	Settings      integration.Settings `json:"settings"`
+	SendBookmarks *bool                `json:"send_bookmarks"`
+}
+
+type RespIntegrationCreate struct {
+	ID uint `json:"id"`
+}
+
+// ReqIntegrationUpdate replaces the name, settings and options of an
+// integration. Empty secret fields keep their current values.
+type ReqIntegrationUpdate struct {
+	ID            uint                 `param:"id" validate:"required"`
+	Name          *string              `json:"name" validate:"required"`
+	Settings      integration.Settings `json:"settings"`
+	SendBookmarks *bool                `json:"send_bookmarks"`
+}
+
+type ReqIntegrationDelete struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type ReqItemSend struct {
+	ID          uint `param:"id" validate:"required"`
+	Integration uint `param:"integration" validate:"required"`
+}
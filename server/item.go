@@ -2,36 +2,61 @@ package server
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
+	"time"
 
 	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
 	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+	"github.com/Sudo-Ivan/fusionx/service/export"
+
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 type ItemRepo interface {
 	List(filter repo.ItemFilter, page, pageSize int) ([]*model.Item, int, error)
+	ListAfter(filter repo.ItemFilter, cursor string, limit int) ([]*model.Item, string, error)
+	Changes(since string, limit int) (*repo.ItemChanges, error)
 	Get(id uint) (*model.Item, error)
 	Delete(id uint) error
 	UpdateUnread(ids []uint, unread *bool) error
+	UpdateUnreadWhere(filter repo.ItemFilter, unread *bool) error
+	UpdateClusterUnread(ids []uint, unread *bool) error
+	ClusterMembers(clusterIDs []uint) ([]*model.Item, error)
 	UpdateBookmark(id uint, bookmark *bool) error
+	Revisions(id uint) ([]*model.ItemRevision, error)
+}
+
+// ArchiveStore keeps the archived pages of bookmarked items.
+type ArchiveStore interface {
+	Read(id uint) ([]byte, error)
+	Exists(id uint) bool
+	Remove(id uint) error
 }
 
 type Item struct {
-	repo ItemRepo
+	repo       ItemRepo
+	folderRepo SmartFolderRepo
+	archives   ArchiveStore
 }
 
-func NewItem(repo ItemRepo) *Item {
+func NewItem(repo ItemRepo, folderRepo SmartFolderRepo, archives ArchiveStore) *Item {
 	return &Item{
-		repo: repo,
+		repo:       repo,
+		folderRepo: folderRepo,
+		archives:   archives,
 	}
 }
 
 func (i Item) List(ctx context.Context, req *ReqItemList) (*RespItemList, error) {
-	filter := repo.ItemFilter{
-		Keyword:  req.Keyword,
-		FeedID:   req.FeedID,
-		GroupID:  req.GroupID,
-		Unread:   req.Unread,
-		Bookmark: req.Bookmark,
+	filter, err := i.filter(&req.ReqItemFilter)
+	if err != nil {
+		return nil, err
 	}
 	if req.Page == 0 {
 		req.Page = 1
@@ -39,18 +64,140 @@ func (i Item) List(ctx context.Context, req *ReqItemList) (*RespItemList, error)
 	if req.PageSize == 0 {
 		req.PageSize = 10
 	}
-	data, total, err := i.repo.List(filter, req.Page, req.PageSize)
-	if err != nil {
-		return nil, err
+
+	var data []*model.Item
+	var total *int
+	var nextCursor *string
+	if req.Cursor != nil {
+		var next string
+		var err error
+		data, next, err = i.repo.ListAfter(filter, *req.Cursor, req.PageSize)
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			err = NewBizError(err, http.StatusBadRequest, "invalid cursor")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next != "" {
+			nextCursor = &next
+		}
+	} else {
+		var count int
+		var err error
+		data, count, err = i.repo.List(filter, req.Page, req.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+
+	var alsoIn map[uint][]*model.Item
+	if req.Collapse {
+		var err error
+		alsoIn, err = i.clusterMembers(data)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	items := make([]*ItemForm, 0, len(data))
 	for _, v := range data {
+		form := &ItemForm{
+			ID:        v.ID,
+			GUID:      v.GUID,
+			Title:     v.Title,
+			Link:      v.Link,
+			Unread:    v.Unread,
+			Bookmark:  v.Bookmark,
+			PubDate:   v.PubDate,
+			UpdatedAt: &v.UpdatedAt,
+			Feed: ItemFeed{
+				ID:   v.Feed.ID,
+				Name: v.Feed.Name,
+				Link: v.Feed.Link,
+			},
+			ClusterID: v.ClusterID,
+		}
+		if v.ClusterID != nil {
+			for _, m := range alsoIn[*v.ClusterID] {
+				if m.ID == v.ID || m.FeedID == v.FeedID || slices.ContainsFunc(form.AlsoIn, func(f ItemFeed) bool {
+					return f.ID == m.FeedID
+				}) {
+					continue
+				}
+				form.AlsoIn = append(form.AlsoIn, ItemFeed{ID: m.Feed.ID, Name: m.Feed.Name, Link: m.Feed.Link})
+			}
+		}
+		items = append(items, form)
+	}
+	return &RespItemList{
+		Total:      total,
+		Items:      items,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// maxExportItems is the most items exported at once.
+const maxExportItems = 10000
+
+// Export writes the items matching the filters of the request to a file.
+func (i Item) Export(ctx context.Context, req *ReqItemExport) (*RespItemExport, error) {
+	filter, err := i.filter(&req.ReqItemFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*model.Item, 0)
+	cursor := ""
+	for {
+		page, next, err := i.repo.ListAfter(filter, cursor, 500)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if len(items) > maxExportItems {
+			return nil, NewBizError(errors.New("too many items to export"), http.StatusBadRequest, "too many items to export, narrow the filters")
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	now := time.Now()
+	return &RespItemExport{
+		ContentType: export.ContentType(req.Format),
+		Filename:    export.Filename(req.Format, now),
+		Write: func(ctx context.Context, w io.Writer) error {
+			return export.Write(ctx, w, req.Format, items, export.Options{Created: now})
+		},
+	}, nil
+}
+
+// Changes returns what changed since the client's last sync.
+func (i Item) Changes(ctx context.Context, req *ReqItemChanges) (*RespItemChanges, error) {
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+	data, err := i.repo.Changes(req.ChangedSince, req.Limit)
+	if errors.Is(err, repo.ErrInvalidCursor) {
+		err = NewBizError(err, http.StatusBadRequest, "invalid changed_since token")
+	}
+	if errors.Is(err, repo.ErrSyncExpired) {
+		err = NewBizError(err, http.StatusGone, "changed_since token expired, sync again from scratch")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*ItemForm, 0, len(data.Items))
+	for _, v := range data.Items {
 		items = append(items, &ItemForm{
 			ID:        v.ID,
 			GUID:      v.GUID,
 			Title:     v.Title,
 			Link:      v.Link,
+			Content:   v.Content,
 			Unread:    v.Unread,
 			Bookmark:  v.Bookmark,
 			PubDate:   v.PubDate,
@@ -60,14 +207,36 @@ func (i Item) List(ctx context.Context, req *ReqItemList) (*RespItemList, error)
 				Name: v.Feed.Name,
 				Link: v.Feed.Link,
 			},
+			ClusterID: v.ClusterID,
 		})
 	}
-	return &RespItemList{
-		Total: &total,
-		Items: items,
+	return &RespItemChanges{
+		Items:   items,
+		Deleted: data.Deleted,
+		Next:    data.Next,
+		HasMore: data.HasMore,
 	}, nil
 }
 
+// clusterMembers returns the items of the clusters of items, by cluster ID.
+func (i Item) clusterMembers(items []*model.Item) (map[uint][]*model.Item, error) {
+	ids := make([]uint, 0)
+	for _, v := range items {
+		if v.ClusterID != nil && !slices.Contains(ids, *v.ClusterID) {
+			ids = append(ids, *v.ClusterID)
+		}
+	}
+	members, err := i.repo.ClusterMembers(ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint][]*model.Item, len(ids))
+	for _, m := range members {
+		res[*m.ClusterID] = append(res[*m.ClusterID], m)
+	}
+	return res, nil
+}
+
 func (i Item) Get(ctx context.Context, req *ReqItemGet) (*RespItemGet, error) {
 	data, err := i.repo.Get(req.ID)
 	if err != nil {
@@ -89,17 +258,155 @@ func (i Item) Get(ctx context.Context, req *ReqItemGet) (*RespItemGet, error) {
 			Name: data.Feed.Name,
 			Link: data.Feed.Link,
 		},
+		ClusterID: data.ClusterID,
+		Archived:  i.archives.Exists(data.ID),
 	}, nil
 }
 
+// Archive returns the archived page of an item.
+func (i Item) Archive(ctx context.Context, req *ReqItemArchive) (*RespItemArchive, error) {
+	data, err := i.archives.Read(req.ID)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = NewBizError(err, http.StatusNotFound, "item has no archive")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &RespItemArchive{Body: data}, nil
+}
+
 func (i Item) Delete(ctx context.Context, req *ReqItemDelete) error {
-	return i.repo.Delete(req.ID)
+	if err := i.repo.Delete(req.ID); err != nil {
+		return err
+	}
+	return i.archives.Remove(req.ID)
 }
 
 func (i Item) UpdateUnread(ctx context.Context, req *ReqItemUpdateUnread) error {
-	return i.repo.UpdateUnread(req.IDs, req.Unread)
+	var err error
+	if req.SmartFolderID != nil {
+		var within *repo.ItemFilter
+		within, err = i.smartFolderFilter(*req.SmartFolderID)
+		if err != nil {
+			return err
+		}
+		err = i.repo.UpdateUnreadWhere(*within, req.Unread)
+		if errors.Is(err, repo.ErrNotFound) {
+			// nothing to update
+			err = nil
+		}
+	} else if req.Cluster {
+		err = i.repo.UpdateClusterUnread(req.IDs, req.Unread)
+	} else {
+		err = i.repo.UpdateUnread(req.IDs, req.Unread)
+	}
+	if err != nil {
+		return err
+	}
+	events.Publish(events.ItemsReadChanged, events.ReadChange{
+		IDs:           req.IDs,
+		Unread:        *req.Unread,
+		Cluster:       req.Cluster,
+		SmartFolderID: req.SmartFolderID,
+	})
+	return nil
+}
+
+// filter returns the repo filter of the filters of a request.
+func (i Item) filter(req *ReqItemFilter) (repo.ItemFilter, error) {
+	filter := repo.ItemFilter{
+		Keyword:  req.Keyword,
+		FeedID:   req.FeedID,
+		GroupID:  req.GroupID,
+		Unread:   req.Unread,
+		Bookmark: req.Bookmark,
+		Collapse: req.Collapse,
+		Sort:     repo.ItemSort(req.Sort),
+	}
+	if req.SmartFolderID != nil {
+		within, err := i.smartFolderFilter(*req.SmartFolderID)
+		if err != nil {
+			return filter, err
+		}
+		filter.Within = within
+	}
+	return filter, nil
 }
 
+// smartFolderFilter returns the conditions of a smart folder. A missing
+// folder is a bad request rather than a missing resource.
+func (i Item) smartFolderFilter(id uint) (*repo.ItemFilter, error) {
+	folder, err := i.folderRepo.Get(id)
+	if errors.Is(err, repo.ErrNotFound) {
+		err = NewBizError(err, http.StatusBadRequest, "smart folder not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter := smartFolderFilter(folder)
+	return &filter, nil
+}
+
+// UpdateBookmark sets the bookmark state of an item and publishes
+// events.ItemBookmarkChanged if it changed, which e.g. sends new bookmarks to
+// integrations.
 func (i Item) UpdateBookmark(ctx context.Context, req *ReqItemUpdateBookmark) error {
-	return i.repo.UpdateBookmark(req.ID, req.Bookmark)
+	before, err := i.repo.Get(req.ID)
+	if err != nil {
+		return err
+	}
+	if err := i.repo.UpdateBookmark(req.ID, req.Bookmark); err != nil {
+		return err
+	}
+	if ptr.From(before.Bookmark) != ptr.From(req.Bookmark) {
+		events.Publish(events.ItemBookmarkChanged, events.BookmarkChange{ID: req.ID, Bookmark: ptr.From(req.Bookmark)})
+	}
+	return nil
+}
+
+// Revisions returns the previous versions of an item, newest first, each with
+// a diff to the version that replaced it.
+func (i Item) Revisions(ctx context.Context, req *ReqItemRevisions) (*RespItemRevisions, error) {
+	current, err := i.repo.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := i.repo.Revisions(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*ItemRevisionForm, 0, len(data))
+	newer := &model.ItemRevision{Title: current.Title, Content: current.Content}
+	for _, v := range data {
+		diff, err := revisionDiff(v, newer)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &ItemRevisionForm{
+			ID:         v.ID,
+			ReplacedAt: v.CreatedAt,
+			Title:      v.Title,
+			Content:    v.Content,
+			Diff:       diff,
+		})
+		newer = v
+	}
+	return &RespItemRevisions{
+		Revisions: revisions,
+	}, nil
+}
+
+// revisionDiff returns a line based unified diff of the title and content.
+func revisionDiff(older, newer *model.ItemRevision) (string, error) {
+	text := func(r *model.ItemRevision) []string {
+		return difflib.SplitLines(ptr.From(r.Title) + "\n\n" + ptr.From(r.Content))
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        text(older),
+		B:        text(newer),
+		FromFile: "previous",
+		ToFile:   "updated",
+		Context:  3,
+	})
 }
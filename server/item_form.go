@@ -1,6 +1,10 @@
 package server
 
-import "time"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type ItemFeed struct {
 	ID   uint    `json:"id"`
@@ -19,20 +23,74 @@ type ItemForm struct {
 	PubDate   *time.Time `json:"pub_date"`
 	UpdatedAt *time.Time `json:"updated_at"`
 	Feed      ItemFeed   `json:"feed"`
+	// ClusterID groups items of the same story in different feeds.
+	ClusterID *uint `json:"cluster_id"`
+	// AlsoIn lists the other feeds that have the story, only set when
+	// listing with collapse.
+	AlsoIn []ItemFeed `json:"also_in,omitempty"`
+	// Archived is whether the page of the item was archived, only set when
+	// getting a single item.
+	Archived bool `json:"archived"`
 }
 
-type ReqItemList struct {
-	Paginate
+// ReqItemFilter are the filters of item lists and exports.
+type ReqItemFilter struct {
 	Keyword  *string `query:"keyword"`
 	FeedID   *uint   `query:"feed_id"`
 	GroupID  *uint   `query:"group_id"`
 	Unread   *bool   `query:"unread"`
 	Bookmark *bool   `query:"bookmark"`
+	// SmartFolderID limits the items to those of a smart folder, in
+	// addition to the other filters.
+	SmartFolderID *uint `query:"smart_folder_id"`
+	// Collapse lists each story found in several feeds only once.
+	Collapse bool   `query:"collapse"`
+	Sort     string `query:"sort" validate:"omitempty,oneof=newest oldest feed"`
+}
+
+type ReqItemList struct {
+	Paginate
+	ReqItemFilter
+	// Cursor switches to cursor based pagination: Page is ignored and the
+	// next page starts after the cursor. An empty cursor is the first page.
+	Cursor *string `query:"cursor"`
 }
 
 type RespItemList struct {
+	// Total is not counted with cursor based pagination.
 	Total *int        `json:"total"`
 	Items []*ItemForm `json:"items"`
+	// NextCursor is the cursor of the next page, empty on the last one.
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+type ReqItemExport struct {
+	ReqItemFilter
+	Format string `query:"format" validate:"required,oneof=json html markdown epub"`
+}
+
+type RespItemExport struct {
+	ContentType string
+	Filename    string
+	// Write writes the file. EPUBs download their images meanwhile, so it
+	// may take a while.
+	Write func(ctx context.Context, w io.Writer) error
+}
+
+type ReqItemChanges struct {
+	// ChangedSince is the token returned by the previous call, empty for a
+	// full sync.
+	ChangedSince string `query:"changed_since"`
+	Limit        int    `query:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+type RespItemChanges struct {
+	// Items are new and changed items, including their content.
+	Items   []*ItemForm `json:"items"`
+	Deleted []uint      `json:"deleted"`
+	// Next is the token for the next call.
+	Next    string `json:"next"`
+	HasMore bool   `json:"has_more"`
 }
 
 type ReqItemGet struct {
@@ -41,16 +99,46 @@ type ReqItemGet struct {
 
 type RespItemGet ItemForm
 
+type ReqItemArchive struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type RespItemArchive struct {
+	Body []byte
+}
+
 type ReqItemDelete struct {
 	ID uint `param:"id" validate:"required"`
 }
 
 type ReqItemUpdateUnread struct {
-	IDs    []uint `json:"ids" validate:"required"`
-	Unread *bool  `json:"unread" validate:"required"`
+	IDs []uint `json:"ids" validate:"required_without=SmartFolderID,excluded_with=SmartFolderID"`
+	// SmartFolderID updates all items of a smart folder instead of IDs.
+	SmartFolderID *uint `json:"smart_folder_id"`
+	Unread        *bool `json:"unread" validate:"required"`
+	// Cluster also updates the other items of the same story.
+	Cluster bool `json:"cluster"`
 }
 
 type ReqItemUpdateBookmark struct {
 	ID       uint  `param:"id" validate:"required"`
 	Bookmark *bool `json:"bookmark" validate:"required"`
 }
+
+type ReqItemRevisions struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type ItemRevisionForm struct {
+	ID uint `json:"id"`
+	// ReplacedAt is when the publisher replaced this version.
+	ReplacedAt time.Time `json:"replaced_at"`
+	Title      *string   `json:"title"`
+	Content    *string   `json:"content"`
+	// Diff is a unified diff from this version to the next newer one.
+	Diff string `json:"diff"`
+}
+
+type RespItemRevisions struct {
+	Revisions []*ItemRevisionForm `json:"revisions"`
+}
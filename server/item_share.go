@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/sanitize"
+	"github.com/Sudo-Ivan/fusionx/repo"
+)
+
+type ItemShareRepo interface {
+	List(itemID *uint) ([]*model.ItemShare, error)
+	GetByToken(token string) (*model.ItemShare, error)
+	Create(share *model.ItemShare) error
+	Delete(id uint) error
+}
+
+type ItemShareItemRepo interface {
+	Get(id uint) (*model.Item, error)
+}
+
+type ItemShare struct {
+	repo     ItemShareRepo
+	itemRepo ItemShareItemRepo
+}
+
+func NewItemShare(repo ItemShareRepo, itemRepo ItemShareItemRepo) *ItemShare {
+	return &ItemShare{
+		repo:     repo,
+		itemRepo: itemRepo,
+	}
+}
+
+func (s ItemShare) Create(ctx context.Context, req *ReqItemShareCreate) (*RespItemShareCreate, error) {
+	item, err := s.itemRepo.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	share := &model.ItemShare{
+		Token:     token,
+		ExpiresAt: req.ExpiresAt,
+		ItemID:    item.ID,
+	}
+	if err := s.repo.Create(share); err != nil {
+		return nil, err
+	}
+	share.Item = *item
+	return (*RespItemShareCreate)(newItemShareForm(share, req.BaseURL)), nil
+}
+
+func (s ItemShare) List(ctx context.Context, req *ReqItemShareList) (*RespItemShareList, error) {
+	data, err := s.repo.List(req.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*ItemShareForm, 0, len(data))
+	for _, v := range data {
+		shares = append(shares, newItemShareForm(v, req.BaseURL))
+	}
+	return &RespItemShareList{Shares: shares}, nil
+}
+
+// Delete revokes a share.
+func (s ItemShare) Delete(ctx context.Context, req *ReqItemShareDelete) error {
+	return s.repo.Delete(req.ID)
+}
+
+// View returns the shared item of a token. Expired shares and shares of
+// deleted items aren't found.
+func (s ItemShare) View(ctx context.Context, req *ReqItemShareView) (*RespItemShareView, error) {
+	share, err := s.repo.GetByToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if share.Item.ID == 0 || isExpired(share) {
+		return nil, repo.ErrNotFound
+	}
+
+	item := share.Item
+	var base *url.URL
+	if link := ptr.From(item.Link); link != "" {
+		base, _ = url.Parse(link)
+	}
+	if base == nil {
+		base, _ = url.Parse(ptr.From(item.Feed.Link))
+	}
+	content, err := sanitize.HTML(ptr.From(item.Content), base)
+	if err != nil {
+		return nil, err
+	}
+	title := strings.TrimSpace(ptr.From(item.Title))
+	if title == "" {
+		title = "(untitled)"
+	}
+	return &RespItemShareView{
+		Title:    title,
+		Link:     ptr.From(item.Link),
+		FeedName: ptr.From(item.Feed.Name),
+		PubDate:  item.PubDate,
+		Content:  template.HTML(content), // #nosec G203 - sanitized above
+	}, nil
+}
+
+func newItemShareForm(share *model.ItemShare, baseURL string) *ItemShareForm {
+	var title *string
+	if share.Item.ID != 0 {
+		title = share.Item.Title
+	}
+	return &ItemShareForm{
+		ID:        share.ID,
+		ItemID:    share.ItemID,
+		ItemTitle: title,
+		URL:       strings.TrimSuffix(baseURL, "/") + "/share/" + share.Token,
+		ExpiresAt: share.ExpiresAt,
+		Expired:   isExpired(share),
+		CreatedAt: share.CreatedAt,
+	}
+}
+
+func isExpired(share *model.ItemShare) bool {
+	return share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now())
+}
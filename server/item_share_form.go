@@ -0,0 +1,53 @@
+package server
+
+import (
+	"html/template"
+	"time"
+)
+
+type ItemShareForm struct {
+	ID        uint       `json:"id"`
+	ItemID    uint       `json:"item_id"`
+	ItemTitle *string    `json:"item_title"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Expired   bool       `json:"expired"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ReqItemShareCreate struct {
+	ID uint `param:"id" validate:"required"`
+	// ExpiresAt is optional and must be in the future.
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitnil,gt"`
+	// BaseURL is the address of the web UI, for the share URL.
+	BaseURL string `json:"-"`
+}
+
+type RespItemShareCreate ItemShareForm
+
+type ReqItemShareList struct {
+	ItemID  *uint  `query:"item_id"`
+	BaseURL string `json:"-"`
+}
+
+type RespItemShareList struct {
+	Shares []*ItemShareForm `json:"shares"`
+}
+
+type ReqItemShareDelete struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type ReqItemShareView struct {
+	Token string `param:"token" validate:"required"`
+}
+
+// RespItemShareView is a shared item as shown to the public.
+type RespItemShareView struct {
+	Title    string
+	Link     string
+	FeedName string
+	PubDate  *time.Time
+	// Content is sanitized and safe to embed.
+	Content template.HTML
+}
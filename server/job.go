@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+	"github.com/Sudo-Ivan/fusionx/service/maintenance"
+	"github.com/Sudo-Ivan/fusionx/service/pull"
+)
+
+// Job kinds.
+const (
+	JobRefresh     = "refresh"
+	JobImport      = "import"
+	JobMaintenance = "maintenance"
+	JobRepairItems = "repair_items"
+)
+
+type JobRepo interface {
+	List(limit int) ([]*model.Job, error)
+	Get(id uint) (*model.Job, error)
+}
+
+type Job struct {
+	jobs *jobs.Manager
+	repo JobRepo
+	// purgeAfter is passed to maintenance jobs
+	purgeAfter time.Duration
+}
+
+func NewJob(manager *jobs.Manager, repo JobRepo, purgeAfter time.Duration) *Job {
+	return &Job{
+		jobs:       manager,
+		repo:       repo,
+		purgeAfter: purgeAfter,
+	}
+}
+
+func (j Job) List(ctx context.Context, req *ReqJobList) (*RespJobList, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = 50
+	}
+	data, err := j.repo.List(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*JobForm, 0, len(data))
+	for _, v := range data {
+		list = append(list, newJobForm(v))
+	}
+	return &RespJobList{Jobs: list}, nil
+}
+
+func (j Job) Get(ctx context.Context, req *ReqJobGet) (*JobForm, error) {
+	data, err := j.repo.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newJobForm(data), nil
+}
+
+// Create starts a maintenance job on demand.
+func (j Job) Create(ctx context.Context, req *ReqJobCreate) (*JobForm, error) {
+	var fn jobs.Func
+	switch req.Kind {
+	case JobMaintenance:
+		fn = j.maintenance
+	case JobRepairItems:
+		fn = repairItems
+	default:
+		return nil, NewBizError(errors.New("unknown job kind"), http.StatusBadRequest, "unknown job kind")
+	}
+
+	job, err := j.jobs.Submit(req.Kind, fn)
+	if err != nil {
+		return nil, err
+	}
+	return newJobForm(job), nil
+}
+
+func (j Job) Cancel(ctx context.Context, req *ReqJobCancel) error {
+	err := j.jobs.Cancel(req.ID)
+	if errors.Is(err, jobs.ErrFinished) {
+		err = NewBizError(err, http.StatusConflict, "the job has already finished")
+	}
+	return err
+}
+
+func (j Job) maintenance(ctx context.Context, p *jobs.Progress) error {
+	_, err := maintenance.NewJob(repo.NewMaintenance(repo.DB), repo.NewStats(repo.DB), j.purgeAfter).RunOnce()
+	return err
+}
+
+// repairItems runs pull.RepairItemIdentity for all feeds.
+func repairItems(ctx context.Context, p *jobs.Progress) error {
+	feeds, err := repo.NewFeed(repo.DB).List(nil)
+	if err != nil {
+		return err
+	}
+	p.SetTotal(len(feeds))
+
+	itemRepo := repo.NewItem(repo.DB)
+	for _, feed := range feeds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := pull.RepairItemIdentity(itemRepo, feed); err != nil {
+			return fmt.Errorf("feed %d: %w", feed.ID, err)
+		}
+		p.Add(1)
+	}
+	return nil
+}
+
+func newJobForm(job *model.Job) *JobForm {
+	return &JobForm{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Done:       job.Done,
+		Total:      job.Total,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
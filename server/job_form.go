@@ -0,0 +1,35 @@
+package server
+
+import "time"
+
+type JobForm struct {
+	ID         uint       `json:"id"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	Error      *string    `json:"error"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+type ReqJobList struct {
+	Limit int `query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+type RespJobList struct {
+	Jobs []*JobForm `json:"jobs"`
+}
+
+type ReqJobGet struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type ReqJobCreate struct {
+	Kind string `json:"kind" validate:"required,oneof=maintenance repair_items"`
+}
+
+type ReqJobCancel struct {
+	ID uint `param:"id" validate:"required"`
+}
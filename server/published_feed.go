@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/feedgen"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+)
+
+type PublishedFeedRepo interface {
+	All() ([]*model.PublishedFeed, error)
+	Get(id uint) (*model.PublishedFeed, error)
+	GetByToken(token string) (*model.PublishedFeed, error)
+	Create(feed *model.PublishedFeed) error
+	Update(id uint, feed *model.PublishedFeed) error
+	UpdateToken(id uint, token string) error
+	Delete(id uint) error
+}
+
+type PublishedFeedItemRepo interface {
+	List(filter repo.ItemFilter, page, pageSize int) ([]*model.Item, int, error)
+}
+
+type PublishedFeedGroupRepo interface {
+	Get(id uint) (*model.Group, error)
+}
+
+// publishedFeedItems is the number of newest items in a published feed.
+const publishedFeedItems = 50
+
+type PublishedFeed struct {
+	repo       PublishedFeedRepo
+	itemRepo   PublishedFeedItemRepo
+	groupRepo  PublishedFeedGroupRepo
+	folderRepo SmartFolderRepo
+}
+
+func NewPublishedFeed(repo PublishedFeedRepo, itemRepo PublishedFeedItemRepo, groupRepo PublishedFeedGroupRepo, folderRepo SmartFolderRepo) *PublishedFeed {
+	return &PublishedFeed{
+		repo:       repo,
+		itemRepo:   itemRepo,
+		groupRepo:  groupRepo,
+		folderRepo: folderRepo,
+	}
+}
+
+func (p PublishedFeed) All(ctx context.Context, baseURL string) (*RespPublishedFeedAll, error) {
+	data, err := p.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	feeds := make([]*PublishedFeedForm, 0, len(data))
+	for _, v := range data {
+		urls := make(map[string]string, len(feedgen.Formats))
+		for _, format := range feedgen.Formats {
+			urls[format] = publishedFeedURL(baseURL, v.Token, format)
+		}
+		feeds = append(feeds, &PublishedFeedForm{
+			ID:       v.ID,
+			Name:     v.Name,
+			Source:   v.Source,
+			SourceID: v.SourceID,
+			Token:    v.Token,
+			URLs:     urls,
+		})
+	}
+	return &RespPublishedFeedAll{
+		PublishedFeeds: feeds,
+	}, nil
+}
+
+func (p PublishedFeed) Create(ctx context.Context, req *ReqPublishedFeedCreate) (*RespPublishedFeedCreate, error) {
+	feed, err := p.newPublishedFeed(req)
+	if err != nil {
+		return nil, err
+	}
+	feed.Token, err = newToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.repo.Create(feed); err != nil {
+		return nil, err
+	}
+	return &RespPublishedFeedCreate{ID: feed.ID, Token: feed.Token}, nil
+}
+
+func (p PublishedFeed) Update(ctx context.Context, req *ReqPublishedFeedUpdate) error {
+	feed, err := p.newPublishedFeed(&req.ReqPublishedFeedCreate)
+	if err != nil {
+		return err
+	}
+	return p.repo.Update(req.ID, feed)
+}
+
+// RotateToken replaces the token of a published feed, so that the old URLs
+// stop working.
+func (p PublishedFeed) RotateToken(ctx context.Context, req *ReqPublishedFeedToken) (*RespPublishedFeedToken, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.repo.UpdateToken(req.ID, token); err != nil {
+		return nil, err
+	}
+	return &RespPublishedFeedToken{Token: token}, nil
+}
+
+func (p PublishedFeed) Delete(ctx context.Context, req *ReqPublishedFeedDelete) error {
+	return p.repo.Delete(req.ID)
+}
+
+// Render returns the newest items of the published feed with the token in
+// the requested format.
+func (p PublishedFeed) Render(ctx context.Context, req *ReqPublishedFeedRender) (*RespPublishedFeedRender, error) {
+	published, err := p.repo.GetByToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := strings.TrimSuffix(req.BaseURL, "/")
+	feed := &feedgen.Feed{
+		Title:   ptr.From(published.Name),
+		SelfURL: publishedFeedURL(baseURL, published.Token, req.Format),
+		Updated: published.UpdatedAt,
+	}
+	var filter repo.ItemFilter
+	switch published.Source {
+	case model.PublishedSourceGroup:
+		group, err := p.groupRepo.Get(published.SourceID)
+		if err != nil {
+			return nil, err
+		}
+		filter.GroupID = &group.ID
+		feed.Description = "Items of the " + ptr.From(group.Name) + " group"
+		feed.Link = baseURL + "/groups/" + strconv.FormatUint(uint64(group.ID), 10)
+	case model.PublishedSourceBookmarks:
+		filter.Bookmark = ptr.To(true)
+		feed.Description = "Bookmarked items"
+		feed.Link = baseURL + "/bookmarks"
+	case model.PublishedSourceSmartFolder:
+		folder, err := p.folderRepo.Get(published.SourceID)
+		if err != nil {
+			return nil, err
+		}
+		within := smartFolderFilter(folder)
+		filter.Within = &within
+		feed.Description = "Items of the " + ptr.From(folder.Name) + " smart folder"
+		feed.Link = baseURL + "/smart-folders/" + strconv.FormatUint(uint64(folder.ID), 10)
+	default:
+		return nil, repo.ErrNotFound
+	}
+
+	items, _, err := p.itemRepo.List(filter, 1, publishedFeedItems)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range items {
+		item := &feedgen.Item{
+			ID:          "urn:fusionx:item:" + strconv.FormatUint(uint64(v.ID), 10),
+			Title:       ptr.From(v.Title),
+			Link:        ptr.From(v.Link),
+			ContentHTML: ptr.From(v.Content),
+			// the item's own updated_at also changes when it's read
+			Updated:     v.CreatedAt,
+			SourceTitle: ptr.From(v.Feed.Name),
+			SourceURL:   ptr.From(v.Feed.Link),
+		}
+		if v.PubDate != nil {
+			item.Published = *v.PubDate
+			item.Updated = *v.PubDate
+		}
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	var body bytes.Buffer
+	if err := feedgen.Write(&body, req.Format, feed); err != nil {
+		return nil, err
+	}
+	return &RespPublishedFeedRender{
+		ContentType: feedgen.ContentType(req.Format),
+		Body:        body.Bytes(),
+	}, nil
+}
+
+func (p PublishedFeed) newPublishedFeed(req *ReqPublishedFeedCreate) (*model.PublishedFeed, error) {
+	feed := &model.PublishedFeed{
+		Name:     req.Name,
+		Source:   req.Source,
+		SourceID: req.SourceID,
+	}
+	var err error
+	switch req.Source {
+	case model.PublishedSourceGroup:
+		_, err = p.groupRepo.Get(req.SourceID)
+		if errors.Is(err, repo.ErrNotFound) {
+			err = NewBizError(err, http.StatusBadRequest, "group not found")
+		}
+	case model.PublishedSourceSmartFolder:
+		_, err = p.folderRepo.Get(req.SourceID)
+		if errors.Is(err, repo.ErrNotFound) {
+			err = NewBizError(err, http.StatusBadRequest, "smart folder not found")
+		}
+	default:
+		feed.SourceID = 0
+	}
+	return feed, err
+}
+
+// newToken returns a random URL-safe token that grants access to a public
+// URL.
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func publishedFeedURL(baseURL, token, format string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/api/public/feeds/" + token + "/" + format
+}
@@ -0,0 +1,57 @@
+package server
+
+type PublishedFeedForm struct {
+	ID       uint    `json:"id"`
+	Name     *string `json:"name"`
+	Source   string  `json:"source"`
+	SourceID uint    `json:"source_id"`
+	Token    string  `json:"token"`
+	// URLs are the addresses of the feed by format.
+	URLs map[string]string `json:"urls"`
+}
+
+type RespPublishedFeedAll struct {
+	PublishedFeeds []*PublishedFeedForm `json:"published_feeds"`
+}
+
+type ReqPublishedFeedCreate struct {
+	Name   *string `json:"name" validate:"required"`
+	Source string  `json:"source" validate:"required,oneof=group bookmarks smart_folder"`
+	// SourceID is the ID of the group or smart folder.
+	SourceID uint `json:"source_id" validate:"required_unless=Source bookmarks"`
+}
+
+type RespPublishedFeedCreate struct {
+	ID    uint   `json:"id"`
+	Token string `json:"token"`
+}
+
+// ReqPublishedFeedUpdate replaces the name and source of a published feed.
+type ReqPublishedFeedUpdate struct {
+	ID uint `param:"id" validate:"required"`
+	ReqPublishedFeedCreate
+}
+
+type ReqPublishedFeedToken struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type RespPublishedFeedToken struct {
+	Token string `json:"token"`
+}
+
+type ReqPublishedFeedDelete struct {
+	ID uint `param:"id" validate:"required"`
+}
+
+type ReqPublishedFeedRender struct {
+	Token  string `param:"token" validate:"required"`
+	Format string `param:"format" validate:"required,oneof=atom rss json"`
+	// BaseURL is the address of the web UI, for absolute links.
+	BaseURL string `json:"-"`
+}
+
+type RespPublishedFeedRender struct {
+	ContentType string
+	Body        []byte
+}
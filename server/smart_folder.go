@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+)
+
+type SmartFolderRepo interface {
+	All() ([]*model.SmartFolder, error)
+	Get(id uint) (*model.SmartFolder, error)
+	Create(folder *model.SmartFolder) error
+	Update(id uint, folder *model.SmartFolder) error
+	Delete(id uint) error
+}
+
+type SmartFolderItemRepo interface {
+	Count(filter repo.ItemFilter) (int, error)
+}
+
+type SmartFolder struct {
+	repo     SmartFolderRepo
+	itemRepo SmartFolderItemRepo
+}
+
+func NewSmartFolder(repo SmartFolderRepo, itemRepo SmartFolderItemRepo) *SmartFolder {
+	return &SmartFolder{
+		repo:     repo,
+		itemRepo: itemRepo,
+	}
+}
+
+func (s SmartFolder) All(ctx context.Context) (*RespSmartFolderAll, error) {
+	data, err := s.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]*SmartFolderForm, 0, len(data))
+	for _, v := range data {
+		within := smartFolderFilter(v)
+		unread, err := s.itemRepo.Count(repo.ItemFilter{Unread: ptr.To(true), Within: &within})
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, &SmartFolderForm{
+			ID:          v.ID,
+			Name:        v.Name,
+			Keyword:     v.Keyword,
+			FeedIDs:     v.FeedIDs,
+			GroupIDs:    v.GroupIDs,
+			Unread:      v.Unread,
+			Bookmark:    v.Bookmark,
+			UnreadCount: unread,
+		})
+	}
+	return &RespSmartFolderAll{
+		SmartFolders: folders,
+	}, nil
+}
+
+func (s SmartFolder) Create(ctx context.Context, req *ReqSmartFolderCreate) (*RespSmartFolderCreate, error) {
+	folder := newSmartFolder(req)
+	if err := s.repo.Create(folder); err != nil {
+		if errors.Is(err, repo.ErrDuplicatedKey) {
+			err = NewBizError(err, http.StatusBadRequest, "name is not allowed to be the same as other smart folders")
+		}
+		return nil, err
+	}
+	return &RespSmartFolderCreate{ID: folder.ID}, nil
+}
+
+func (s SmartFolder) Update(ctx context.Context, req *ReqSmartFolderUpdate) error {
+	err := s.repo.Update(req.ID, newSmartFolder(&req.ReqSmartFolderCreate))
+	if errors.Is(err, repo.ErrDuplicatedKey) {
+		err = NewBizError(err, http.StatusBadRequest, "name is not allowed to be the same as other smart folders")
+	}
+	return err
+}
+
+func (s SmartFolder) Delete(ctx context.Context, req *ReqSmartFolderDelete) error {
+	return s.repo.Delete(req.ID)
+}
+
+func newSmartFolder(req *ReqSmartFolderCreate) *model.SmartFolder {
+	keyword := req.Keyword
+	if keyword != nil && strings.TrimSpace(*keyword) == "" {
+		keyword = nil
+	}
+	return &model.SmartFolder{
+		Name:     req.Name,
+		Keyword:  keyword,
+		FeedIDs:  req.FeedIDs,
+		GroupIDs: req.GroupIDs,
+		Unread:   req.Unread,
+		Bookmark: req.Bookmark,
+	}
+}
+
+// smartFolderFilter returns the item filter of the folder's conditions.
+func smartFolderFilter(folder *model.SmartFolder) repo.ItemFilter {
+	return repo.ItemFilter{
+		Keyword:  folder.Keyword,
+		FeedIDs:  folder.FeedIDs,
+		GroupIDs: folder.GroupIDs,
+		Unread:   folder.Unread,
+		Bookmark: folder.Bookmark,
+	}
+}
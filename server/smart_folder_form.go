@@ -0,0 +1,41 @@
+package server
+
+type SmartFolderForm struct {
+	ID          uint    `json:"id"`
+	Name        *string `json:"name"`
+	Keyword     *string `json:"keyword"`
+	FeedIDs     []uint  `json:"feed_ids"`
+	GroupIDs    []uint  `json:"group_ids"`
+	Unread      *bool   `json:"unread"`
+	Bookmark    *bool   `json:"bookmark"`
+	UnreadCount int     `json:"unread_count"`
+}
+
+type RespSmartFolderAll struct {
+	SmartFolders []*SmartFolderForm `json:"smart_folders"`
+}
+
+// ReqSmartFolderCreate is the definition of a smart folder. Conditions
+// that are left out don't limit the items.
+type ReqSmartFolderCreate struct {
+	Name     *string `json:"name" validate:"required"`
+	Keyword  *string `json:"keyword"`
+	FeedIDs  []uint  `json:"feed_ids"`
+	GroupIDs []uint  `json:"group_ids"`
+	Unread   *bool   `json:"unread"`
+	Bookmark *bool   `json:"bookmark"`
+}
+
+type RespSmartFolderCreate struct {
+	ID uint `json:"id"`
+}
+
+// ReqSmartFolderUpdate replaces the whole definition of a smart folder.
+type ReqSmartFolderUpdate struct {
+	ID uint `param:"id" validate:"required"`
+	ReqSmartFolderCreate
+}
+
+type ReqSmartFolderDelete struct {
+	ID uint `param:"id" validate:"required"`
+}
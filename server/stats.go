@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
-	"os"
 	"time"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/archive"
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
 )
 
 type StatsRepo interface {
@@ -13,17 +16,25 @@ type StatsRepo interface {
 	GetTotalGroups() (int, error)
 	GetLastFeedUpdate() (*time.Time, error)
 	GetFailedFeeds() (int, error)
+	GetFeedStates() (repo.FeedStateCounts, error)
+	GetDatabaseSize() (int64, error)
+	GetMaintenanceSummary() (repo.MaintenanceSummary, error)
+}
+
+// ArchiveUsage reports the disk space used by archived pages.
+type ArchiveUsage interface {
+	Usage() (archive.Usage, error)
 }
 
 type Stats struct {
 	repo     StatsRepo
-	dbPath   string
+	archives ArchiveUsage
 }
 
-func NewStats(repo StatsRepo, dbPath string) *Stats {
+func NewStats(repo StatsRepo, archives ArchiveUsage) *Stats {
 	return &Stats{
-		repo:   repo,
-		dbPath: dbPath,
+		repo:     repo,
+		archives: archives,
 	}
 }
 
@@ -59,11 +70,19 @@ func (s Stats) Get(ctx context.Context) (*RespStats, error) {
 		return nil, err
 	}
 
-	var dbSize int64
-	if s.dbPath != "" {
-		if stat, err := os.Stat(s.dbPath); err == nil {
-			dbSize = stat.Size()
-		}
+	dbSize, err := s.repo.GetDatabaseSize()
+	if err != nil {
+		return nil, err
+	}
+
+	maintenance, err := s.repo.GetMaintenanceSummary()
+	if err != nil {
+		return nil, err
+	}
+
+	archives, err := s.archives.Usage()
+	if err != nil {
+		return nil, err
 	}
 
 	return &RespStats{
@@ -74,5 +93,36 @@ func (s Stats) Get(ctx context.Context) (*RespStats, error) {
 		DatabaseSize:     dbSize,
 		LastFeedUpdate:   lastFeedUpdate,
 		FailedFeeds:      failedFeeds,
+		LastMaintenance:  maintenance.LastRun,
+		PurgedRows:       maintenance.PurgedRows,
+		ReclaimedBytes:   maintenance.ReclaimedBytes,
+		ArchivedPages:    archives.Count,
+		ArchiveSize:      archives.Size,
+	}, nil
+}
+
+// MetricsState collects the database-backed values exported as metrics.
+func (s Stats) MetricsState(ctx context.Context) (*metrics.State, error) {
+	states, err := s.repo.GetFeedStates()
+	if err != nil {
+		return nil, err
+	}
+
+	totalUnreadItems, err := s.repo.GetTotalUnreadItems()
+	if err != nil {
+		return nil, err
+	}
+
+	dbSize, err := s.repo.GetDatabaseSize()
+	if err != nil {
+		return nil, err
+	}
+
+	return &metrics.State{
+		FeedsOK:        states.OK,
+		FeedsFailing:   states.Failing,
+		FeedsSuspended: states.Suspended,
+		UnreadItems:    totalUnreadItems,
+		DatabaseSize:   dbSize,
 	}, nil
 }
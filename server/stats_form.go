@@ -10,6 +10,15 @@ type StatsForm struct {
 	DatabaseSize       int64       `json:"database_size"`
 	LastFeedUpdate     *time.Time  `json:"last_feed_update"`
 	FailedFeeds        int         `json:"failed_feeds"`
+	// LastMaintenance is when the maintenance job last ran, PurgedRows and
+	// ReclaimedBytes are the totals of all its runs.
+	LastMaintenance *time.Time `json:"last_maintenance"`
+	PurgedRows      int64      `json:"purged_rows"`
+	ReclaimedBytes  int64      `json:"reclaimed_bytes"`
+	// ArchivedPages and ArchiveSize are the number and size in bytes of the
+	// archived pages of bookmarks.
+	ArchivedPages int   `json:"archived_pages"`
+	ArchiveSize   int64 `json:"archive_size"`
 }
 
 type RespStats StatsForm
@@ -0,0 +1,214 @@
+package archive_test
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/archive"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+)
+
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// page is in ISO-8859-1, "Caf\xe9" is "Café".
+const page = `<html><head>
+<meta charset="iso-8859-1">
+<link rel="stylesheet" href="/style.css">
+<link rel="preload" href="/font.woff2" as="font">
+<script src="/app.js"></script>
+<base href="/blog/">
+</head>
+<body onload="evil()">
+<h1>Caf` + "\xe9" + `</h1>
+<img src="data:image/gif;base64,R0lGOD" data-src="pic.png" srcset="pic@2x.png 2x" alt="lazy">
+<noscript><img src="/fallback.png" alt="fallback"></noscript>
+<a href="javascript:alert(1)">bad</a> <a href="next">next</a>
+<iframe src="/frame"></iframe>
+<div style="background: url(pic.png)">styled</div>
+<img src="/missing.png" alt="missing">
+<!-- a comment -->
+</body></html>`
+
+func newSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		_, _ = w.Write([]byte(page))
+	})
+	mux.HandleFunc("/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		_, _ = w.Write([]byte(`@import "print.css" print; body { background: url('/fallback.png') } /* </style><script>x</script> */`))
+	})
+	mux.HandleFunc("/print.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		_, _ = w.Write([]byte(`h1 { color: black }`))
+	})
+	for _, p := range []string{"/blog/pic.png", "/fallback.png"} {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write(png)
+		})
+	}
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fetchLocal is archive.Fetch without the check of the address, for the
+// test site.
+var fetchLocal = archive.NewFetcher(func(ctx context.Context, link string) (*http.Response, error) {
+	return httpx.FusionRequest(ctx, link, model.FeedRequestOptions{})
+})
+
+func TestSnapshot(t *testing.T) {
+	site := newSite(t)
+	data, err := archive.Snapshot(context.Background(), site.URL+"/blog/post", fetchLocal)
+	require.NoError(t, err)
+	got := string(data)
+
+	pngURL := "data:image/png;base64,iVBORw0KGgoAAAANSUhEUg=="
+	assert.True(t, strings.HasPrefix(got, "<!-- Archived by FusionX from "+site.URL+"/blog/post on "))
+	assert.Contains(t, got, `<head><meta charset="utf-8"/><meta name="fusion-archive-source" content="`+site.URL+`/blog/post"/>`)
+	assert.Contains(t, got, "<h1>Café</h1>")
+	assert.Contains(t, got, "@media print {\nh1 { color: black }\n}")
+	assert.Contains(t, got, `body { background: url("`+pngURL+`") }`)
+	assert.Contains(t, got, `<\/style><script>x</script>`, "the stylesheet can't close the style element")
+	assert.Contains(t, got, `<img src="`+pngURL+`" data-src="pic.png" alt="lazy"/>`)
+	assert.Contains(t, got, `<img src="`+pngURL+`" alt="fallback"/>`)
+	assert.Contains(t, got, `<a>bad</a> <a href="`+site.URL+`/blog/next">next</a>`)
+	assert.Contains(t, got, `<div style="background: url(&#34;`+pngURL+`&#34;)">styled</div>`)
+	assert.Contains(t, got, `<img src="`+site.URL+`/missing.png" alt="missing"/>`, "missing resources keep their URL")
+	for _, removed := range []string{"<script src", "onload", "iframe", "preload", "srcset", "<base", "iso-8859-1", "a comment"} {
+		assert.NotContains(t, got, removed)
+	}
+
+	_, err = archive.Snapshot(context.Background(), site.URL+"/pdf", fetchLocal)
+	assert.ErrorContains(t, err, "not an HTML page")
+	_, err = archive.Snapshot(context.Background(), site.URL+"/gone", fetchLocal)
+	assert.ErrorContains(t, err, "404")
+	_, err = archive.Snapshot(context.Background(), site.URL+"/blog/post", archive.Fetch)
+	assert.ErrorIs(t, err, httpx.ErrPrivateAddress, "pages on private addresses aren't archived")
+}
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archives")
+	store := archive.NewStore(dir)
+
+	ids, err := store.IDs()
+	require.NoError(t, err)
+	assert.Empty(t, ids, "the directory is created on the first save")
+	_, err = store.Read(1)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.False(t, store.Exists(1))
+
+	require.NoError(t, store.Save(1, []byte("one")))
+	require.NoError(t, store.Save(12, []byte("twelve")))
+	require.NoError(t, store.Save(1, []byte("first")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an archive"), 0600))
+
+	data, err := store.Read(1)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+	assert.True(t, store.Exists(12))
+	ids, err = store.IDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{1, 12}, ids)
+	usage, err := store.Usage()
+	require.NoError(t, err)
+	assert.Equal(t, archive.Usage{Count: 2, Size: 11}, usage)
+
+	require.NoError(t, store.Remove(12))
+	require.NoError(t, store.Remove(12), "removing a missing archive is fine")
+	assert.False(t, store.Exists(12))
+}
+
+type mockItemRepo struct {
+	mu    sync.Mutex
+	items map[uint]*model.Item
+}
+
+func (m *mockItemRepo) Get(id uint) (*model.Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[id]
+	if !ok {
+		return &model.Item{}, repo.ErrNotFound
+	}
+	return item, nil
+}
+
+func (m *mockItemRepo) BookmarkedIDs() ([]uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []uint
+	for id, item := range m.items {
+		if ptr.From(item.Bookmark) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *mockItemRepo) set(item *model.Item) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[item.ID] = item
+}
+
+func TestArchiver(t *testing.T) {
+	site := newSite(t)
+	store := archive.NewStore(t.TempDir())
+	link := ptr.To(site.URL + "/blog/post")
+	items := &mockItemRepo{items: map[uint]*model.Item{
+		1: {ID: 1, Link: link, Bookmark: ptr.To(true)},
+		2: {ID: 2, Link: link, Bookmark: ptr.To(false)},
+		4: {ID: 4, Link: link, Bookmark: ptr.To(true)},
+		5: {ID: 5, Link: link, Bookmark: ptr.To(false)},
+	}}
+	for _, id := range []uint{1, 2, 3} {
+		require.NoError(t, store.Save(id, []byte("old")))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go archive.NewArchiverWithFetcher(store, items, fetchLocal).Run(ctx)
+
+	// bookmarked without an archive, such as an imported item
+	require.Eventually(t, func() bool { return store.Exists(4) }, 5*time.Second, 10*time.Millisecond)
+	data, err := store.Read(4)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<h1>Café</h1>")
+	data, err = store.Read(1)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data), "existing archives are kept")
+	ids, err := store.IDs()
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 4}, ids, "archives of unbookmarked and deleted items are pruned")
+
+	items.set(&model.Item{ID: 5, Link: link, Bookmark: ptr.To(true)})
+	events.Publish(events.ItemBookmarkChanged, events.BookmarkChange{ID: 5, Bookmark: true})
+	require.Eventually(t, func() bool { return store.Exists(5) }, 5*time.Second, 10*time.Millisecond)
+
+	events.Publish(events.ItemBookmarkChanged, events.BookmarkChange{ID: 1, Bookmark: false})
+	require.Eventually(t, func() bool { return !store.Exists(1) }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,209 @@
+// Package archive snapshots the pages of bookmarked items into single HTML
+// files, so that they can still be read when the page changes or goes away.
+package archive
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+)
+
+type ItemRepo interface {
+	Get(id uint) (*model.Item, error)
+	BookmarkedIDs() ([]uint, error)
+}
+
+// archiveTimeout limits archiving one page with its resources.
+const archiveTimeout = 5 * time.Minute
+
+// Archiver archives items when they are bookmarked and removes their archive
+// when the bookmark is removed.
+type Archiver struct {
+	store *Store
+	items ItemRepo
+	fetch Fetcher
+
+	// queue holds the IDs of the items waiting to be archived, queued is
+	// the set of them
+	mu     sync.Mutex
+	queue  []uint
+	queued map[uint]bool
+	wake   chan struct{}
+}
+
+func NewArchiver(store *Store, items ItemRepo) *Archiver {
+	return NewArchiverWithFetcher(store, items, Fetch)
+}
+
+// NewArchiverWithFetcher creates an archiver that downloads pages with a
+// custom Fetcher.
+func NewArchiverWithFetcher(store *Store, items ItemRepo, fetch Fetcher) *Archiver {
+	return &Archiver{
+		store:  store,
+		items:  items,
+		fetch:  fetch,
+		queued: make(map[uint]bool),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Run archives items as they are bookmarked until ctx is done. Pages are
+// downloaded one at a time in the background, so that slow sites don't hold
+// up the events.
+//
+// It first catches up with what happened while it wasn't running: archives
+// of items that were deleted or unbookmarked are removed, and bookmarked
+// items without an archive, such as imported ones or those that failed
+// before, are queued. It catches up again when it missed events.
+func (a *Archiver) Run(ctx context.Context) {
+	ch, unsubscribe := events.Subscribe(256, events.ItemBookmarkChanged)
+	defer func() { unsubscribe() }()
+
+	a.catchUp()
+	go a.work(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				slog.Warn("missed bookmark changes, catching up")
+				ch, unsubscribe = events.Subscribe(256, events.ItemBookmarkChanged)
+				a.catchUp()
+				continue
+			}
+			change, _ := e.Data.(events.BookmarkChange)
+			if !change.Bookmark {
+				if err := a.store.Remove(change.ID); err != nil {
+					slog.Error("failed to remove archive", "item_id", change.ID, "error", err)
+				}
+				continue
+			}
+			a.enqueue(change.ID)
+		}
+	}
+}
+
+// catchUp removes the archives that aren't needed anymore and queues the
+// bookmarked items without an archive.
+func (a *Archiver) catchUp() {
+	if err := a.Prune(); err != nil {
+		slog.Error("failed to prune archives", "error", err)
+	}
+	ids, err := a.items.BookmarkedIDs()
+	if err != nil {
+		slog.Error("failed to list bookmarks to archive", "error", err)
+		return
+	}
+	for _, id := range ids {
+		if !a.store.Exists(id) {
+			a.enqueue(id)
+		}
+	}
+}
+
+func (a *Archiver) enqueue(id uint) {
+	a.mu.Lock()
+	if !a.queued[id] {
+		a.queued[id] = true
+		a.queue = append(a.queue, id)
+	}
+	a.mu.Unlock()
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the first ID from the queue, ok is false if it's empty.
+func (a *Archiver) next() (id uint, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.queue) == 0 {
+		return 0, false
+	}
+	id = a.queue[0]
+	a.queue = a.queue[1:]
+	delete(a.queued, id)
+	return id, true
+}
+
+// work archives the queued items until ctx is done.
+func (a *Archiver) work(ctx context.Context) {
+	for {
+		id, ok := a.next()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.wake:
+				continue
+			}
+		}
+		item, err := a.items.Get(id)
+		if err != nil || !ptr.From(item.Bookmark) {
+			// deleted or unbookmarked meanwhile
+			continue
+		}
+		if err := a.Archive(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("failed to archive bookmark", "item_id", id, "error", err)
+		}
+	}
+}
+
+// Archive snapshots the page of an item and stores it.
+func (a *Archiver) Archive(ctx context.Context, id uint) error {
+	item, err := a.items.Get(id)
+	if err != nil {
+		return err
+	}
+	link := ptr.From(item.Link)
+	if link == "" {
+		return errors.New("item has no link")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, archiveTimeout)
+	defer cancel()
+	data, err := Snapshot(ctx, link, a.fetch)
+	if err != nil {
+		return err
+	}
+	if err := a.store.Save(id, data); err != nil {
+		return err
+	}
+	slog.Info("archived bookmark", "item_id", id, "size", len(data))
+	return nil
+}
+
+// Prune removes the archives of items that are gone or not bookmarked.
+func (a *Archiver) Prune() error {
+	ids, err := a.store.IDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		item, err := a.items.Get(id)
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+		case err != nil:
+			return err
+		case ptr.From(item.Bookmark):
+			continue
+		}
+		if err := a.store.Remove(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+)
+
+// Resource is a downloaded page, stylesheet, image or font.
+type Resource struct {
+	// URL is where the resource was found, after redirects.
+	URL         *url.URL
+	ContentType string
+	Data        []byte
+}
+
+// Fetcher downloads the resource at link.
+type Fetcher func(ctx context.Context, link string) (*Resource, error)
+
+// Limits of a snapshot. Resources beyond them keep their original URL.
+const (
+	maxPageSize     = 10 << 20
+	maxResourceSize = 5 << 20
+	maxTotalSize    = 50 << 20
+	maxResources    = 300
+	maxImportDepth  = 3
+	resourceTimeout = 30 * time.Second
+)
+
+// Fetch downloads a resource over HTTP. Links to private addresses are
+// refused, see httpx.PublicRequest.
+var Fetch = NewFetcher(httpx.PublicRequest)
+
+// NewFetcher returns a Fetcher that downloads resources with request.
+func NewFetcher(request func(ctx context.Context, link string) (*http.Response, error)) Fetcher {
+	return func(ctx context.Context, link string) (*Resource, error) {
+		ctx, cancel := context.WithTimeout(ctx, resourceTimeout)
+		defer cancel()
+		resp, err := request(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("got status code %d", resp.StatusCode)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxPageSize {
+			return nil, errors.New("too large")
+		}
+		return &Resource{URL: resp.Request.URL, ContentType: resp.Header.Get("Content-Type"), Data: data}, nil
+	}
+}
+
+// Snapshot downloads the page at link and returns it as a single HTML file:
+// stylesheets, images and fonts are inlined as data URLs, and scripts, frames
+// and event handlers are removed.
+func Snapshot(ctx context.Context, link string, fetch Fetcher) ([]byte, error) {
+	page, err := fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if page.URL == nil {
+		if page.URL, err = url.Parse(link); err != nil {
+			return nil, err
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(page.ContentType)
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(page.Data))
+	}
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("not an HTML page: %s", mediaType)
+	}
+
+	r, err := charset.NewReader(bytes.NewReader(page.Data), page.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	// parse without scripting, so that the fallbacks in noscript are parsed
+	// as elements
+	doc, err := html.ParseWithOptions(r, html.ParseOptionEnableScripting(false))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{
+		ctx:   ctx,
+		fetch: fetch,
+		base:  page.URL,
+		cache: make(map[string]string),
+	}
+	if base := findBase(doc); base != "" {
+		if u, err := s.base.Parse(base); err == nil {
+			s.base = u
+		}
+	}
+	s.clean(doc)
+	addMeta(doc, page.URL.String())
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<!-- Archived by FusionX from %s on %s -->\n",
+		strings.ReplaceAll(page.URL.String(), "--", "%2D%2D"), time.Now().UTC().Format(time.RFC3339))
+	if err := html.Render(&b, doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// snapshot is the state of a running Snapshot.
+type snapshot struct {
+	ctx   context.Context
+	fetch Fetcher
+	base  *url.URL
+	// cache are the data URLs of the inlined resources by URL.
+	cache map[string]string
+	total int
+	count int
+}
+
+// removedElements are removed together with their children.
+var removedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Iframe: true, atom.Frame: true, atom.Frameset: true,
+	atom.Object: true, atom.Embed: true, atom.Applet: true, atom.Template: true,
+	atom.Base: true,
+}
+
+// lazySrcAttrs hold the real source of images that are loaded by scripts.
+var lazySrcAttrs = []string{"data-src", "data-lazy-src", "data-original"}
+
+// removedAttrs are attributes that break inlined resources or need scripts.
+var removedAttrs = map[string]bool{
+	"srcset": true, "sizes": true, "integrity": true, "crossorigin": true, "loading": true,
+	"nonce": true, "ping": true,
+}
+
+// clean removes the active content of n and inlines its resources.
+func (s *snapshot) clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			switch {
+			case removedElements[c.DataAtom] || c.DataAtom == atom.Meta && removeMeta(c):
+				n.RemoveChild(c)
+			case c.DataAtom == atom.Noscript:
+				// scripts don't run in the archive, show the fallback
+				s.clean(c)
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+			case c.DataAtom == atom.Link:
+				s.inlineLink(n, c)
+			case c.DataAtom == atom.Source && n.DataAtom == atom.Picture:
+				// the img of the picture is inlined instead
+				n.RemoveChild(c)
+			default:
+				s.cleanElement(c)
+				s.clean(c)
+			}
+		case html.TextNode:
+			if n.DataAtom == atom.Style {
+				c.Data = escapeStyle(s.inlineCSS(c.Data, s.base, 0))
+			}
+		case html.CommentNode:
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// cleanElement removes the scripts of the attributes of n and inlines the
+// resources they refer to.
+func (s *snapshot) cleanElement(n *html.Node) {
+	if n.DataAtom == atom.Img {
+		src := getAttr(n, "src")
+		for _, key := range lazySrcAttrs {
+			if lazy := getAttr(n, key); lazy != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+				src = lazy
+				break
+			}
+		}
+		setAttr(n, "src", src)
+	}
+
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if strings.HasPrefix(key, "on") || removedAttrs[key] {
+			continue
+		}
+		switch key {
+		case "href", "action", "formaction":
+			link, ok := s.resolve(a.Val)
+			if !ok {
+				continue
+			}
+			a.Val = link
+		case "src", "poster":
+			link, ok := s.resolve(a.Val)
+			if !ok {
+				continue
+			}
+			if n.DataAtom == atom.Img || key == "poster" {
+				a.Val = s.inline(link)
+			} else {
+				// audio and video are too large to inline
+				a.Val = link
+			}
+		case "style":
+			a.Val = s.inlineCSS(a.Val, s.base, 0)
+		}
+		attrs = append(attrs, a)
+	}
+	n.Attr = attrs
+}
+
+// inlineLink replaces a stylesheet link with a style element and removes
+// other links, such as preloads, that would fetch from the network.
+func (s *snapshot) inlineLink(parent, link *html.Node) {
+	rel := strings.Fields(strings.ToLower(getAttr(link, "rel")))
+	isStylesheet := false
+	for _, r := range rel {
+		if r == "stylesheet" {
+			isStylesheet = true
+		}
+		if r == "alternate" {
+			isStylesheet = false
+			break
+		}
+	}
+	href, ok := s.resolve(getAttr(link, "href"))
+	if !isStylesheet || !ok {
+		parent.RemoveChild(link)
+		return
+	}
+	res, err := s.get(href)
+	if err != nil {
+		// keep the link, the stylesheet may still be online
+		s.cleanElement(link)
+		return
+	}
+	style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+	if media := getAttr(link, "media"); media != "" {
+		style.Attr = []html.Attribute{{Key: "media", Val: media}}
+	}
+	style.AppendChild(&html.Node{Type: html.TextNode, Data: escapeStyle(s.inlineCSS(string(res.Data), res.URL, 0))})
+	parent.InsertBefore(style, link)
+	parent.RemoveChild(link)
+}
+
+var styleEnd = regexp.MustCompile(`(?i)</style`)
+
+// escapeStyle keeps the CSS of a style element from closing it.
+func escapeStyle(css string) string {
+	return styleEnd.ReplaceAllString(css, `<\/style`)
+}
+
+var (
+	cssImport = regexp.MustCompile(`@import\s+(?:url\(\s*)?(?:"([^"]*)"|'([^']*)'|([^\s'")]+))\s*\)?\s*([^;]*);`)
+	cssURL    = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^\s'")]+))\s*\)`)
+)
+
+// inlineCSS inlines the imports and URLs of css, whose URLs are relative to
+// base.
+func (s *snapshot) inlineCSS(css string, base *url.URL, depth int) string {
+	css = cssImport.ReplaceAllStringFunc(css, func(m string) string {
+		sub := cssImport.FindStringSubmatch(m)
+		link, ok := resolve(base, sub[1]+sub[2]+sub[3])
+		if !ok || depth >= maxImportDepth {
+			return ""
+		}
+		res, err := s.get(link)
+		if err != nil {
+			return m
+		}
+		imported := s.inlineCSS(string(res.Data), res.URL, depth+1)
+		if media := strings.TrimSpace(sub[4]); media != "" {
+			return "@media " + media + " {\n" + imported + "\n}"
+		}
+		return imported
+	})
+	return cssURL.ReplaceAllStringFunc(css, func(m string) string {
+		sub := cssURL.FindStringSubmatch(m)
+		ref := sub[1] + sub[2] + sub[3]
+		if strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+			return m
+		}
+		link, ok := resolve(base, ref)
+		if !ok {
+			return "url()"
+		}
+		return `url("` + s.inline(link) + `")`
+	})
+}
+
+// inline returns the data URL of the resource at link, or link if it can't
+// be downloaded.
+func (s *snapshot) inline(link string) string {
+	if strings.HasPrefix(link, "data:") {
+		return link
+	}
+	if data, ok := s.cache[link]; ok {
+		return data
+	}
+	res, err := s.get(link)
+	if err != nil || len(res.Data) > maxResourceSize {
+		s.cache[link] = link
+		return link
+	}
+	mediaType, _, _ := mime.ParseMediaType(res.ContentType)
+	if mediaType == "" || mediaType == "application/octet-stream" || mediaType == "text/plain" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(res.Data))
+	}
+	data := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(res.Data)
+	s.cache[link] = data
+	return data
+}
+
+var errLimit = errors.New("snapshot limit reached")
+
+// get downloads a resource within the limits of the snapshot.
+func (s *snapshot) get(link string) (*Resource, error) {
+	if s.count >= maxResources || s.total >= maxTotalSize {
+		return nil, errLimit
+	}
+	s.count++
+	res, err := s.fetch(s.ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if s.total+len(res.Data) > maxTotalSize {
+		return nil, errLimit
+	}
+	if res.URL == nil {
+		if res.URL, err = url.Parse(link); err != nil {
+			return nil, err
+		}
+	}
+	s.total += len(res.Data)
+	return res, nil
+}
+
+func (s *snapshot) resolve(ref string) (string, bool) {
+	return resolve(s.base, ref)
+}
+
+// resolve returns the absolute URL of ref, or false if it isn't an http,
+// https, mailto or data URL, e.g. a javascript: URL.
+func resolve(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	if strings.HasPrefix(ref, "#") {
+		return ref, true
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "data":
+		return u.String(), true
+	}
+	return "", false
+}
+
+// findBase returns the href of the base element of doc.
+func findBase(n *html.Node) string {
+	if n.DataAtom == atom.Base {
+		return getAttr(n, "href")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := findBase(c); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// removeMeta reports whether a meta element should be removed: charsets,
+// which no longer apply, refreshes and content security policies.
+func removeMeta(n *html.Node) bool {
+	if getAttr(n, "charset") != "" {
+		return true
+	}
+	switch strings.ToLower(getAttr(n, "http-equiv")) {
+	case "content-type", "refresh", "content-security-policy", "set-cookie":
+		return true
+	}
+	return false
+}
+
+// addMeta adds the charset of the snapshot and the original URL to the head
+// of doc.
+func addMeta(doc *html.Node, link string) {
+	var head *html.Node
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && head == nil; c = c.NextSibling {
+			if c.DataAtom == atom.Head {
+				head = c
+				return
+			}
+			find(c)
+		}
+	}
+	find(doc)
+	if head == nil {
+		return
+	}
+	head.InsertBefore(&html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta, Attr: []html.Attribute{
+		{Key: "name", Val: "fusion-archive-source"}, {Key: "content", Val: link},
+	}}, head.FirstChild)
+	head.InsertBefore(&html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta, Attr: []html.Attribute{
+		{Key: "charset", Val: "utf-8"},
+	}}, head.FirstChild)
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	if val != "" {
+		n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+	}
+}
@@ -0,0 +1,124 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CacheDir is the directory archives are stored in.
+const CacheDir = "./cache/archives"
+
+// Store keeps an archive per item, as <item ID>.html files in a directory.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{
+		dir: dir,
+	}
+}
+
+// Usage is the number of archives and their total size in bytes.
+type Usage struct {
+	Count int
+	Size  int64
+}
+
+func (s *Store) path(id uint) string {
+	return filepath.Join(s.dir, strconv.FormatUint(uint64(id), 10)+".html")
+}
+
+// Read returns the archive of an item. It returns an error satisfying
+// errors.Is(err, fs.ErrNotExist) if there is none.
+func (s *Store) Read(id uint) ([]byte, error) {
+	return os.ReadFile(s.path(id))
+}
+
+// Exists reports whether an item has an archive.
+func (s *Store) Exists(id uint) bool {
+	_, err := os.Stat(s.path(id))
+	return err == nil
+}
+
+// Save stores the archive of an item, replacing the previous one.
+func (s *Store) Save(id uint, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return err
+	}
+	// write to a temporary file first, so that a failed write doesn't leave
+	// a partial archive
+	f, err := os.CreateTemp(s.dir, ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(id))
+}
+
+// Remove deletes the archive of an item, if it has one.
+func (s *Store) Remove(id uint) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// IDs returns the IDs of the items that have an archive.
+func (s *Store) IDs() ([]uint, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".html")
+		if !ok || e.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseUint(name, 10, 0)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// Usage returns the number and size of the archives.
+func (s *Store) Usage() (Usage, error) {
+	var u Usage
+	ids, err := s.IDs()
+	if err != nil {
+		return u, err
+	}
+	for _, id := range ids {
+		info, err := os.Stat(s.path(id))
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed meanwhile
+			continue
+		}
+		if err != nil {
+			return u, fmt.Errorf("stat archive: %w", err)
+		}
+		u.Count++
+		u.Size += info.Size()
+	}
+	return u, nil
+}
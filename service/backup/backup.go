@@ -0,0 +1,277 @@
+// Package backup creates and restores archives of the database and the
+// favicon cache. Archives are gzipped tarballs containing manifest.json,
+// fusion.db and the favicons/ directory.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/repo"
+)
+
+const (
+	manifestName  = "manifest.json"
+	dbName        = "fusion.db"
+	faviconPrefix = "favicons/"
+
+	// maxFileSize limits the size of a single file extracted from an
+	// archive.
+	maxFileSize = 16 << 30
+)
+
+// Manifest describes an archive.
+type Manifest struct {
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion uint      `json:"schema_version"`
+}
+
+// Snapshot is a consistent copy of the database, taken while the server may
+// be writing. It must be closed to remove the copy.
+type Snapshot struct {
+	dir           string
+	createdAt     time.Time
+	schemaVersion uint
+}
+
+// NewSnapshot copies the database to a temporary file.
+func NewSnapshot() (*Snapshot, error) {
+	dir, err := os.MkdirTemp("", "fusion-backup-")
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{dir: dir, createdAt: time.Now().UTC()}
+	if err := repo.Snapshot(filepath.Join(dir, dbName)); err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.schemaVersion, err = repo.SchemaVersion()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Filename returns the suggested file name of the archive.
+func (s *Snapshot) Filename() string {
+	return "fusion-backup-" + s.createdAt.Format("20060102-150405") + ".tar.gz"
+}
+
+// WriteArchive writes the archive of the snapshot and the favicons in
+// faviconDir to w. A missing faviconDir is treated as empty.
+func (s *Snapshot) WriteArchive(w io.Writer, faviconDir string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifest, err := json.MarshalIndent(Manifest{
+		CreatedAt:     s.createdAt,
+		SchemaVersion: s.schemaVersion,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0o600,
+		Size:    int64(len(manifest)),
+		ModTime: s.createdAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	if err := addFile(tw, filepath.Join(s.dir, dbName), dbName); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(faviconDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := addFile(tw, filepath.Join(faviconDir, e.Name()), faviconPrefix+e.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Close removes the snapshot.
+func (s *Snapshot) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+func addFile(tw *tar.Writer, src, name string) error {
+	f, err := os.Open(src) // #nosec G304 - paths are built from our own directories
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Restore replaces the SQLite database at dbPath and the favicons in
+// faviconDir with the contents of the archive. The server must not be
+// running.
+//
+// The archive is extracted and validated next to the targets first, so a
+// broken archive leaves the current data untouched. The replaced database
+// and favicons are kept with a ".pre-restore-<time>" suffix.
+func Restore(archive io.Reader, dbPath, faviconDir string) (*Manifest, error) {
+	dbTmp, err := os.MkdirTemp(filepath.Dir(dbPath), ".fusion-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dbTmp)
+
+	if err := os.MkdirAll(filepath.Dir(faviconDir), 0o750); err != nil {
+		return nil, err
+	}
+	faviconTmp, err := os.MkdirTemp(filepath.Dir(faviconDir), ".fusion-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(faviconTmp)
+	newFavicons := filepath.Join(faviconTmp, "favicons")
+	if err := os.Mkdir(newFavicons, 0o750); err != nil {
+		return nil, err
+	}
+
+	manifest, err := extract(archive, dbTmp, newFavicons)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	newDB := filepath.Join(dbTmp, dbName)
+	version, err := repo.CheckSnapshot(newDB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if version != manifest.SchemaVersion {
+		return nil, fmt.Errorf("invalid archive: manifest schema version %d doesn't match the database (%d)",
+			manifest.SchemaVersion, version)
+	}
+
+	suffix := ".pre-restore-" + time.Now().Format("20060102-150405")
+	// The WAL and shared memory files belong to the old database and
+	// must not be applied to the restored one.
+	for _, p := range []string{dbPath + "-wal", dbPath + "-shm", dbPath} {
+		if err := os.Rename(p, p+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if err := os.Rename(newDB, dbPath); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(faviconDir, faviconDir+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.Rename(newFavicons, faviconDir); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// extract reads the archive, writing the database to dbDir and favicons to
+// faviconDir.
+func extract(archive io.Reader, dbDir, faviconDir string) (*Manifest, error) {
+	gr, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var manifest *Manifest
+	hasDB := false
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %q", h.Name)
+		}
+
+		switch {
+		case h.Name == manifestName:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("read manifest: %w", err)
+			}
+		case h.Name == dbName:
+			if err := writeFile(filepath.Join(dbDir, dbName), tr); err != nil {
+				return nil, err
+			}
+			hasDB = true
+		case strings.HasPrefix(h.Name, faviconPrefix):
+			name := strings.TrimPrefix(h.Name, faviconPrefix)
+			if name == "" || name == "." || name == ".." || path.Base(name) != name {
+				return nil, fmt.Errorf("unexpected entry %q", h.Name)
+			}
+			if err := writeFile(filepath.Join(faviconDir, name), tr); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected entry %q", h.Name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, errors.New("missing " + manifestName)
+	}
+	if !hasDB {
+		return nil, errors.New("missing " + dbName)
+	}
+	return manifest, nil
+}
+
+func writeFile(name string, r io.Reader) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec G304 - name is validated by the caller
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, maxFileSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxFileSize {
+		err = fmt.Errorf("%s is too large", filepath.Base(name))
+	}
+	return err
+}
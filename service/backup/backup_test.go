@@ -0,0 +1,130 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/backup"
+)
+
+func initDB(t *testing.T, path string) {
+	repo.Logger = logger.Default.LogMode(logger.Silent)
+	repo.Init(repo.DriverSQLite, path)
+	t.Cleanup(func() { repo.Close() })
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	initDB(t, filepath.Join(dir, "source.db"))
+	require.NoError(t, repo.NewFeed(repo.DB).Create([]*model.Feed{
+		{Name: ptr.To("News"), Link: ptr.To("https://news.example.com/feed"), GroupID: 1},
+	}))
+	favicons := filepath.Join(dir, "favicons")
+	require.NoError(t, os.Mkdir(favicons, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(favicons, "abc.png"), []byte("png"), 0o600))
+
+	snapshot, err := backup.NewSnapshot()
+	require.NoError(t, err)
+	var archive bytes.Buffer
+	require.NoError(t, snapshot.WriteArchive(&archive, favicons))
+	require.NoError(t, snapshot.Close())
+	require.NoError(t, repo.Close())
+
+	target := filepath.Join(dir, "restore")
+	require.NoError(t, os.Mkdir(target, 0o750))
+	targetDB := filepath.Join(target, "fusion.db")
+	require.NoError(t, os.WriteFile(targetDB, []byte("old"), 0o600))
+	targetFavicons := filepath.Join(target, "cache", "favicons")
+
+	manifest, err := backup.Restore(&archive, targetDB, targetFavicons)
+	require.NoError(t, err)
+	assert.Equal(t, repo.LatestSchemaVersion(), manifest.SchemaVersion)
+
+	data, err := os.ReadFile(filepath.Join(targetFavicons, "abc.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+	old, err := filepath.Glob(targetDB + ".pre-restore-*")
+	require.NoError(t, err)
+	assert.Len(t, old, 1, "the replaced database should be kept")
+
+	initDB(t, targetDB)
+	feeds, err := repo.NewFeed(repo.DB).List(nil)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, "News", ptr.From(feeds[0].Name))
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		entries map[string]string
+	}{
+		{name: "missing manifest", entries: map[string]string{"fusion.db": "x"}},
+		{name: "missing database", entries: map[string]string{"manifest.json": "{}"}},
+		{name: "path traversal", entries: map[string]string{"favicons/../../evil": "x"}},
+		{name: "unknown entry", entries: map[string]string{"other.txt": "x"}},
+		{name: "not a database", entries: map[string]string{"manifest.json": "{}", "fusion.db": "not sqlite"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "fusion.db")
+			require.NoError(t, os.WriteFile(dbPath, []byte("current"), 0o600))
+
+			_, err := backup.Restore(tarball(t, tt.entries), dbPath, filepath.Join(dir, "favicons"))
+			assert.Error(t, err)
+
+			data, err := os.ReadFile(dbPath)
+			require.NoError(t, err)
+			assert.Equal(t, "current", string(data), "the current database should be untouched")
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, 1, "temporary files should be removed")
+		})
+	}
+}
+
+func TestSchedulerRotation(t *testing.T) {
+	dir := t.TempDir()
+	initDB(t, filepath.Join(dir, "fusion.db"))
+	backups := filepath.Join(dir, "backups")
+	require.NoError(t, os.Mkdir(backups, 0o750))
+	for _, name := range []string{"fusion-backup-20200101-000000.tar.gz", "fusion-backup-20200102-000000.tar.gz", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(backups, name), nil, 0o600))
+	}
+
+	path, err := backup.NewScheduler(backups, 0, 2, filepath.Join(dir, "favicons")).Backup()
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(backups)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"fusion-backup-20200102-000000.tar.gz", filepath.Base(path), "notes.txt"}, names)
+}
+
+func tarball(t *testing.T, entries map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return &buf
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scheduler writes an archive to a local directory periodically and keeps
+// only the most recent ones.
+type Scheduler struct {
+	dir        string
+	interval   time.Duration
+	keep       int
+	faviconDir string
+}
+
+func NewScheduler(dir string, interval time.Duration, keep int, faviconDir string) *Scheduler {
+	return &Scheduler{
+		dir:        dir,
+		interval:   interval,
+		keep:       keep,
+		faviconDir: faviconDir,
+	}
+}
+
+// Run writes a backup every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := s.Backup()
+			if err != nil {
+				slog.Error("scheduled backup failed", "error", err)
+				continue
+			}
+			slog.Info("scheduled backup written", "path", path)
+		}
+	}
+}
+
+// Backup writes an archive to the directory and removes the oldest ones
+// beyond the number to keep. It returns the path of the new archive.
+func (s *Scheduler) Backup() (string, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return "", err
+	}
+
+	snapshot, err := NewSnapshot()
+	if err != nil {
+		return "", err
+	}
+	defer snapshot.Close()
+
+	// write to a temporary file first, so that a failed backup is never
+	// mistaken for a complete one
+	f, err := os.CreateTemp(s.dir, ".fusion-backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	err = snapshot.WriteArchive(f, s.faviconDir)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, snapshot.Filename())
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, s.rotate()
+}
+
+func (s *Scheduler) rotate() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	archives := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasPrefix(e.Name(), "fusion-backup-") &&
+			strings.HasSuffix(e.Name(), ".tar.gz") {
+			archives = append(archives, e.Name())
+		}
+	}
+	if len(archives) <= s.keep {
+		return nil
+	}
+
+	// the names contain the time, so they sort from oldest to newest
+	sort.Strings(archives)
+	for _, name := range archives[:len(archives)-s.keep] {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+// Package digest emails a summary of new items on a schedule.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"log/slog"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/mail"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+
+	"golang.org/x/net/html"
+)
+
+type Repo interface {
+	Watermark() (uint, error)
+	BookmarkWatermark() (time.Time, error)
+	CreateRun(run *model.DigestRun) error
+	Prune(keep int) error
+}
+
+type ItemRepo interface {
+	LastID(filter repo.ItemFilter) (uint, error)
+	List(filter repo.ItemFilter, page, pageSize int) ([]*model.Item, int, error)
+}
+
+// SendFn sends a message, such as mail.Send with a fixed config.
+type SendFn func(ctx context.Context, msg *mail.Message) error
+
+// Options select the items of a digest and how it's rendered.
+type Options struct {
+	To []string
+	// Bookmarks includes new bookmarked items instead of new unread items.
+	Bookmarks bool
+	// FeedIDs and GroupIDs limit the items to those of the feeds and
+	// groups. Empty means all feeds.
+	FeedIDs  []uint
+	GroupIDs []uint
+	// MaxItems is the number of items listed, the rest are only counted.
+	MaxItems int
+	// Location is the time zone of dates in the digest.
+	Location *time.Location
+	// AppURL is the address of the web UI, used to link to the items in
+	// fusion. Optional.
+	AppURL string
+}
+
+// summaryLength is the maximum number of characters of an item summary.
+const summaryLength = 240
+
+// keepRuns is the number of digest runs kept in the database.
+const keepRuns = 100
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/digest.html"))
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templates, "templates/digest.txt"))
+)
+
+type Job struct {
+	repo  Repo
+	items ItemRepo
+	send  SendFn
+	opts  Options
+}
+
+func NewJob(repo Repo, items ItemRepo, send SendFn, opts Options) *Job {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 100
+	}
+	opts.AppURL = strings.TrimSuffix(opts.AppURL, "/")
+	return &Job{
+		repo:  repo,
+		items: items,
+		send:  send,
+		opts:  opts,
+	}
+}
+
+// Run sends a digest at every scheduled time until ctx is done. Digests
+// missed while the server was down aren't sent, but their items are
+// included in the next one.
+func (j *Job) Run(ctx context.Context, schedule *Schedule) {
+	for {
+		next := schedule.Next(time.Now())
+		slog.Debug("next digest scheduled", "at", next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		run, err := j.RunOnce(ctx)
+		if err != nil {
+			slog.Error("failed to send digest", "error", err)
+			continue
+		}
+		if run == nil {
+			slog.Info("digest skipped, no new items")
+			continue
+		}
+		slog.Info("digest sent", "items", run.Items)
+	}
+}
+
+// RunOnce sends a digest of the items added since the last one and records
+// the run, also when it fails. It returns a nil run without sending
+// anything when there are no new items.
+func (j *Job) RunOnce(ctx context.Context) (*model.DigestRun, error) {
+	d, err := j.Build()
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, nil
+	}
+
+	run := &model.DigestRun{Watermark: d.Watermark, BookmarkWatermark: d.BookmarkWatermark, Items: d.Items}
+	err = j.send(ctx, d.Message)
+	if err != nil {
+		run.Error = ptr.To(err.Error())
+	}
+	if recordErr := j.repo.CreateRun(run); recordErr != nil {
+		slog.Warn("failed to record digest run", "error", recordErr)
+		if err == nil {
+			// the items would be sent again next time
+			err = recordErr
+		}
+	} else if pruneErr := j.repo.Prune(keepRuns); pruneErr != nil {
+		slog.Warn("failed to prune digest runs", "error", pruneErr)
+	}
+	return run, err
+}
+
+// Digest is a rendered digest.
+type Digest struct {
+	Message *mail.Message
+	// Items is the number of new items, including those that weren't
+	// listed.
+	Items int
+	// Watermark is the ID of the newest included item of a digest of
+	// unread items.
+	Watermark uint
+	// BookmarkWatermark is the time until which bookmarks are included in
+	// a digest of bookmarks.
+	BookmarkWatermark *time.Time
+}
+
+// Build renders the digest of the items added since the last one without
+// sending it. It returns nil when there are no new items.
+//
+// Unread items are new when they were stored after the last digest, and
+// bookmarks when they were bookmarked after it, so that older items that are
+// bookmarked later are included too.
+func (j *Job) Build() (*Digest, error) {
+	filter := repo.ItemFilter{
+		FeedIDs:  j.opts.FeedIDs,
+		GroupIDs: j.opts.GroupIDs,
+		Sort:     repo.ItemSortFeed,
+	}
+	d := &Digest{}
+	if j.opts.Bookmarks {
+		since, err := j.repo.BookmarkWatermark()
+		if err != nil {
+			return nil, err
+		}
+		// fix the upper end first, so that items bookmarked meanwhile are
+		// left for the next digest
+		d.BookmarkWatermark = ptr.To(time.Now())
+		filter.Bookmark = ptr.To(true)
+		filter.BookmarkedAfter = &since
+		filter.BookmarkedUntil = d.BookmarkWatermark
+	} else {
+		watermark, err := j.repo.Watermark()
+		if err != nil {
+			return nil, err
+		}
+		filter.Unread = ptr.To(true)
+		filter.AfterID = watermark
+		// fix the upper end first, so that items stored meanwhile are left
+		// for the next digest
+		filter.UntilID, err = j.items.LastID(filter)
+		if err != nil || filter.UntilID == 0 {
+			return nil, err
+		}
+		d.Watermark = filter.UntilID
+	}
+
+	items, total, err := j.items.List(filter, 1, j.opts.MaxItems)
+	if err != nil || total == 0 {
+		return nil, err
+	}
+
+	data := j.templateData(items, total)
+	var htmlBody, textBody bytes.Buffer
+	if err := htmlTemplate.Execute(&htmlBody, data); err != nil {
+		return nil, err
+	}
+	if err := textTemplate.Execute(&textBody, data); err != nil {
+		return nil, err
+	}
+	d.Message = &mail.Message{
+		To:      j.opts.To,
+		Subject: data.Title,
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}
+	d.Items = total
+	return d, nil
+}
+
+type templateData struct {
+	Title  string
+	Feeds  []*templateFeed
+	More   int
+	AppURL string
+}
+
+type templateFeed struct {
+	Name  string
+	Items []*templateItem
+}
+
+type templateItem struct {
+	Title   string
+	Link    string
+	Date    string
+	Summary string
+	// AppLink opens the item in fusion, empty without Options.AppURL
+	AppLink string
+}
+
+func (j *Job) templateData(items []*model.Item, total int) *templateData {
+	noun := "new unread items"
+	if j.opts.Bookmarks {
+		noun = "new bookmarks"
+	}
+	if total == 1 {
+		noun = strings.TrimSuffix(noun, "s")
+	}
+	data := &templateData{
+		Title:  "Fusion digest: " + strconv.Itoa(total) + " " + noun,
+		More:   total - len(items),
+		AppURL: j.opts.AppURL,
+	}
+
+	// items are sorted by feed
+	var feed *templateFeed
+	for i, item := range items {
+		if i == 0 || item.FeedID != items[i-1].FeedID {
+			feed = &templateFeed{Name: ptr.From(item.Feed.Name)}
+			data.Feeds = append(data.Feeds, feed)
+		}
+		v := &templateItem{
+			Title:   ptr.From(item.Title),
+			Link:    ptr.From(item.Link),
+			Summary: summary(ptr.From(item.Content)),
+		}
+		if v.Title == "" {
+			v.Title = "(untitled)"
+		}
+		if item.PubDate != nil {
+			v.Date = item.PubDate.In(j.opts.Location).Format("Jan 2, 15:04")
+		}
+		if j.opts.AppURL != "" {
+			v.AppLink = j.opts.AppURL + "/items/" + strconv.FormatUint(uint64(item.ID), 10)
+		}
+		feed.Items = append(feed.Items, v)
+	}
+	return data
+}
+
+// summary returns the beginning of the text of an HTML document.
+func summary(content string) string {
+	var text strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+	skip := 0
+	for text.Len() < summaryLength*4 {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		name, _ := z.TagName()
+		switch tt {
+		case html.StartTagToken:
+			if string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+		case html.EndTagToken:
+			if (string(name) == "script" || string(name) == "style") && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				text.Write(z.Text())
+				text.WriteByte(' ')
+			}
+		}
+	}
+
+	s := strings.Join(strings.Fields(text.String()), " ")
+	if utf8.RuneCountInString(s) <= summaryLength {
+		return s
+	}
+	runes := []rune(s)[:summaryLength]
+	// cut at a word boundary if there is one
+	if i := strings.LastIndexByte(string(runes), ' '); i > summaryLength/2 {
+		return string(runes)[:i] + "…"
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,215 @@
+package digest_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/mail"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/digest"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, tt := range []struct {
+		times, days, timezone string
+		wantErr               string
+	}{
+		{times: "07:00", days: "", timezone: ""},
+		{times: "7:00,18:30", days: "mon-fri,sun", timezone: "Europe/Berlin"},
+		{times: "", wantErr: "no time of day"},
+		{times: "25:00", wantErr: "invalid time of day"},
+		{times: "07:00", days: "someday", wantErr: "invalid weekday"},
+		{times: "07:00", timezone: "Mars/Olympus", wantErr: "invalid time zone"},
+	} {
+		_, err := digest.ParseSchedule(tt.times, tt.days, tt.timezone)
+		if tt.wantErr == "" {
+			assert.NoError(t, err, tt.times)
+		} else {
+			assert.ErrorContains(t, err, tt.wantErr, tt.times)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	for _, tt := range []struct {
+		description string
+		times, days string
+		now         time.Time
+		want        time.Time
+	}{
+		{
+			description: "later today",
+			times:       "07:00,18:30",
+			now:         time.Date(2025, 3, 5, 8, 0, 0, 0, berlin),
+			want:        time.Date(2025, 3, 5, 18, 30, 0, 0, berlin),
+		},
+		{
+			description: "exactly at a time is the next one",
+			times:       "07:00,18:30",
+			now:         time.Date(2025, 3, 5, 18, 30, 0, 0, berlin),
+			want:        time.Date(2025, 3, 6, 7, 0, 0, 0, berlin),
+		},
+		{
+			description: "skips the weekend",
+			times:       "07:00",
+			days:        "mon-fri",
+			// a Friday
+			now:  time.Date(2025, 3, 7, 9, 0, 0, 0, berlin),
+			want: time.Date(2025, 3, 10, 7, 0, 0, 0, berlin),
+		},
+		{
+			description: "ranges wrap around the week",
+			times:       "07:00",
+			days:        "sat-mon",
+			// a Tuesday
+			now:  time.Date(2025, 3, 4, 9, 0, 0, 0, berlin),
+			want: time.Date(2025, 3, 8, 7, 0, 0, 0, berlin),
+		},
+		{
+			description: "in the time zone of the schedule",
+			times:       "07:00",
+			// 07:30 in Berlin
+			now:  time.Date(2025, 3, 5, 6, 30, 0, 0, time.UTC),
+			want: time.Date(2025, 3, 6, 7, 0, 0, 0, berlin),
+		},
+		{
+			description: "same wall clock time across a DST change",
+			times:       "07:00",
+			now:         time.Date(2025, 3, 29, 8, 0, 0, 0, berlin),
+			want:        time.Date(2025, 3, 30, 7, 0, 0, 0, berlin),
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			s, err := digest.ParseSchedule(tt.times, tt.days, "Europe/Berlin")
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(s.Next(tt.now)), "got %s", s.Next(tt.now))
+		})
+	}
+}
+
+func initDB(t *testing.T) {
+	repo.Logger = logger.Default.LogMode(logger.Silent)
+	repo.Init(repo.DriverSQLite, filepath.Join(t.TempDir(), "fusion.db"))
+	t.Cleanup(func() { repo.Close() })
+}
+
+func addItems(t *testing.T, feedID uint, items ...*model.Item) {
+	for _, item := range items {
+		item.FeedID = feedID
+		item.Unread = ptr.To(true)
+	}
+	_, err := repo.NewItem(repo.DB).Insert(items)
+	require.NoError(t, err)
+}
+
+func TestDigest(t *testing.T) {
+	initDB(t)
+	feeds := []*model.Feed{
+		{Name: ptr.To("News"), Link: ptr.To("https://news.example.com/feed"), GroupID: 1},
+		{Name: ptr.To("Blog"), Link: ptr.To("https://blog.example.com/feed"), GroupID: 1},
+	}
+	require.NoError(t, repo.NewFeed(repo.DB).Create(feeds))
+	addItems(t, feeds[0].ID,
+		&model.Item{GUID: ptr.To("1"), Title: ptr.To("First story"), Link: ptr.To("https://news.example.com/1"),
+			Content: ptr.To("<p>Some <b>bold</b> text</p><script>alert(1)</script>")},
+		&model.Item{GUID: ptr.To("2"), Title: ptr.To("<Second> story"), Link: ptr.To("https://news.example.com/2")},
+	)
+	addItems(t, feeds[1].ID, &model.Item{GUID: ptr.To("3"), Title: ptr.To("Ignored"), Link: ptr.To("https://blog.example.com/3")})
+
+	var sent []*mail.Message
+	var sendErr error
+	job := digest.NewJob(repo.NewDigest(repo.DB), repo.NewItem(repo.DB), func(ctx context.Context, msg *mail.Message) error {
+		sent = append(sent, msg)
+		return sendErr
+	}, digest.Options{
+		To:       []string{"me@example.com"},
+		FeedIDs:  []uint{feeds[0].ID},
+		MaxItems: 1,
+		AppURL:   "https://fusion.example.com/",
+	})
+
+	run, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, 2, run.Items)
+	require.Len(t, sent, 1)
+	msg := sent[0]
+	assert.Equal(t, []string{"me@example.com"}, msg.To)
+	assert.Equal(t, "Fusion digest: 2 new unread items", msg.Subject)
+	assert.Contains(t, msg.Text, "== News ==")
+	assert.Contains(t, msg.Text, "And 1 more at https://fusion.example.com.")
+	assert.NotContains(t, msg.Text, "Ignored")
+	// the newest item comes first, and only one is listed
+	assert.Contains(t, msg.HTML, "&lt;Second&gt; story")
+	assert.Contains(t, msg.HTML, `href="https://fusion.example.com/items/2"`)
+	assert.NotContains(t, msg.HTML, "First story")
+
+	run, err = job.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, run, "items must not be repeated")
+
+	// a failed digest doesn't advance the watermark
+	addItems(t, feeds[0].ID, &model.Item{GUID: ptr.To("4"), Title: ptr.To("Later story"),
+		Content: ptr.To("<p>Some <b>bold</b> text</p><script>alert(1)</script>")})
+	sendErr = errors.New("connection refused")
+	run, err = job.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "connection refused", ptr.From(run.Error))
+
+	sendErr = nil
+	run, err = job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, 1, run.Items)
+	msg = sent[len(sent)-1]
+	assert.Equal(t, "Fusion digest: 1 new unread item", msg.Subject)
+	assert.Contains(t, msg.Text, "Later story\n")
+	assert.Contains(t, msg.Text, "\nSome bold text\n", "the summary should be plain text")
+	assert.NotContains(t, msg.Text, "alert")
+}
+
+func TestDigestBookmarks(t *testing.T) {
+	initDB(t)
+	feed := &model.Feed{Name: ptr.To("News"), Link: ptr.To("https://news.example.com/feed"), GroupID: 1}
+	require.NoError(t, repo.NewFeed(repo.DB).Create([]*model.Feed{feed}))
+	old := &model.Item{GUID: ptr.To("1"), Title: ptr.To("Old story")}
+	addItems(t, feed.ID, old, &model.Item{GUID: ptr.To("2"), Title: ptr.To("Newer story")})
+
+	itemRepo := repo.NewItem(repo.DB)
+	var sent []*mail.Message
+	job := digest.NewJob(repo.NewDigest(repo.DB), itemRepo, func(ctx context.Context, msg *mail.Message) error {
+		sent = append(sent, msg)
+		return nil
+	}, digest.Options{To: []string{"me@example.com"}, Bookmarks: true})
+
+	run, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, run, "nothing is bookmarked yet")
+
+	// an item stored before the last digest is bookmarked later
+	require.NoError(t, itemRepo.UpdateBookmark(old.ID, ptr.To(true)))
+	run, err = job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, 1, run.Items)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Fusion digest: 1 new bookmark", sent[0].Subject)
+	assert.Contains(t, sent[0].Text, "Old story")
+
+	// bookmarking it again keeps the bookmark time
+	require.NoError(t, itemRepo.UpdateBookmark(old.ID, ptr.To(true)))
+	run, err = job.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, run, "bookmarks must not be repeated")
+}
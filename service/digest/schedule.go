@@ -0,0 +1,112 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	// the container image has no time zone database
+	_ "time/tzdata"
+)
+
+// Schedule is the times of day at which digests are sent, optionally only
+// on some days of the week.
+type Schedule struct {
+	// times are minutes after midnight, sorted
+	times []int
+	// days is empty for every day
+	days     []time.Weekday
+	location *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseSchedule parses comma-separated times of day like "07:00,18:30",
+// comma-separated weekdays like "mon-fri,sun" (empty for every day) and an
+// IANA time zone name (empty for the local time zone).
+func ParseSchedule(times, days, timezone string) (*Schedule, error) {
+	s := &Schedule{location: time.Local}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", timezone, err)
+		}
+		s.location = loc
+	}
+
+	for _, v := range strings.Split(times, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day %q, use HH:MM", v)
+		}
+		s.times = append(s.times, t.Hour()*60+t.Minute())
+	}
+	if len(s.times) == 0 {
+		return nil, errors.New("no time of day")
+	}
+	slices.Sort(s.times)
+	s.times = slices.Compact(s.times)
+
+	for _, v := range strings.Split(strings.ToLower(days), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(v, "-")
+		from, ok := weekdays[first]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", first)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[last]; !ok {
+				return nil, fmt.Errorf("invalid weekday %q", last)
+			}
+		}
+		// ranges may wrap around the end of the week, e.g. sat-mon
+		for d := from; ; d = (d + 1) % 7 {
+			if !slices.Contains(s.days, d) {
+				s.days = append(s.days, d)
+			}
+			if d == to {
+				break
+			}
+		}
+	}
+	return s, nil
+}
+
+// Location returns the time zone of the schedule.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the first scheduled time after t.
+func (s *Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.location)
+	// a week ahead always has a scheduled day, and one more day covers the
+	// times of the current day that already passed
+	for d := 0; d <= 7; d++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, s.location)
+		if len(s.days) > 0 && !slices.Contains(s.days, day.Weekday()) {
+			continue
+		}
+		for _, minutes := range s.times {
+			next := time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, s.location)
+			if next.After(t) {
+				return next
+			}
+		}
+	}
+	// unreachable, as there is at least one time and one day
+	return t.Add(24 * time.Hour)
+}
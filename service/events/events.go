@@ -0,0 +1,177 @@
+// Package events is an in-process publish/subscribe bus. Subsystems publish
+// what happened, e.g. that a feed was fetched, and others subscribe to react
+// to it, e.g. the event stream of the web UI.
+package events
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Type is the kind of an event. Its Data has the type noted on each
+// constant.
+type Type string
+
+const (
+	// RefreshStarted is published when a round of pulling all feeds
+	// starts. Data is Refresh.
+	RefreshStarted Type = "refresh.started"
+	// RefreshFinished is published when all feeds of a round were pulled.
+	// Data is Refresh.
+	RefreshFinished Type = "refresh.finished"
+	// FeedFetchStarted is published before a feed is fetched. Data is
+	// FeedFetch.
+	FeedFetchStarted Type = "feed.fetch_started"
+	// FeedFetchFinished is published after a feed was fetched and its
+	// items were stored. Data is FeedFetch.
+	FeedFetchFinished Type = "feed.fetch_finished"
+	// FeedFetchFailed is published when fetching or storing a feed failed.
+	// Data is FeedFetch.
+	FeedFetchFailed Type = "feed.fetch_failed"
+	// ItemsNew is published when a pull stored new items. Data is
+	// NewItems.
+	ItemsNew Type = "items.new"
+	// ItemsReadChanged is published when items were marked read or unread.
+	// Data is ReadChange.
+	ItemsReadChanged Type = "items.read_changed"
+	// ItemBookmarkChanged is published when an item was bookmarked or
+	// unbookmarked. Data is BookmarkChange.
+	ItemBookmarkChanged Type = "items.bookmark_changed"
+	// UnreadCountsChanged is published when the number of unread items of
+	// feeds changed. Data is UnreadCounts.
+	UnreadCountsChanged Type = "unread_counts.changed"
+	// JobUpdated is published when a background job was queued, made
+	// progress or ended. Data is Job.
+	JobUpdated Type = "job.updated"
+)
+
+type Refresh struct {
+	Feeds int `json:"feeds"`
+}
+
+type FeedFetch struct {
+	FeedID       uint   `json:"feed_id"`
+	NewItems     int    `json:"new_items,omitempty"`
+	UpdatedItems int    `json:"updated_items,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type NewItems struct {
+	FeedID uint `json:"feed_id"`
+	Count  int  `json:"count"`
+}
+
+type ReadChange struct {
+	IDs    []uint `json:"ids"`
+	Unread bool   `json:"unread"`
+	// Cluster is set when the other items of the same stories changed
+	// too. Their IDs are not listed.
+	Cluster bool `json:"cluster,omitempty"`
+	// SmartFolderID is set instead of IDs when all items of a smart folder
+	// changed.
+	SmartFolderID *uint `json:"smart_folder_id,omitempty"`
+}
+
+type BookmarkChange struct {
+	ID       uint `json:"id"`
+	Bookmark bool `json:"bookmark"`
+}
+
+type UnreadCounts struct {
+	// Counts is the number of unread items by feed ID.
+	Counts map[uint]int `json:"counts"`
+}
+
+type Job struct {
+	ID     uint   `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Event is something that happened.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Bus delivers published events to subscribers.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch    chan Event
+	types []Type
+	once  sync.Once
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish sends an event to all subscribers of its type without waiting for
+// them. A subscriber that doesn't keep up is unsubscribed, which closes its
+// channel, rather than slowing down the publisher.
+func (b *Bus) Publish(t Type, data any) {
+	e := Event{Type: t, Time: time.Now(), Data: data}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		if len(s.types) > 0 && !slices.Contains(s.types, t) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			slog.Warn("event subscriber is too slow, dropping it", "event", t)
+			// can't take the write lock while holding the read lock
+			go b.unsubscribe(s)
+		}
+	}
+}
+
+// Subscribe returns a channel that receives the events of the given types, or
+// all events if no type is given. buffer is the number of events that may
+// queue up before the subscriber is dropped. The returned function
+// unsubscribes and closes the channel, it may be called more than once.
+func (b *Bus) Subscribe(buffer int, types ...Type) (<-chan Event, func()) {
+	s := &subscription{
+		ch:    make(chan Event, buffer),
+		types: types,
+	}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s.ch, func() { b.unsubscribe(s) }
+}
+
+func (b *Bus) unsubscribe(s *subscription) {
+	s.once.Do(func() {
+		b.mu.Lock()
+		delete(b.subs, s)
+		b.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Default is the bus of the application.
+var Default = NewBus()
+
+// Publish publishes an event on the Default bus.
+func Publish(t Type, data any) {
+	Default.Publish(t, data)
+}
+
+// Subscribe subscribes to the Default bus.
+func Subscribe(buffer int, types ...Type) (<-chan Event, func()) {
+	return Default.Subscribe(buffer, types...)
+}
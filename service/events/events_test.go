@@ -0,0 +1,53 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/service/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	bus := events.NewBus()
+	all, unsubscribeAll := bus.Subscribe(10)
+	defer unsubscribeAll()
+	fetches, unsubscribeFetches := bus.Subscribe(10, events.FeedFetchFinished)
+
+	bus.Publish(events.FeedFetchStarted, events.FeedFetch{FeedID: 1})
+	bus.Publish(events.FeedFetchFinished, events.FeedFetch{FeedID: 1, NewItems: 2})
+
+	assert.Equal(t, events.FeedFetchStarted, (<-all).Type)
+	e := <-all
+	assert.Equal(t, events.FeedFetchFinished, e.Type)
+	assert.Equal(t, events.FeedFetch{FeedID: 1, NewItems: 2}, e.Data)
+
+	e = <-fetches
+	assert.Equal(t, events.FeedFetchFinished, e.Type)
+	assert.Empty(t, fetches)
+
+	unsubscribeFetches()
+	unsubscribeFetches()
+	_, ok := <-fetches
+	assert.False(t, ok, "unsubscribing should close the channel")
+	bus.Publish(events.FeedFetchFinished, events.FeedFetch{FeedID: 2})
+	assert.Equal(t, events.FeedFetchFinished, (<-all).Type)
+}
+
+func TestBusDropsSlowSubscribers(t *testing.T) {
+	bus := events.NewBus()
+	slow, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	bus.Publish(events.RefreshStarted, events.Refresh{Feeds: 1})
+	bus.Publish(events.RefreshFinished, events.Refresh{Feeds: 1})
+
+	assert.Equal(t, events.RefreshStarted, (<-slow).Type)
+	select {
+	case _, ok := <-slow:
+		require.False(t, ok, "the event that didn't fit should not be delivered")
+	case <-time.After(time.Second):
+		t.Fatal("the slow subscriber was not dropped")
+	}
+}
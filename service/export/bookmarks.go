@@ -0,0 +1,38 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// writeBookmarks writes a Netscape bookmarks file, which browsers and
+// bookmark managers import, with a folder per feed.
+func writeBookmarks(w io.Writer, items []*model.Item, opts Options) error {
+	bw := bufio.NewWriter(w)
+	esc := html.EscapeString
+	created := opts.Created.Unix()
+
+	fmt.Fprint(bw, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	fmt.Fprint(bw, "<!-- This is an automatically generated file. -->\n")
+	fmt.Fprint(bw, `<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">`+"\n")
+	fmt.Fprintf(bw, "<TITLE>%s</TITLE>\n<H1>%s</H1>\n<DL><p>\n", esc(opts.Title), esc(opts.Title))
+	for _, g := range groupByFeed(items) {
+		fmt.Fprintf(bw, "    <DT><H3 ADD_DATE=\"%d\">%s</H3>\n    <DL><p>\n", created, esc(g.name))
+		for _, item := range g.items {
+			link := ptr.From(item.Link)
+			if link == "" {
+				continue
+			}
+			fmt.Fprintf(bw, "        <DT><A HREF=\"%s\" ADD_DATE=\"%d\">%s</A>\n",
+				esc(link), itemDate(item).Unix(), esc(itemTitle(item)))
+		}
+		fmt.Fprint(bw, "    </DL><p>\n")
+	}
+	fmt.Fprint(bw, "</DL><p>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,353 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// chapter is the page of an item in an EPUB.
+type chapter struct {
+	Item *model.Item
+	ID   string
+	File string
+	// Play is the position of the chapter in the book.
+	Play int
+}
+
+// writeEPUB writes an EPUB 3 book with a chapter per item, a table of
+// contents by feed and the images of the items embedded. The book is
+// written as it's made: each chapter with its images, then the manifest.
+func writeEPUB(ctx context.Context, w io.Writer, items []*model.Item, opts Options) error {
+	book := epubBook{
+		ID:       "urn:uuid:" + newUUID(),
+		Title:    opts.Title,
+		Modified: opts.Created.UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	for _, g := range groupByFeed(items) {
+		toc := epubTOCFeed{Name: g.name}
+		for _, item := range g.items {
+			n := len(book.Chapters) + 1
+			c := &chapter{
+				Item: item,
+				ID:   fmt.Sprintf("item%d", n),
+				File: fmt.Sprintf("items/%d.xhtml", n),
+				Play: n,
+			}
+			book.Chapters = append(book.Chapters, c)
+			toc.Chapters = append(toc.Chapters, c)
+		}
+		book.TOC = append(book.TOC, toc)
+	}
+
+	zw := zip.NewWriter(w)
+	create := func(name string, method uint16) (io.Writer, error) {
+		return zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: opts.Created})
+	}
+	// the mimetype comes first and isn't compressed, so that it can be found
+	// at a fixed offset
+	f, err := create("mimetype", zip.Store)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	f, err = create("OEBPS/style.css", zip.Deflate)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, epubStyle); err != nil {
+		return err
+	}
+
+	images := newImageEmbedder(ctx, opts.FetchImage, create)
+	for _, c := range book.Chapters {
+		// stop when the download was canceled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := contentBody(c.Item)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", c.Item.ID, err)
+		}
+		if err := images.embed(findImages(body)); err != nil {
+			return err
+		}
+		f, err := create("OEBPS/"+c.File, zip.Deflate)
+		if err != nil {
+			return err
+		}
+		if err := writeChapter(f, c.Item, body); err != nil {
+			return fmt.Errorf("item %d: %w", c.Item.ID, err)
+		}
+	}
+	book.Images = images.images
+
+	files := []struct {
+		name string
+		tmpl *template.Template
+	}{
+		{"META-INF/container.xml", containerTmpl},
+		{"OEBPS/content.opf", packageTmpl},
+		{"OEBPS/nav.xhtml", navTmpl},
+		{"OEBPS/toc.ncx", ncxTmpl},
+	}
+	for _, file := range files {
+		f, err := create(file.name, zip.Deflate)
+		if err != nil {
+			return err
+		}
+		if err := file.tmpl.Execute(f, book); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// contentBody returns a body element with the sanitized content of an item,
+// without the audio and video players that e-readers can't play offline.
+func contentBody(item *model.Item) (*html.Node, error) {
+	content, err := cleanContent(item)
+	if err != nil {
+		return nil, err
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	var strip func(n *html.Node)
+	strip = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.DataAtom == atom.Audio || c.DataAtom == atom.Video {
+				n.RemoveChild(c)
+			} else {
+				strip(c)
+			}
+			c = next
+		}
+	}
+	strip(body)
+	return body, nil
+}
+
+// findImages returns the img elements in n.
+func findImages(n *html.Node) []*html.Node {
+	if n.DataAtom == atom.Img {
+		return []*html.Node{n}
+	}
+	var res []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		res = append(res, findImages(c)...)
+	}
+	return res
+}
+
+// writeChapter writes the XHTML page of an item with the body returned by
+// contentBody.
+func writeChapter(w io.Writer, item *model.Item, body *html.Node) error {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE html>` + "\n")
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">` + "\n")
+	fmt.Fprintf(&b, "<head><title>%s</title>", xmlEscape(itemTitle(item)))
+	b.WriteString(`<link rel="stylesheet" type="text/css" href="../style.css"/></head>` + "\n")
+	fmt.Fprintf(&b, "<body>\n<h1>%s</h1>\n<p class=\"meta\">%s &#183; %s",
+		xmlEscape(itemTitle(item)), xmlEscape(ptr.From(item.Feed.Name)), itemDate(item).Format("2 January 2006"))
+	if link := ptr.From(item.Link); link != "" {
+		fmt.Fprintf(&b, ` &#183; <a href="%s">Original</a>`, xmlEscape(link))
+	}
+	b.WriteString("</p>\n")
+	for n := body.FirstChild; n != nil; n = n.NextSibling {
+		if err := renderXHTML(&b, n); err != nil {
+			return err
+		}
+	}
+	b.WriteString("\n</body>\n</html>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// voidElements are the elements without children, which XHTML closes with
+// "/>".
+var voidElements = map[atom.Atom]bool{atom.Br: true, atom.Hr: true, atom.Img: true, atom.Source: true}
+
+// renderXHTML writes n as XHTML. html.Render writes HTML, where attributes
+// may have no value and void elements aren't closed.
+func renderXHTML(w *bytes.Buffer, n *html.Node) error {
+	switch n.Type {
+	case html.TextNode:
+		w.WriteString(xmlEscape(n.Data))
+		return nil
+	case html.ElementNode:
+	default:
+		return nil
+	}
+	w.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		val := a.Val
+		if val == "" && a.Key != "alt" {
+			val = a.Key
+		}
+		fmt.Fprintf(w, ` %s="%s"`, a.Key, xmlEscape(val))
+	}
+	if voidElements[n.DataAtom] {
+		w.WriteString("/>")
+		return nil
+	}
+	w.WriteString(">")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := renderXHTML(w, c); err != nil {
+			return err
+		}
+	}
+	w.WriteString("</" + n.Data + ">")
+	return nil
+}
+
+// xmlEscape escapes s for XML text and attributes, dropping the characters
+// that XML doesn't allow.
+func xmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, strings.ToValidUTF8(s, "\uFFFD"))
+	return html.EscapeString(s)
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+type epubTOCFeed struct {
+	Name     string
+	Chapters []*chapter
+}
+
+type epubBook struct {
+	ID       string
+	Title    string
+	Modified string
+	Chapters []*chapter
+	TOC      []epubTOCFeed
+	Images   []*epubImage
+}
+
+var epubFuncs = template.FuncMap{
+	"xml":   xmlEscape,
+	"title": itemTitle,
+	"add":   func(a, b int) int { return a + b },
+}
+
+var containerTmpl = template.Must(template.New("container").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`))
+
+var packageTmpl = template.Must(template.New("package").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{.ID}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+    <dc:language>en</dc:language>
+    <dc:creator>Fusion</dc:creator>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+{{- range .Chapters}}
+    <item id="{{.ID}}" href="{{.File}}" media-type="application/xhtml+xml"/>
+{{- end}}
+{{- range .Images}}
+    <item id="{{.ID}}" href="{{.File}}" media-type="{{.MediaType}}"/>
+{{- end}}
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="nav"/>
+{{- range .Chapters}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+`))
+
+var navTmpl = template.Must(template.New("nav").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>{{xml .Title}}</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+<nav epub:type="toc" id="toc">
+<h1>{{xml .Title}}</h1>
+<ol>
+{{- range .TOC}}
+<li><a href="{{(index .Chapters 0).File}}">{{xml .Name}}</a>
+<ol>
+{{- range .Chapters}}
+<li><a href="{{.File}}">{{xml (title .Item)}}</a></li>
+{{- end}}
+</ol>
+</li>
+{{- end}}
+</ol>
+</nav>
+</body>
+</html>
+`))
+
+var ncxTmpl = template.Must(template.New("ncx").Funcs(epubFuncs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:uid" content="{{.ID}}"/></head>
+<docTitle><text>{{xml .Title}}</text></docTitle>
+<navMap>
+{{- range $i, $feed := .TOC}}
+<navPoint id="feed{{add $i 1}}" playOrder="{{(index $feed.Chapters 0).Play}}">
+<navLabel><text>{{xml $feed.Name}}</text></navLabel>
+<content src="{{(index $feed.Chapters 0).File}}"/>
+{{- range $feed.Chapters}}
+<navPoint id="nav-{{.ID}}" playOrder="{{.Play}}">
+<navLabel><text>{{xml (title .Item)}}</text></navLabel>
+<content src="{{.File}}"/>
+</navPoint>
+{{- end}}
+</navPoint>
+{{- end}}
+</navMap>
+</ncx>
+`))
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+h1 { font-size: 1.5em; }
+.meta { color: #666; font-size: 0.9em; }
+img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; }
+blockquote { margin-left: 1em; padding-left: 1em; border-left: 3px solid #ccc; }
+`
@@ -0,0 +1,143 @@
+// Package export writes items to files for other apps: JSON, a browser
+// bookmarks file, a Markdown bundle or an EPUB for e-readers.
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/sanitize"
+)
+
+// Export formats.
+const (
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatEPUB     = "epub"
+)
+
+// Formats are the supported export formats.
+var Formats = []string{FormatJSON, FormatHTML, FormatMarkdown, FormatEPUB}
+
+// ErrUnknownFormat is returned for formats that aren't in Formats.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Options describe an export.
+type Options struct {
+	Title   string
+	Created time.Time
+	// FetchImage downloads the images embedded in EPUBs, FetchImage if nil.
+	FetchImage ImageFetcher
+}
+
+// ContentType returns the media type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatMarkdown:
+		return "application/zip"
+	case FormatEPUB:
+		return "application/epub+zip"
+	}
+	return "application/octet-stream"
+}
+
+// Filename returns the name of an export file created at t.
+func Filename(format string, t time.Time) string {
+	ext := map[string]string{
+		FormatJSON:     ".json",
+		FormatHTML:     ".html",
+		FormatMarkdown: ".zip",
+		FormatEPUB:     ".epub",
+	}[format]
+	return "fusion-export-" + t.Format("2006-01-02") + ext
+}
+
+// Write writes items, which must have their feed, in format to w.
+func Write(ctx context.Context, w io.Writer, format string, items []*model.Item, opts Options) error {
+	if opts.Created.IsZero() {
+		opts.Created = time.Now()
+	}
+	if opts.Title == "" {
+		opts.Title = "Fusion export"
+	}
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, items, opts)
+	case FormatHTML:
+		return writeBookmarks(w, items, opts)
+	case FormatMarkdown:
+		return writeMarkdown(w, items, opts)
+	case FormatEPUB:
+		if opts.FetchImage == nil {
+			opts.FetchImage = FetchImage
+		}
+		return writeEPUB(ctx, w, items, opts)
+	}
+	return ErrUnknownFormat
+}
+
+// feedGroup is the items of a feed, in the order of the export.
+type feedGroup struct {
+	name  string
+	items []*model.Item
+}
+
+// groupByFeed groups items by feed in the order in which the feeds first
+// appear.
+func groupByFeed(items []*model.Item) []*feedGroup {
+	groups := make([]*feedGroup, 0)
+	byFeed := make(map[uint]*feedGroup)
+	for _, item := range items {
+		g, ok := byFeed[item.FeedID]
+		if !ok {
+			g = &feedGroup{name: ptr.From(item.Feed.Name)}
+			if g.name == "" {
+				g.name = ptr.From(item.Feed.Link)
+			}
+			byFeed[item.FeedID] = g
+			groups = append(groups, g)
+		}
+		g.items = append(g.items, item)
+	}
+	return groups
+}
+
+// itemTitle returns the title of an item, or its link if it has none.
+func itemTitle(item *model.Item) string {
+	if title := strings.TrimSpace(ptr.From(item.Title)); title != "" {
+		return title
+	}
+	if link := ptr.From(item.Link); link != "" {
+		return link
+	}
+	return "Untitled"
+}
+
+// itemDate returns the publication date of an item, or when it was added.
+func itemDate(item *model.Item) time.Time {
+	if item.PubDate != nil {
+		return *item.PubDate
+	}
+	return item.CreatedAt
+}
+
+// cleanContent returns the sanitized content of an item with links resolved
+// against the item's link.
+func cleanContent(item *model.Item) (string, error) {
+	base, err := url.Parse(ptr.From(item.Link))
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+	return sanitize.HTML(ptr.From(item.Content), base)
+}
@@ -0,0 +1,232 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/export"
+)
+
+var (
+	goFeed   = model.Feed{ID: 1, Name: ptr.To("Go blog"), Link: ptr.To("https://go.dev/blog/feed.atom")}
+	newsFeed = model.Feed{ID: 2, Name: ptr.To("News & more"), Link: ptr.To("https://news.example.com/rss")}
+	pubDate  = time.Date(2024, 8, 12, 22, 0, 0, 0, time.UTC)
+)
+
+func testItems() []*model.Item {
+	return []*model.Item{
+		{
+			ID: 1, FeedID: 1, Feed: goFeed,
+			Title:    ptr.To("Range functions"),
+			Link:     ptr.To("https://go.dev/blog/range-functions"),
+			Content:  ptr.To(`<p>Use <code>iter.Seq</code> with <b>range</b>.</p><img src="/gopher.png" alt="Gopher"><img src="https://cdn.example.com/gone.png" alt="Missing"><script>alert(1)</script>`),
+			PubDate:  &pubDate,
+			Bookmark: ptr.To(true),
+		},
+		{
+			ID: 2, FeedID: 2, Feed: newsFeed,
+			Title:   ptr.To("Headline <1>"),
+			Link:    ptr.To("https://news.example.com/1"),
+			Content: ptr.To(`<ul><li>one</li><li>two</li></ul><video src="https://news.example.com/v.mp4"></video>`),
+			PubDate: &pubDate,
+		},
+		{
+			ID: 3, FeedID: 1, Feed: goFeed,
+			Title:   ptr.To("Range functions"),
+			Link:    ptr.To("https://go.dev/blog/range-functions-2"),
+			Content: ptr.To(`<img src="https://go.dev/gopher.png" alt="Gopher again">`),
+			PubDate: &pubDate,
+		},
+	}
+}
+
+var created = time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func write(t *testing.T, format string, opts export.Options) []byte {
+	t.Helper()
+	opts.Created = created
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(context.Background(), &buf, format, testItems(), opts))
+	return buf.Bytes()
+}
+
+func unzip(t *testing.T, data []byte) (*zip.Reader, map[string]string) {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	return r, files
+}
+
+func TestWriteJSON(t *testing.T) {
+	var got struct {
+		Items []struct {
+			ID      uint   `json:"id"`
+			Title   string `json:"title"`
+			Content string `json:"content"`
+			Feed    struct {
+				Name string `json:"name"`
+			} `json:"feed"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(write(t, export.FormatJSON, export.Options{}), &got))
+	require.Len(t, got.Items, 3)
+	assert.Equal(t, "Headline <1>", got.Items[1].Title)
+	assert.Equal(t, "News & more", got.Items[1].Feed.Name)
+	assert.Contains(t, got.Items[0].Content, "<script>", "the original content is kept")
+}
+
+func TestWriteBookmarks(t *testing.T) {
+	got := string(write(t, export.FormatHTML, export.Options{Title: "Saved"}))
+	assert.True(t, strings.HasPrefix(got, "<!DOCTYPE NETSCAPE-Bookmark-file-1>"))
+	assert.Contains(t, got, "<TITLE>Saved</TITLE>")
+	assert.Contains(t, got, `<H3 ADD_DATE="1725148800">News &amp; more</H3>`)
+	assert.Contains(t, got, `<A HREF="https://news.example.com/1" ADD_DATE="1723500000">Headline &lt;1&gt;</A>`)
+	// items are grouped by feed
+	assert.Less(t, strings.Index(got, "range-functions-2"), strings.Index(got, "News &amp; more"))
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	_, files := unzip(t, write(t, export.FormatMarkdown, export.Options{}))
+	assert.Len(t, files, 4)
+
+	index := files["index.md"]
+	assert.Contains(t, index, "## Go blog\n\n- [Range functions](go-blog/2024-08-12-range-functions.md)\n- [Range functions](go-blog/2024-08-12-range-functions-2.md)\n")
+	assert.Contains(t, index, "## News & more\n\n- [Headline \\<1\\>](news-more/2024-08-12-headline-1.md)\n")
+
+	assert.Equal(t, `---
+title: "Range functions"
+link: "https://go.dev/blog/range-functions"
+feed: "Go blog"
+date: 2024-08-12T22:00:00Z
+bookmark: true
+---
+
+# Range functions
+
+Use `+"`iter.Seq`"+` with **range**.
+
+![Gopher](https://go.dev/gopher.png)![Missing](https://cdn.example.com/gone.png)
+`, files["go-blog/2024-08-12-range-functions.md"])
+	assert.Contains(t, files["news-more/2024-08-12-headline-1.md"], "- one\n- two\n\n[video](https://news.example.com/v.mp4)")
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	item := &model.Item{Feed: goFeed, Title: ptr.To("t"), Content: ptr.To(`<h2>Steps</h2>
+<ol start="3"><li>First <a href="https://example.com/a b">link</a></li><li><p>Second</p><ul><li>nested</li></ul></li></ol>
+<blockquote><p>Quoted</p><p>twice</p></blockquote>
+<pre><code>for range 3 {
+}</code></pre>
+<table><tr><th>a</th><th>b|c</th></tr><tr><td>1</td></tr></table>
+<p>1. not a list, *not* emphasis<br>next line</p>`)}
+	var buf bytes.Buffer
+	require.NoError(t, export.Write(context.Background(), &buf, export.FormatMarkdown, []*model.Item{item}, export.Options{}))
+	_, files := unzip(t, buf.Bytes())
+	got := files["go-blog/0001-01-01-t.md"]
+	assert.Contains(t, got, `## Steps
+
+3. First [link](https://example.com/a%20b)
+4. Second
+
+   - nested
+
+> Quoted
+>
+> twice
+
+`+"```\nfor range 3 {\n}\n```"+`
+
+| a | b\|c |
+| --- | --- |
+| 1 |  |
+
+1\. not a list, \*not\* emphasis  
+next line
+`)
+}
+
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestWriteEPUB(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []string
+	fetch := func(ctx context.Context, link string) ([]byte, string, error) {
+		mu.Lock()
+		fetched = append(fetched, link)
+		mu.Unlock()
+		if link == "https://go.dev/gopher.png" {
+			return png, "image/png", nil
+		}
+		return nil, "", errors.New("not found")
+	}
+	data := write(t, export.FormatEPUB, export.Options{Title: "Saved", FetchImage: fetch})
+	r, files := unzip(t, data)
+
+	assert.Equal(t, "mimetype", r.File[0].Name)
+	assert.Equal(t, zip.Store, r.File[0].Method)
+	assert.Equal(t, "application/epub+zip", files["mimetype"])
+	assert.ElementsMatch(t, []string{"https://go.dev/gopher.png", "https://cdn.example.com/gone.png"}, fetched,
+		"images are downloaded once")
+	assert.Equal(t, string(png), files["OEBPS/images/1.png"])
+
+	// all documents are well-formed XML
+	for name, content := range files {
+		if strings.HasSuffix(name, ".xhtml") || strings.HasSuffix(name, ".opf") ||
+			strings.HasSuffix(name, ".ncx") || strings.HasSuffix(name, ".xml") {
+			dec := xml.NewDecoder(strings.NewReader(content))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, name)
+			}
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	assert.Contains(t, opf, `<item id="img1" href="images/1.png" media-type="image/png"/>`)
+	assert.Contains(t, opf, `<itemref idref="item3"/>`)
+
+	nav := files["OEBPS/nav.xhtml"]
+	assert.Contains(t, nav, `<li><a href="items/1.xhtml">Go blog</a>
+<ol>
+<li><a href="items/1.xhtml">Range functions</a></li>
+<li><a href="items/2.xhtml">Range functions</a></li>
+</ol>
+</li>
+<li><a href="items/3.xhtml">News &amp; more</a>`)
+
+	first := files["OEBPS/items/1.xhtml"]
+	assert.Contains(t, first, `<img src="../images/1.png" alt="Gopher"/>[Missing]`)
+	assert.NotContains(t, first, "script")
+	assert.Contains(t, files["OEBPS/items/2.xhtml"], `<img src="../images/1.png" alt="Gopher again"/>`)
+	assert.Contains(t, files["OEBPS/items/3.xhtml"], "<h1>Headline &lt;1&gt;</h1>")
+	assert.NotContains(t, files["OEBPS/items/3.xhtml"], "video")
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	err := export.Write(context.Background(), io.Discard, "pdf", nil, export.Options{})
+	assert.ErrorIs(t, err, export.ErrUnknownFormat)
+}
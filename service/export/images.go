@@ -0,0 +1,193 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+)
+
+// ImageFetcher downloads the image at link and returns it with its media
+// type.
+type ImageFetcher func(ctx context.Context, link string) ([]byte, string, error)
+
+// Limits of the images embedded in an EPUB. Images beyond them are replaced
+// by their alternative text.
+const (
+	maxImageSize      = 10 << 20
+	maxImagesSize     = 200 << 20
+	maxImages         = 2000
+	imageTimeout      = 30 * time.Second
+	imageFetchWorkers = 4
+)
+
+// imageExtensions are the image types that EPUB readers must support.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var errImageType = errors.New("unsupported image type")
+
+// FetchImage downloads an image over HTTP. Links to private addresses are
+// refused, see httpx.PublicRequest.
+func FetchImage(ctx context.Context, link string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageTimeout)
+	defer cancel()
+	resp, err := httpx.PublicRequest(ctx, link)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("got status code %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", errors.New("image too large")
+	}
+	// servers often get the type wrong, trust the content
+	return data, http.DetectContentType(data), nil
+}
+
+// epubImage is an image embedded in an EPUB.
+type epubImage struct {
+	ID        string
+	File      string
+	MediaType string
+}
+
+// imageEmbedder downloads the images of the chapters of a book as they are
+// written, so that only the images of one chapter are held in memory.
+// Images are downloaded once, and images that can't be downloaded or are
+// beyond the limits are replaced by their alternative text.
+type imageEmbedder struct {
+	ctx    context.Context
+	fetch  ImageFetcher
+	create func(name string, method uint16) (io.Writer, error)
+	// files are the files of the images in the book by link, empty for
+	// images that weren't embedded.
+	files  map[string]string
+	images []*epubImage
+	total  int
+}
+
+func newImageEmbedder(ctx context.Context, fetch ImageFetcher, create func(name string, method uint16) (io.Writer, error)) *imageEmbedder {
+	return &imageEmbedder{
+		ctx:    ctx,
+		fetch:  fetch,
+		create: create,
+		files:  make(map[string]string),
+	}
+}
+
+// embed downloads the images of imgs that weren't seen yet, writes them to
+// the book and points imgs to their copy.
+func (e *imageEmbedder) embed(imgs []*html.Node) error {
+	var links []string
+	for _, img := range imgs {
+		src := attr(img, "src")
+		if _, ok := e.files[src]; ok || src == "" || slices.Contains(links, src) {
+			continue
+		}
+		if len(e.files)+len(links) >= maxImages {
+			break
+		}
+		links = append(links, src)
+	}
+
+	for i, r := range fetchImages(e.ctx, links, e.fetch) {
+		link := links[i]
+		if r.data == nil || e.total+len(r.data) > maxImagesSize {
+			e.files[link] = ""
+			continue
+		}
+		e.total += len(r.data)
+		n := len(e.images) + 1
+		image := &epubImage{
+			ID:        fmt.Sprintf("img%d", n),
+			File:      fmt.Sprintf("images/%d%s", n, imageExtensions[r.mediaType]),
+			MediaType: r.mediaType,
+		}
+		// images are compressed already
+		f, err := e.create("OEBPS/"+image.File, zip.Store)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(r.data); err != nil {
+			return err
+		}
+		e.images = append(e.images, image)
+		e.files[link] = image.File
+	}
+
+	for _, img := range imgs {
+		if file := e.files[attr(img, "src")]; file != "" {
+			// chapters are in items/
+			setAttr(img, "src", "../"+file)
+			continue
+		}
+		if alt := strings.TrimSpace(attr(img, "alt")); alt != "" {
+			img.Parent.InsertBefore(&html.Node{Type: html.TextNode, Data: "[" + alt + "]"}, img)
+		}
+		img.Parent.RemoveChild(img)
+	}
+	return nil
+}
+
+type fetchedImage struct {
+	data      []byte
+	mediaType string
+}
+
+// fetchImages downloads the images at links in parallel. Images that can't
+// be downloaded or have an unsupported type have no data.
+func fetchImages(ctx context.Context, links []string, fetch ImageFetcher) []fetchedImage {
+	results := make([]fetchedImage, len(links))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range imageFetchWorkers {
+		wg.Go(func() {
+			for i := range jobs {
+				data, mediaType, err := fetch(ctx, links[i])
+				if err == nil && imageExtensions[mediaType] == "" {
+					err = errImageType
+				}
+				if err == nil {
+					results[i] = fetchedImage{data: data, mediaType: mediaType}
+				}
+			}
+		})
+	}
+	for i := range links {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
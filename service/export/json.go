@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+type jsonFeed struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+type jsonItem struct {
+	ID       uint       `json:"id"`
+	Title    string     `json:"title"`
+	Link     string     `json:"link"`
+	GUID     string     `json:"guid"`
+	Content  string     `json:"content"`
+	PubDate  *time.Time `json:"pub_date"`
+	Unread   bool       `json:"unread"`
+	Bookmark bool       `json:"bookmark"`
+	Feed     jsonFeed   `json:"feed"`
+}
+
+type jsonExport struct {
+	Title      string      `json:"title"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Items      []*jsonItem `json:"items"`
+}
+
+// writeJSON writes the items with their original content.
+func writeJSON(w io.Writer, items []*model.Item, opts Options) error {
+	res := jsonExport{
+		Title:      opts.Title,
+		ExportedAt: opts.Created,
+		Items:      make([]*jsonItem, 0, len(items)),
+	}
+	for _, item := range items {
+		res.Items = append(res.Items, &jsonItem{
+			ID:       item.ID,
+			Title:    ptr.From(item.Title),
+			Link:     ptr.From(item.Link),
+			GUID:     ptr.From(item.GUID),
+			Content:  ptr.From(item.Content),
+			PubDate:  item.PubDate,
+			Unread:   ptr.From(item.Unread),
+			Bookmark: ptr.From(item.Bookmark),
+			Feed: jsonFeed{
+				ID:   item.FeedID,
+				Name: ptr.From(item.Feed.Name),
+				Link: ptr.From(item.Feed.Link),
+			},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(res)
+}
@@ -0,0 +1,369 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// writeMarkdown writes a ZIP archive with a Markdown file per item, in a
+// folder per feed, and an index.md linking to all of them.
+func writeMarkdown(w io.Writer, items []*model.Item, opts Options) error {
+	zw := zip.NewWriter(w)
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n\nExported on %s.\n", escapeMarkdown(opts.Title), opts.Created.Format("2006-01-02"))
+
+	used := make(map[string]bool)
+	for _, g := range groupByFeed(items) {
+		dir := uniqueName(used, slugify(g.name), "")
+		fmt.Fprintf(&index, "\n## %s\n\n", escapeMarkdown(g.name))
+		for _, item := range g.items {
+			name := uniqueName(used, path.Join(dir, itemDate(item).Format("2006-01-02")+"-"+slugify(itemTitle(item))), ".md")
+			content, err := itemMarkdown(item)
+			if err != nil {
+				return fmt.Errorf("item %d: %w", item.ID, err)
+			}
+			f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: itemDate(item)})
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(f, content); err != nil {
+				return err
+			}
+			fmt.Fprintf(&index, "- [%s](%s)\n", escapeMarkdown(itemTitle(item)), markdownURL(name))
+		}
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "index.md", Method: zip.Deflate, Modified: opts.Created})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, index.String()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// itemMarkdown returns an item as Markdown with its metadata in YAML front
+// matter.
+func itemMarkdown(item *model.Item) (string, error) {
+	content, err := cleanContent(item)
+	if err != nil {
+		return "", err
+	}
+	body, err := htmlToMarkdown(content)
+	if err != nil {
+		return "", err
+	}
+
+	// JSON strings are valid YAML and need no other escaping
+	quote := func(s string) string {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", quote(itemTitle(item)))
+	if link := ptr.From(item.Link); link != "" {
+		fmt.Fprintf(&b, "link: %s\n", quote(link))
+	}
+	fmt.Fprintf(&b, "feed: %s\n", quote(ptr.From(item.Feed.Name)))
+	fmt.Fprintf(&b, "date: %s\n", itemDate(item).Format("2006-01-02T15:04:05Z07:00"))
+	fmt.Fprintf(&b, "bookmark: %t\n", ptr.From(item.Bookmark))
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", escapeMarkdown(itemTitle(item)))
+	if body != "" {
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// uniqueName returns name+ext, adding a number to name if it's already used.
+func uniqueName(used map[string]bool, name, ext string) string {
+	res := name + ext
+	for n := 2; used[res]; n++ {
+		res = name + "-" + strconv.Itoa(n) + ext
+	}
+	used[res] = true
+	return res
+}
+
+// slugify returns s in lower case with other characters than letters and
+// digits replaced by dashes, for file names.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "untitled"
+	}
+	return b.String()
+}
+
+// markdownURL escapes the characters of a link target that end it.
+func markdownURL(link string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(link)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// escapeMarkdown escapes the characters of text that Markdown would read as
+// formatting.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var (
+	spaces     = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLines = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+	// orderedLine is a line that Markdown would read as an ordered list item.
+	orderedLine = regexp.MustCompile(`(?m)^(\s*\d+)\.`)
+)
+
+// htmlToMarkdown converts sanitized HTML to Markdown. Elements without a
+// Markdown equivalent are replaced by their text.
+func htmlToMarkdown(content string) (string, error) {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), root)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		writeMarkdownNode(&b, n)
+	}
+	res := blankLines.ReplaceAllString(b.String(), "\n\n")
+	return strings.TrimSpace(res), nil
+}
+
+// markdownChildren returns the Markdown of the children of n.
+func markdownChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeMarkdownNode(&b, c)
+	}
+	return b.String()
+}
+
+// inlineMarkdown returns the Markdown of the children of n on a single line.
+func inlineMarkdown(n *html.Node) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(markdownChildren(n), " "))
+}
+
+// textContent returns the text of n and its children.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// prefixLines prefixes the lines of s, using first for the first line.
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(strings.Trim(s, "\n"), "\n")
+	for i, l := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + l
+		case strings.TrimSpace(l) == "":
+			lines[i] = strings.TrimRight(rest, " ")
+		default:
+			lines[i] = rest + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emphasis wraps the Markdown of the children of n in marker.
+func emphasis(n *html.Node, marker string) string {
+	s := markdownChildren(n)
+	inner := strings.TrimSpace(s)
+	if inner == "" {
+		return s
+	}
+	// keep the surrounding spaces outside of the markers
+	lead := s[:strings.Index(s, inner)]
+	trail := s[len(lead)+len(inner):]
+	return lead + marker + inner + marker + trail
+}
+
+func writeMarkdownNode(b *strings.Builder, n *html.Node) {
+	if n.Type == html.TextNode {
+		text := spaces.ReplaceAllString(n.Data, " ")
+		b.WriteString(orderedLine.ReplaceAllString(escapeMarkdown(text), `$1\.`))
+		return
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		fmt.Fprintf(b, "\n\n%s %s\n\n", strings.Repeat("#", level), inlineMarkdown(n))
+	case atom.P, atom.Div, atom.Figure, atom.Details, atom.Summary, atom.Dl, atom.Dd, atom.Dt, atom.Caption:
+		fmt.Fprintf(b, "\n\n%s\n\n", strings.TrimSpace(markdownChildren(n)))
+	case atom.Br:
+		b.WriteString("  \n")
+	case atom.Hr:
+		b.WriteString("\n\n---\n\n")
+	case atom.Strong, atom.B:
+		b.WriteString(emphasis(n, "**"))
+	case atom.Em, atom.I, atom.Cite:
+		b.WriteString(emphasis(n, "*"))
+	case atom.Del, atom.S:
+		b.WriteString(emphasis(n, "~~"))
+	case atom.Code:
+		code := textContent(n)
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			code = " " + code + " "
+		}
+		b.WriteString(fence + code + fence)
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(b, "\n\n%s\n%s\n%s\n\n", fence, code, fence)
+	case atom.A:
+		text := inlineMarkdown(n)
+		href := attr(n, "href")
+		if href == "" {
+			b.WriteString(text)
+			return
+		}
+		if text == "" {
+			text = escapeMarkdown(href)
+		}
+		fmt.Fprintf(b, "[%s](%s)", text, markdownURL(href))
+	case atom.Img:
+		if src := attr(n, "src"); src != "" {
+			fmt.Fprintf(b, "![%s](%s)", escapeMarkdown(attr(n, "alt")), markdownURL(src))
+		}
+	case atom.Audio, atom.Video:
+		src := attr(n, "src")
+		for c := n.FirstChild; c != nil && src == ""; c = c.NextSibling {
+			if c.DataAtom == atom.Source {
+				src = attr(c, "src")
+			}
+		}
+		if src != "" {
+			fmt.Fprintf(b, "\n\n[%s](%s)\n\n", n.Data, markdownURL(src))
+		}
+	case atom.Blockquote:
+		quote := blankLines.ReplaceAllString(strings.TrimSpace(markdownChildren(n)), "\n\n")
+		fmt.Fprintf(b, "\n\n%s\n\n", prefixLines(quote, "> ", "> "))
+	case atom.Ul, atom.Ol:
+		b.WriteString("\n\n")
+		num, _ := strconv.Atoi(attr(n, "start"))
+		if num == 0 {
+			num = 1
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom != atom.Li {
+				continue
+			}
+			marker := "- "
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(num) + ". "
+				num++
+			}
+			item := blankLines.ReplaceAllString(strings.TrimSpace(markdownChildren(c)), "\n\n")
+			b.WriteString(prefixLines(item, marker, strings.Repeat(" ", len(marker))))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	case atom.Table:
+		writeMarkdownTable(b, n)
+	default:
+		b.WriteString(markdownChildren(n))
+	}
+}
+
+// writeMarkdownTable writes a table with the first row as its header.
+func writeMarkdownTable(b *strings.Builder, table *html.Node) {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						row = append(row, strings.ReplaceAll(inlineMarkdown(cell), "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			}
+		}
+	}
+	walk(table)
+	if len(rows) == 0 {
+		return
+	}
+
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	b.WriteString("\n\n")
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+	b.WriteString("\n")
+}
@@ -12,6 +12,9 @@ import (
 	"time"
 )
 
+// CacheDir is the directory favicons are cached in.
+const CacheDir = "./cache/favicons"
+
 type Service struct {
 	cacheDir string
 	client   *http.Client
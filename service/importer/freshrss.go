@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxZipEntrySize limits the size of a file extracted from an export.
+const maxZipEntrySize = 256 << 20
+
+// parseFreshRSS reads the ZIP archive exported by FreshRSS, which contains
+// the subscriptions as OPML and the starred and optionally all articles as
+// Google Reader JSON. The files of the archive may also be given
+// separately.
+func parseFreshRSS(f File, data *Data) error {
+	if !bytes.HasPrefix(f.Content, []byte("PK\x03\x04")) {
+		return parseGReader(f, data)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(f.Content), int64(len(f.Content)))
+	if err != nil {
+		return err
+	}
+	for _, zf := range archive.File {
+		name := strings.ToLower(zf.Name)
+		if zf.FileInfo().IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".xml")) {
+			continue
+		}
+		content, err := readZipFile(zf)
+		if err != nil {
+			return fmt.Errorf("%s: %w", zf.Name, err)
+		}
+		entry := File{Name: zf.Name, Content: content}
+		if isOPML(content) {
+			err = parseOPML(entry, data)
+		} else {
+			err = parseGReader(entry, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", zf.Name, err)
+		}
+	}
+	return nil
+}
+
+func readZipFile(zf *zip.File) ([]byte, error) {
+	r, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(io.LimitReader(r, maxZipEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxZipEntrySize {
+		return nil, fmt.Errorf("larger than %d MiB", maxZipEntrySize>>20)
+	}
+	return content, nil
+}
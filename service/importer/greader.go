@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+// greaderStreamPrefix starts the stream IDs of feeds.
+const greaderStreamPrefix = "feed/"
+
+// greaderExport is a Google Reader JSON export, as written by FreshRSS,
+// Feedbin and Inoreader for starred items, or a subscription list.
+type greaderExport struct {
+	Items []struct {
+		Title      string   `json:"title"`
+		Published  int64    `json:"published"`
+		Categories []string `json:"categories"`
+		Alternate  []struct {
+			Href string `json:"href"`
+		} `json:"alternate"`
+		Canonical []struct {
+			Href string `json:"href"`
+		} `json:"canonical"`
+		Content *struct {
+			Content string `json:"content"`
+		} `json:"content"`
+		Summary *struct {
+			Content string `json:"content"`
+		} `json:"summary"`
+		Origin struct {
+			StreamID string `json:"streamId"`
+			Title    string `json:"title"`
+		} `json:"origin"`
+	} `json:"items"`
+	Subscriptions []struct {
+		ID         string `json:"id"`
+		Title      string `json:"title"`
+		URL        string `json:"url"`
+		Categories []struct {
+			Label string `json:"label"`
+		} `json:"categories"`
+	} `json:"subscriptions"`
+}
+
+// parseGReader reads a Google Reader JSON export. All items of files named
+// starred*.json are starred, as some readers don't repeat the state in
+// each item.
+func parseGReader(f File, data *Data) error {
+	starred := strings.HasPrefix(strings.ToLower(path.Base(f.Name)), "starred")
+	var export greaderExport
+	if err := json.Unmarshal(f.Content, &export); err != nil {
+		return err
+	}
+	if export.Items == nil && export.Subscriptions == nil {
+		return errors.New("neither a list of items nor of subscriptions")
+	}
+
+	for _, s := range export.Subscriptions {
+		feed := opml.Feed{Name: s.Title, Link: s.URL}
+		if feed.Link == "" {
+			feed.Link = strings.TrimPrefix(s.ID, greaderStreamPrefix)
+		}
+		if len(s.Categories) > 0 {
+			feed.Group = s.Categories[0].Label
+		}
+		data.Feeds = append(data.Feeds, feed)
+	}
+
+	for _, v := range export.Items {
+		if !strings.HasPrefix(v.Origin.StreamID, greaderStreamPrefix) {
+			continue
+		}
+		e := &Entry{
+			FeedLink: strings.TrimPrefix(v.Origin.StreamID, greaderStreamPrefix),
+			FeedName: v.Origin.Title,
+			Title:    v.Title,
+			Starred:  starred,
+		}
+		switch {
+		case len(v.Canonical) > 0:
+			e.Link = v.Canonical[0].Href
+		case len(v.Alternate) > 0:
+			e.Link = v.Alternate[0].Href
+		}
+		switch {
+		case v.Content != nil:
+			e.Content = v.Content.Content
+		case v.Summary != nil:
+			e.Content = v.Summary.Content
+		}
+		if v.Published > 0 {
+			e.PubDate = ptr.To(time.Unix(v.Published, 0))
+		}
+		// states are "user/-/state/com.google/read", or with the user's
+		// ID instead of "-"
+		for _, c := range v.Categories {
+			switch {
+			case strings.HasSuffix(c, "/state/com.google/read"):
+				e.Read = true
+			case strings.HasSuffix(c, "/state/com.google/starred"):
+				e.Starred = true
+			}
+		}
+		data.addEntry(e)
+	}
+	return nil
+}
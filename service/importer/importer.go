@@ -0,0 +1,326 @@
+// Package importer migrates subscriptions, bookmarks and read history from
+// other feed readers.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/simhash"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+// Entry is an article exported by another reader.
+type Entry struct {
+	FeedLink string
+	FeedName string
+	// GUID is the feed's own GUID of the article, if the export has it.
+	GUID    string
+	Title   string
+	Link    string
+	Content string
+	PubDate *time.Time
+	Read    bool
+	Starred bool
+}
+
+// Data is what was found in the exported files.
+type Data struct {
+	Feeds   []opml.Feed
+	Entries []*Entry
+}
+
+// Options change what is imported.
+type Options struct {
+	// History also imports the entries that aren't starred, marked read,
+	// so that they don't show up as unread when the feed is pulled.
+	History bool
+}
+
+// Result is what an import created. Feeds and groups that already existed
+// aren't counted, and neither are items that already existed with the same
+// state, so importing the same files again is safe.
+type Result struct {
+	Groups    int `json:"groups"`
+	Feeds     int `json:"feeds"`
+	Bookmarks int `json:"bookmarks"`
+	History   int `json:"history"`
+}
+
+type FeedRepo interface {
+	List(filter *repo.FeedListFilter) ([]*model.Feed, error)
+	Create(feeds []*model.Feed) error
+}
+
+type GroupRepo interface {
+	All() ([]*model.Group, error)
+	Create(group *model.Group) error
+}
+
+type ItemRepo interface {
+	Insert(items []*model.Item) (int, error)
+	ListByKeys(feedID uint, guids, links []string) ([]*model.Item, error)
+	Update(id uint, item *model.Item) error
+}
+
+// Progress receives the progress of Run, in entries.
+type Progress interface {
+	SetTotal(n int)
+	Add(n int)
+}
+
+// insertBatch is the number of items inserted at once.
+const insertBatch = 500
+
+type Importer struct {
+	feedRepo  FeedRepo
+	groupRepo GroupRepo
+	itemRepo  ItemRepo
+}
+
+func NewImporter(feedRepo FeedRepo, groupRepo GroupRepo, itemRepo ItemRepo) *Importer {
+	return &Importer{
+		feedRepo:  feedRepo,
+		groupRepo: groupRepo,
+		itemRepo:  itemRepo,
+	}
+}
+
+// Run creates the missing groups and feeds of data, then inserts the starred
+// entries as bookmarked items and, with Options.History, the other entries
+// as read items. Feeds that only appear in entries go to the default group.
+// Entries that are already stored, such as those of a feed that was pulled
+// before, are found by their GUID or canonical link, and only their state
+// is changed. progress may be nil.
+func (im *Importer) Run(ctx context.Context, data *Data, opts Options, progress Progress) (Result, error) {
+	var res Result
+
+	feedIDs, err := im.createFeeds(data, &res)
+	if err != nil {
+		return res, err
+	}
+
+	var bookmarks, history []*model.Item
+	for _, e := range data.Entries {
+		feedID, ok := feedIDs[e.FeedLink]
+		if !ok {
+			continue
+		}
+		switch {
+		case e.Starred:
+			bookmarks = append(bookmarks, newItem(feedID, e))
+		case opts.History:
+			history = append(history, newItem(feedID, e))
+		}
+	}
+
+	if progress != nil {
+		progress.SetTotal(len(bookmarks) + len(history))
+	}
+	// bookmarks go first, so that they win if an export lists the same
+	// entry as starred and as history
+	if res.Bookmarks, err = im.insert(ctx, bookmarks, progress); err != nil {
+		return res, err
+	}
+	if res.History, err = im.insert(ctx, history, progress); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// insert stores the items that don't exist yet, applies the state of the
+// others to the stored items, and returns the number of items that were
+// stored or changed.
+func (im *Importer) insert(ctx context.Context, items []*model.Item, progress Progress) (int, error) {
+	total := 0
+	for batch := range slices.Chunk(items, insertBatch) {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		fresh, n, err := im.markStored(batch)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		n, err = im.itemRepo.Insert(fresh)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if progress != nil {
+			progress.Add(len(batch))
+		}
+	}
+	return total, nil
+}
+
+// markStored bookmarks or marks read the stored items that items match by
+// GUID or canonical link. It returns the items that aren't stored, and the
+// number of stored items that changed.
+func (im *Importer) markStored(items []*model.Item) ([]*model.Item, int, error) {
+	byFeed := make(map[uint][]*model.Item)
+	feedIDs := make([]uint, 0)
+	for _, item := range items {
+		if _, ok := byFeed[item.FeedID]; !ok {
+			feedIDs = append(feedIDs, item.FeedID)
+		}
+		byFeed[item.FeedID] = append(byFeed[item.FeedID], item)
+	}
+
+	fresh := make([]*model.Item, 0, len(items))
+	changed := 0
+	for _, feedID := range feedIDs {
+		guids := make([]string, 0, len(byFeed[feedID]))
+		links := make([]string, 0, len(byFeed[feedID]))
+		for _, item := range byFeed[feedID] {
+			guids = append(guids, ptr.From(item.GUID))
+			if link := ptr.From(item.CanonicalLink); link != "" {
+				links = append(links, link)
+			}
+		}
+		stored, err := im.itemRepo.ListByKeys(feedID, guids, links)
+		if err != nil {
+			return nil, changed, err
+		}
+		byGUID := make(map[string]*model.Item, len(stored))
+		byLink := make(map[string]*model.Item, len(stored))
+		for _, old := range stored {
+			byGUID[ptr.From(old.GUID)] = old
+			if link := ptr.From(old.CanonicalLink); link != "" {
+				byLink[link] = old
+			}
+		}
+
+		for _, item := range byFeed[feedID] {
+			old, ok := byGUID[ptr.From(item.GUID)]
+			if !ok {
+				old, ok = byLink[ptr.From(item.CanonicalLink)]
+			}
+			if !ok {
+				fresh = append(fresh, item)
+				continue
+			}
+			update := &model.Item{}
+			if ptr.From(item.Bookmark) && !ptr.From(old.Bookmark) {
+				update.Bookmark = ptr.To(true)
+				update.BookmarkedAt = item.BookmarkedAt
+				old.Bookmark = update.Bookmark
+			}
+			if !ptr.From(item.Unread) && ptr.From(old.Unread) {
+				update.Unread = ptr.To(false)
+				old.Unread = update.Unread
+			}
+			if update.Bookmark == nil && update.Unread == nil {
+				continue
+			}
+			if err := im.itemRepo.Update(old.ID, update); err != nil {
+				return nil, changed, err
+			}
+			changed++
+		}
+	}
+	return fresh, changed, nil
+}
+
+// createFeeds returns the IDs of the feeds of data by link, creating the
+// missing ones.
+func (im *Importer) createFeeds(data *Data, res *Result) (map[string]uint, error) {
+	existing, err := im.feedRepo.List(nil)
+	if err != nil {
+		return nil, err
+	}
+	feedIDs := make(map[string]uint, len(existing))
+	for _, f := range existing {
+		feedIDs[ptr.From(f.Link)] = f.ID
+	}
+
+	groups, err := im.groupRepo.All()
+	if err != nil {
+		return nil, err
+	}
+	groupIDs := make(map[string]uint, len(groups))
+	for _, g := range groups {
+		groupIDs[ptr.From(g.Name)] = g.ID
+	}
+
+	feeds := slices.Clone(data.Feeds)
+	for _, e := range data.Entries {
+		feeds = append(feeds, opml.Feed{Name: e.FeedName, Link: e.FeedLink})
+	}
+
+	created := make([]*model.Feed, 0)
+	for _, f := range feeds {
+		link := strings.TrimSpace(f.Link)
+		if link == "" {
+			continue
+		}
+		if _, ok := feedIDs[link]; ok {
+			continue
+		}
+		groupID := uint(1)
+		if f.Group != "" {
+			id, ok := groupIDs[f.Group]
+			if !ok {
+				group := &model.Group{Name: ptr.To(f.Group)}
+				if err := im.groupRepo.Create(group); err != nil {
+					return nil, fmt.Errorf("create group %q: %w", f.Group, err)
+				}
+				id = group.ID
+				groupIDs[f.Group] = id
+				res.Groups++
+			}
+			groupID = id
+		}
+		name := strings.TrimSpace(f.Name)
+		if name == "" {
+			name = link
+		}
+		feed := &model.Feed{Name: ptr.To(name), Link: ptr.To(link), GroupID: groupID}
+		created = append(created, feed)
+		// reserve the link, the ID is set after Create
+		feedIDs[link] = 0
+	}
+
+	if len(created) > 0 {
+		if err := im.feedRepo.Create(created); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range created {
+		feedIDs[ptr.From(f.Link)] = f.ID
+	}
+	res.Feeds = len(created)
+	return feedIDs, nil
+}
+
+// newItem returns the item of an entry. Entries without the feed's GUID are
+// keyed like items without a GUID, by their link, and take the GUID of the
+// feed when it's pulled.
+func newItem(feedID uint, e *Entry) *model.Item {
+	item := &model.Item{
+		FeedID:   feedID,
+		Title:    ptr.To(e.Title),
+		Link:     ptr.To(e.Link),
+		Content:  ptr.To(e.Content),
+		PubDate:  e.PubDate,
+		Unread:   ptr.To(e.Starred && !e.Read),
+		Bookmark: ptr.To(e.Starred),
+	}
+	if e.GUID != "" {
+		item.GUID = ptr.To(e.GUID)
+	}
+	if e.Starred {
+		item.BookmarkedAt = ptr.To(time.Now())
+	}
+	item.GUID = ptr.To(client.ItemKey(client.IdentityAuto, item))
+	item.CanonicalLink = ptr.To(client.NormalizeLink(e.Link))
+	item.TitleHash = simhash.Title(e.Title)
+	return item
+}
@@ -0,0 +1,344 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/importer"
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+const subscriptions = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0"><body>
+  <outline text="Tech"><outline text="Go blog" xmlUrl="https://go.dev/blog/feed.atom"/></outline>
+</body></opml>`
+
+const greaderStarred = `{"items": [{
+  "title": "Range functions",
+  "published": 1723500000,
+  "categories": ["user/-/state/com.google/reading-list", "user/-/state/com.google/read"],
+  "alternate": [{"href": "https://go.dev/blog/range-functions"}],
+  "content": {"content": "<p>Iterators</p>"},
+  "origin": {"streamId": "feed/https://go.dev/blog/feed.atom", "title": "Go blog"}
+}]}`
+
+var goEntry = &importer.Entry{
+	FeedLink: "https://go.dev/blog/feed.atom",
+	FeedName: "Go blog",
+	Title:    "Range functions",
+	Link:     "https://go.dev/blog/range-functions",
+	Content:  "<p>Iterators</p>",
+	PubDate:  ptr.To(time.Unix(1723500000, 0)),
+	Read:     true,
+	Starred:  true,
+}
+
+var goFeed = opml.Feed{Group: "Tech", Name: "Go blog", Link: "https://go.dev/blog/feed.atom"}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format string
+		files  []importer.File
+		want   *importer.Data
+	}{
+		{
+			name:   "miniflux",
+			format: importer.FormatMiniflux,
+			files: []importer.File{
+				{Name: "feeds.json", Content: []byte(`[{"feed_url": "https://go.dev/blog/feed.atom", "title": "Go blog", "category": {"title": "Tech"}}]`)},
+				{Name: "entries.json", Content: []byte(`{"total": 2, "entries": [
+				  {"status": "read", "title": "Range functions", "url": "https://go.dev/blog/range-functions",
+				   "published_at": "2024-08-12T22:00:00Z", "content": "<p>Iterators</p>", "starred": true,
+				   "feed": {"feed_url": "https://go.dev/blog/feed.atom", "title": "Go blog", "category": {"title": "Tech"}}},
+				  {"status": "unread", "title": " Old ", "url": "https://example.com/1", "starred": false,
+				   "feed": {"feed_url": "https://example.com/feed", "title": "Example", "category": {"title": "All"}}}
+				]}`)},
+			},
+			want: &importer.Data{
+				Feeds: []opml.Feed{goFeed, goFeed, {Group: "All", Name: "Example", Link: "https://example.com/feed"}},
+				Entries: []*importer.Entry{
+					{
+						FeedLink: goEntry.FeedLink, FeedName: "Go blog", Title: goEntry.Title, Link: goEntry.Link,
+						Content: goEntry.Content, PubDate: ptr.To(time.Date(2024, 8, 12, 22, 0, 0, 0, time.UTC)),
+						Read: true, Starred: true,
+					},
+					{FeedLink: "https://example.com/feed", FeedName: "Example", Title: "Old", Link: "https://example.com/1"},
+				},
+			},
+		},
+		{
+			name:   "greader",
+			format: importer.FormatGReader,
+			files: []importer.File{
+				{Name: "subscriptions.xml", Content: []byte(subscriptions)},
+				{Name: "export.json", Content: []byte(`{"items": [{
+				  "title": "Range functions", "published": 1723500000,
+				  "categories": ["user/1005921515/state/com.google/starred", "user/1005921515/state/com.google/read"],
+				  "canonical": [{"href": "https://go.dev/blog/range-functions"}],
+				  "summary": {"content": "<p>Iterators</p>"},
+				  "origin": {"streamId": "feed/https://go.dev/blog/feed.atom", "title": "Go blog"}
+				}, {"title": "not a feed", "origin": {"streamId": "user/-/state/com.google/broadcast"}}]}`)},
+			},
+			want: &importer.Data{Feeds: []opml.Feed{goFeed}, Entries: []*importer.Entry{goEntry}},
+		},
+		{
+			name:   "freshrss zip",
+			format: importer.FormatFreshRSS,
+			files: []importer.File{{Name: "freshrss.zip", Content: zipFiles(t, map[string]string{
+				"feeds_2024-08-13.opml.xml": subscriptions,
+				"starred_2024-08-13.json":   greaderStarred,
+				"export/notes.txt":          "ignored",
+				"feed_example_2024-08.json": `{"items": []}`,
+			})}},
+			want: &importer.Data{Feeds: []opml.Feed{goFeed}, Entries: []*importer.Entry{goEntry}},
+		},
+		{
+			name:   "ttrss",
+			format: importer.FormatTTRSS,
+			files: []importer.File{{Name: "export.xml", Content: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<articles schema-version="140">
+<article>
+<guid><![CDATA[{"ver":2,"uid":"1","hash":"SHA1:7d1b"}]]></guid>
+<title><![CDATA[Range functions]]></title>
+<content><![CDATA[<p>Iterators</p>]]></content>
+<marked>1</marked>
+<published>0</published>
+<link><![CDATA[https://go.dev/blog/range-functions]]></link>
+<feed_title><![CDATA[Go blog]]></feed_title>
+<feed_url><![CDATA[https://go.dev/blog/feed.atom]]></feed_url>
+<updated><![CDATA[2024-08-12 22:00:00+00]]></updated>
+</article>
+</articles>`)}},
+			want: &importer.Data{Entries: []*importer.Entry{{
+				FeedLink: goEntry.FeedLink, FeedName: "Go blog", Title: goEntry.Title, Link: goEntry.Link,
+				Content: goEntry.Content, PubDate: ptr.To(time.Date(2024, 8, 12, 22, 0, 0, 0, time.FixedZone("", 0))),
+				Read: true, Starred: true,
+			}}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := importer.Parse(tc.format, tc.files)
+			require.NoError(t, err)
+			// the order of files in a ZIP archive is arbitrary
+			assert.ElementsMatch(t, tc.want.Feeds, got.Feeds)
+			require.Len(t, got.Entries, len(tc.want.Entries))
+			for i, want := range tc.want.Entries {
+				// compare dates apart, their locations differ
+				w, g := *want, *got.Entries[i]
+				if w.PubDate == nil {
+					assert.Nil(t, g.PubDate)
+				} else {
+					assert.True(t, w.PubDate.Equal(ptr.From(g.PubDate)), "pub date of entry %d", i)
+				}
+				w.PubDate, g.PubDate = nil, nil
+				assert.Equal(t, w, g)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := importer.Parse("newsblur", nil)
+	assert.ErrorIs(t, err, importer.ErrUnknownFormat)
+
+	_, err = importer.Parse(importer.FormatMiniflux, []importer.File{{Name: "me.json", Content: []byte(`{"id": 1}`)}})
+	assert.ErrorContains(t, err, "me.json")
+
+	_, err = importer.Parse(importer.FormatTTRSS, []importer.File{{Name: "export.xml", Content: []byte("not xml")}})
+	assert.Error(t, err)
+	_, err = importer.Parse(importer.FormatTTRSS, []importer.File{{Name: "starred.json", Content: []byte(greaderStarred)}})
+	assert.ErrorContains(t, err, "not a Tiny Tiny RSS export")
+}
+
+type mockFeedRepo struct {
+	feeds []*model.Feed
+}
+
+func (m *mockFeedRepo) List(filter *repo.FeedListFilter) ([]*model.Feed, error) {
+	return m.feeds, nil
+}
+
+func (m *mockFeedRepo) Create(feeds []*model.Feed) error {
+	for _, f := range feeds {
+		f.ID = uint(len(m.feeds) + 1)
+		m.feeds = append(m.feeds, f)
+	}
+	return nil
+}
+
+type mockGroupRepo struct {
+	groups []*model.Group
+}
+
+func (m *mockGroupRepo) All() ([]*model.Group, error) {
+	return m.groups, nil
+}
+
+func (m *mockGroupRepo) Create(group *model.Group) error {
+	group.ID = uint(len(m.groups) + 1)
+	m.groups = append(m.groups, group)
+	return nil
+}
+
+type mockItemRepo struct {
+	items map[string]*model.Item
+}
+
+func (m *mockItemRepo) ListByKeys(feedID uint, guids, links []string) ([]*model.Item, error) {
+	res := make([]*model.Item, 0)
+	for _, item := range m.items {
+		if item.FeedID == feedID && (slices.Contains(guids, ptr.From(item.GUID)) || slices.Contains(links, ptr.From(item.CanonicalLink))) {
+			res = append(res, item)
+		}
+	}
+	return res, nil
+}
+
+func (m *mockItemRepo) Update(id uint, item *model.Item) error {
+	for _, v := range m.items {
+		if v.ID != id {
+			continue
+		}
+		if item.Unread != nil {
+			v.Unread = item.Unread
+		}
+		if item.Bookmark != nil {
+			v.Bookmark = item.Bookmark
+		}
+	}
+	return nil
+}
+
+func (m *mockItemRepo) Insert(items []*model.Item) (int, error) {
+	n := 0
+	for _, item := range items {
+		key := ptr.From(item.GUID)
+		if _, ok := m.items[key]; !ok {
+			item.ID = uint(len(m.items) + 1)
+			m.items[key] = item
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestImporterRun(t *testing.T) {
+	feedRepo := &mockFeedRepo{feeds: []*model.Feed{{ID: 1, Link: ptr.To("https://example.com/feed"), GroupID: 1}}}
+	groupRepo := &mockGroupRepo{groups: []*model.Group{{ID: 1, Name: ptr.To("Default")}}}
+	itemRepo := &mockItemRepo{items: map[string]*model.Item{}}
+	im := importer.NewImporter(feedRepo, groupRepo, itemRepo)
+
+	data := &importer.Data{
+		Feeds: []opml.Feed{goFeed, {Group: "Default", Name: "Example", Link: "https://example.com/feed"}},
+		Entries: []*importer.Entry{
+			goEntry,
+			{FeedLink: "https://example.com/feed", Title: "Old", Link: "https://www.example.com/1?utm_source=rss"},
+			{FeedLink: "https://news.example.com/rss", FeedName: "News", GUID: "n-1", Title: "Unread star", Starred: true},
+		},
+	}
+	res, err := im.Run(context.Background(), data, importer.Options{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, importer.Result{Groups: 1, Feeds: 2, Bookmarks: 2}, res)
+
+	require.Len(t, feedRepo.feeds, 3)
+	assert.Equal(t, "Go blog", ptr.From(feedRepo.feeds[1].Name))
+	assert.Equal(t, uint(2), feedRepo.feeds[1].GroupID, "the Tech group")
+	assert.Equal(t, "News", ptr.From(feedRepo.feeds[2].Name))
+	assert.Equal(t, uint(1), feedRepo.feeds[2].GroupID, "feeds only in entries go to the default group")
+
+	starred := itemRepo.items["https://go.dev/blog/range-functions"]
+	require.NotNil(t, starred, "entries without a GUID are keyed by their normalized link")
+	assert.Equal(t, uint(2), starred.FeedID)
+	assert.True(t, ptr.From(starred.Bookmark))
+	assert.False(t, ptr.From(starred.Unread))
+	assert.Equal(t, "<p>Iterators</p>", ptr.From(starred.Content))
+	assert.True(t, ptr.From(itemRepo.items["n-1"].Unread))
+
+	// importing again with the history only adds the history
+	res, err = im.Run(context.Background(), data, importer.Options{History: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, importer.Result{History: 1}, res)
+	old := itemRepo.items["https://example.com/1"]
+	require.NotNil(t, old)
+	assert.Equal(t, uint(1), old.FeedID)
+	assert.False(t, ptr.From(old.Unread))
+	assert.False(t, ptr.From(old.Bookmark))
+}
+
+type progress struct {
+	total, done int
+}
+
+func (p *progress) SetTotal(n int) { p.total = n }
+func (p *progress) Add(n int)      { p.done += n }
+
+func TestImporterRunStoredItems(t *testing.T) {
+	repo.Logger = logger.Default.LogMode(logger.Silent)
+	repo.Init(repo.DriverSQLite, filepath.Join(t.TempDir(), "fusion.db"))
+	t.Cleanup(func() { repo.Close() })
+
+	feedRepo, itemRepo := repo.NewFeed(repo.DB), repo.NewItem(repo.DB)
+	feed := &model.Feed{Name: ptr.To("Go blog"), Link: ptr.To(goEntry.FeedLink), GroupID: 1}
+	require.NoError(t, feedRepo.Create([]*model.Feed{feed}))
+	// the feed was pulled before, its items have the feed's own GUIDs
+	_, err := itemRepo.Save([]*model.Item{
+		{FeedID: feed.ID, GUID: ptr.To("tag:go.dev,2024:range-functions"), Link: ptr.To(goEntry.Link), Title: ptr.To(goEntry.Title),
+			CanonicalLink: ptr.To(goEntry.Link), Unread: ptr.To(true)},
+		{FeedID: feed.ID, GUID: ptr.To("tag:go.dev,2024:aliases"), Link: ptr.To("https://go.dev/blog/alias-names"), Title: ptr.To("Aliases"),
+			CanonicalLink: ptr.To("https://go.dev/blog/alias-names"), Unread: ptr.To(true)},
+	}, false)
+	require.NoError(t, err)
+
+	im := importer.NewImporter(feedRepo, repo.NewGroup(repo.DB), itemRepo)
+	data := &importer.Data{Entries: []*importer.Entry{
+		goEntry,
+		{FeedLink: goEntry.FeedLink, Title: "Aliases", Link: "https://go.dev/blog/alias-names?utm_source=rss", Read: true},
+		{FeedLink: goEntry.FeedLink, Title: "Not pulled", Link: "https://go.dev/blog/old", Read: true},
+	}}
+	p := &progress{}
+	res, err := im.Run(context.Background(), data, importer.Options{History: true}, p)
+	require.NoError(t, err)
+	assert.Equal(t, importer.Result{Bookmarks: 1, History: 2}, res)
+	assert.Equal(t, progress{total: 3, done: 3}, *p)
+
+	items, err := itemRepo.ListByFeed(feed.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 3, "stored items aren't added again")
+	assert.Equal(t, "tag:go.dev,2024:range-functions", ptr.From(items[0].GUID))
+	assert.True(t, ptr.From(items[0].Bookmark))
+	assert.False(t, ptr.From(items[0].Unread))
+	assert.False(t, ptr.From(items[1].Unread))
+	assert.False(t, ptr.From(items[1].Bookmark))
+	assert.Equal(t, "https://go.dev/blog/old", ptr.From(items[2].GUID))
+
+	res, err = im.Run(context.Background(), data, importer.Options{History: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, importer.Result{}, res, "importing again should change nothing")
+}
@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+// minifluxFeed is a feed of the Miniflux API, as returned by /v1/feeds and
+// in entries.
+type minifluxFeed struct {
+	FeedURL  string `json:"feed_url"`
+	Title    string `json:"title"`
+	Category struct {
+		Title string `json:"title"`
+	} `json:"category"`
+}
+
+func (f minifluxFeed) feed() opml.Feed {
+	return opml.Feed{Group: f.Category.Title, Name: f.Title, Link: f.FeedURL}
+}
+
+// minifluxEntries is the response of /v1/entries.
+type minifluxEntries struct {
+	Entries []struct {
+		Status      string       `json:"status"`
+		Title       string       `json:"title"`
+		URL         string       `json:"url"`
+		PublishedAt *time.Time   `json:"published_at"`
+		Content     string       `json:"content"`
+		Starred     bool         `json:"starred"`
+		Feed        minifluxFeed `json:"feed"`
+	} `json:"entries"`
+}
+
+// parseMiniflux reads the responses of the Miniflux API saved to files:
+// /v1/feeds for the subscriptions and /v1/entries, e.g. with starred=true,
+// for the entries.
+func parseMiniflux(f File, data *Data) error {
+	content := bytes.TrimSpace(f.Content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		var feeds []minifluxFeed
+		if err := json.Unmarshal(content, &feeds); err != nil {
+			return err
+		}
+		for _, v := range feeds {
+			data.Feeds = append(data.Feeds, v.feed())
+		}
+		return nil
+	}
+
+	var resp minifluxEntries
+	if err := json.Unmarshal(content, &resp); err != nil {
+		return err
+	}
+	if resp.Entries == nil {
+		return errors.New("neither a list of feeds nor of entries")
+	}
+	for _, v := range resp.Entries {
+		data.Feeds = append(data.Feeds, v.Feed.feed())
+		data.addEntry(&Entry{
+			FeedLink: v.Feed.FeedURL,
+			FeedName: v.Feed.Title,
+			Title:    v.Title,
+			Link:     v.URL,
+			Content:  v.Content,
+			PubDate:  v.PublishedAt,
+			Read:     v.Status != "unread",
+			Starred:  v.Starred,
+		})
+	}
+	return nil
+}
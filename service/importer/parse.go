@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+// Export formats.
+const (
+	FormatMiniflux = "miniflux"
+	FormatFreshRSS = "freshrss"
+	FormatGReader  = "greader"
+	FormatTTRSS    = "ttrss"
+)
+
+// Formats are the supported export formats.
+var Formats = []string{FormatMiniflux, FormatFreshRSS, FormatGReader, FormatTTRSS}
+
+// ErrUnknownFormat is returned for formats that aren't in Formats.
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// File is an exported file.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// parsers read the files of each format that aren't OPML.
+var parsers = map[string]func(f File, data *Data) error{
+	FormatMiniflux: parseMiniflux,
+	FormatFreshRSS: parseFreshRSS,
+	FormatGReader:  parseGReader,
+	FormatTTRSS:    parseTTRSS,
+}
+
+// Parse reads the files exported by a reader in format. OPML files with the
+// subscriptions are accepted in every format.
+func Parse(format string, files []File) (*Data, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	data := &Data{}
+	for _, f := range files {
+		var err error
+		if isOPML(f.Content) {
+			err = parseOPML(f, data)
+		} else {
+			err = parse(f, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return data, nil
+}
+
+// isOPML reports whether content looks like an OPML document.
+func isOPML(content []byte) bool {
+	head := content[:min(len(content), 1024)]
+	return bytes.Contains(bytes.ToLower(head), []byte("<opml"))
+}
+
+func parseOPML(f File, data *Data) error {
+	feeds, err := opml.Parse(bytes.NewReader(f.Content))
+	if err != nil {
+		return fmt.Errorf("invalid OPML: %w", err)
+	}
+	data.Feeds = append(data.Feeds, feeds...)
+	return nil
+}
+
+// addEntry adds an entry after trimming its fields, skipping entries without
+// a feed.
+func (d *Data) addEntry(e *Entry) {
+	e.FeedLink = strings.TrimSpace(e.FeedLink)
+	e.FeedName = strings.TrimSpace(e.FeedName)
+	e.GUID = strings.TrimSpace(e.GUID)
+	e.Title = strings.TrimSpace(e.Title)
+	e.Link = strings.TrimSpace(e.Link)
+	if e.FeedLink == "" {
+		return
+	}
+	d.Entries = append(d.Entries, e)
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ttrssExport is the XML written by the import/export plugin of Tiny Tiny
+// RSS. It has no read state, and GUIDs are its own.
+type ttrssExport struct {
+	XMLName  xml.Name
+	Articles []struct {
+		Title     string `xml:"title"`
+		Content   string `xml:"content"`
+		Marked    string `xml:"marked"`
+		Link      string `xml:"link"`
+		FeedTitle string `xml:"feed_title"`
+		FeedURL   string `xml:"feed_url"`
+		Updated   string `xml:"updated"`
+	} `xml:"article"`
+}
+
+// ttrssTimeLayouts are the formats of dates in exports, which depend on the
+// database.
+var ttrssTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999-07:00",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+func parseTTRSS(f File, data *Data) error {
+	var export ttrssExport
+	if err := xml.NewDecoder(bytes.NewReader(f.Content)).Decode(&export); err != nil {
+		return err
+	}
+	if export.XMLName.Local != "articles" {
+		return errors.New("not a Tiny Tiny RSS export")
+	}
+	for _, v := range export.Articles {
+		data.addEntry(&Entry{
+			FeedLink: v.FeedURL,
+			FeedName: v.FeedTitle,
+			Title:    v.Title,
+			Link:     v.Link,
+			Content:  v.Content,
+			PubDate:  parseTTRSSTime(v.Updated),
+			Read:     true,
+			Starred:  strings.TrimSpace(v.Marked) == "1",
+		})
+	}
+	return nil
+}
+
+func parseTTRSSTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range ttrssTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
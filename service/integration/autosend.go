@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+)
+
+type Repo interface {
+	SendingBookmarks() ([]*model.Integration, error)
+}
+
+type ItemRepo interface {
+	Get(id uint) (*model.Item, error)
+}
+
+// sendTimeout limits sending one item to one integration.
+const sendTimeout = time.Minute
+
+// AutoSender sends new bookmarks to the integrations that have
+// SendBookmarks set.
+type AutoSender struct {
+	repo  Repo
+	items ItemRepo
+	box   *secret.Box
+}
+
+func NewAutoSender(repo Repo, items ItemRepo, box *secret.Box) *AutoSender {
+	return &AutoSender{
+		repo:  repo,
+		items: items,
+		box:   box,
+	}
+}
+
+// Run sends items as they are bookmarked until ctx is done.
+func (a *AutoSender) Run(ctx context.Context) {
+	ch, unsubscribe := events.Subscribe(256, events.ItemBookmarkChanged)
+	defer func() { unsubscribe() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				// dropped for being too slow, bookmarks meanwhile are
+				// missed
+				slog.Warn("some bookmarks weren't sent to integrations")
+				ch, unsubscribe = events.Subscribe(256, events.ItemBookmarkChanged)
+				continue
+			}
+			if change, _ := e.Data.(events.BookmarkChange); change.Bookmark {
+				a.send(ctx, change.ID)
+			}
+		}
+	}
+}
+
+func (a *AutoSender) send(ctx context.Context, itemID uint) {
+	integrations, err := a.repo.SendingBookmarks()
+	if err != nil || len(integrations) == 0 {
+		if err != nil {
+			slog.Error("failed to list integrations", "error", err)
+		}
+		return
+	}
+	item, err := a.items.Get(itemID)
+	if err != nil {
+		slog.Warn("failed to get bookmarked item", "item_id", itemID, "error", err)
+		return
+	}
+
+	entry := NewEntry(item)
+	for _, in := range integrations {
+		sender, err := Open(a.box, in)
+		if err == nil {
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			err = sender.Send(sendCtx, entry)
+			cancel()
+		}
+		if err != nil {
+			slog.Error("failed to send bookmark", "integration", ptr.From(in.Name), "item_id", itemID, "error", err)
+			continue
+		}
+		slog.Info("sent bookmark", "integration", ptr.From(in.Name), "item_id", itemID)
+	}
+}
@@ -0,0 +1,262 @@
+// Package integration sends items to other services, such as read-it-later
+// apps, bookmark managers and chats.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
+)
+
+// Entry is an item as sent to a service.
+type Entry struct {
+	ID       uint
+	Title    string
+	Link     string
+	Content  string
+	PubDate  *time.Time
+	FeedName string
+	FeedLink string
+}
+
+// NewEntry returns the entry of an item with its feed.
+func NewEntry(item *model.Item) *Entry {
+	return &Entry{
+		ID:       item.ID,
+		Title:    ptr.From(item.Title),
+		Link:     ptr.From(item.Link),
+		Content:  ptr.From(item.Content),
+		PubDate:  item.PubDate,
+		FeedName: ptr.From(item.Feed.Name),
+		FeedLink: ptr.From(item.Feed.Link),
+	}
+}
+
+// Sender sends entries to a service.
+type Sender interface {
+	Send(ctx context.Context, entry *Entry) error
+}
+
+// Settings are the settings of an integration by field name.
+type Settings map[string]string
+
+// Field is a setting of a kind of integration.
+type Field struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Secret fields are never returned by the API.
+	Secret   bool   `json:"secret"`
+	Required bool   `json:"required"`
+	Default  string `json:"default,omitempty"`
+}
+
+// Kind is a type of service.
+type Kind struct {
+	Name   string  `json:"name"`
+	Label  string  `json:"label"`
+	Fields []Field `json:"fields"`
+	new    func(s Settings) Sender
+}
+
+// ErrUnknownKind is returned for kinds that aren't in Kinds.
+var ErrUnknownKind = errors.New("unknown integration kind")
+
+// Kinds are the supported services.
+var Kinds = []*Kind{
+	{Name: "wallabag", Label: "Wallabag", Fields: []Field{
+		{Name: "url", Label: "Server URL", Required: true},
+		{Name: "client_id", Label: "Client ID", Required: true},
+		{Name: "client_secret", Label: "Client secret", Required: true, Secret: true},
+		{Name: "username", Label: "Username", Required: true},
+		{Name: "password", Label: "Password", Required: true, Secret: true},
+		{Name: "tags", Label: "Tags, comma separated"},
+	}, new: newWallabag},
+	{Name: "linkding", Label: "Linkding", Fields: []Field{
+		{Name: "url", Label: "Server URL", Required: true},
+		{Name: "token", Label: "API token", Required: true, Secret: true},
+		{Name: "tags", Label: "Tags, comma separated"},
+	}, new: newLinkding},
+	{Name: "readeck", Label: "Readeck", Fields: []Field{
+		{Name: "url", Label: "Server URL", Required: true},
+		{Name: "token", Label: "API token", Required: true, Secret: true},
+		{Name: "labels", Label: "Labels, comma separated"},
+	}, new: newReadeck},
+	{Name: "raindrop", Label: "Raindrop.io", Fields: []Field{
+		{Name: "token", Label: "Access token", Required: true, Secret: true},
+		{Name: "collection_id", Label: "Collection ID", Default: "-1"},
+		{Name: "tags", Label: "Tags, comma separated"},
+		{Name: "api_url", Label: "API URL", Default: "https://api.raindrop.io"},
+	}, new: newRaindrop},
+	{Name: "telegram", Label: "Telegram", Fields: []Field{
+		{Name: "bot_token", Label: "Bot token", Required: true, Secret: true},
+		{Name: "chat_id", Label: "Chat ID", Required: true},
+		{Name: "api_url", Label: "Bot API URL", Default: "https://api.telegram.org"},
+	}, new: newTelegram},
+	{Name: "webhook", Label: "HTTP endpoint", Fields: []Field{
+		{Name: "url", Label: "URL", Required: true},
+		{Name: "secret", Label: "Signing secret", Secret: true},
+	}, new: newWebhook},
+}
+
+// FindKind returns the kind with the name.
+func FindKind(name string) (*Kind, error) {
+	for _, k := range Kinds {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKind
+}
+
+// New returns the sender of a kind. Missing settings are set to their
+// defaults.
+func New(kind string, s Settings) (Sender, error) {
+	k, err := FindKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	s = k.withDefaults(s)
+	for _, f := range k.Fields {
+		if f.Required && strings.TrimSpace(s[f.Name]) == "" {
+			return nil, fmt.Errorf("%s is required", f.Label)
+		}
+	}
+	return k.new(s), nil
+}
+
+func (k *Kind) withDefaults(s Settings) Settings {
+	res := make(Settings, len(k.Fields))
+	for _, f := range k.Fields {
+		v := strings.TrimSpace(s[f.Name])
+		if v == "" {
+			v = f.Default
+		}
+		res[f.Name] = v
+	}
+	return res
+}
+
+// Public returns the settings without secret fields.
+func (k *Kind) Public(s Settings) Settings {
+	res := make(Settings, len(k.Fields))
+	for _, f := range k.Fields {
+		if !f.Secret && s[f.Name] != "" {
+			res[f.Name] = s[f.Name]
+		}
+	}
+	return res
+}
+
+// Seal encrypts settings for model.Integration.Settings.
+func Seal(box *secret.Box, s Settings) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return box.Seal(data)
+}
+
+// Unseal decrypts the settings of an integration.
+func Unseal(box *secret.Box, in *model.Integration) (Settings, error) {
+	data, err := box.Open(in.Settings)
+	if err != nil {
+		return nil, err
+	}
+	var s Settings
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// Open returns the sender of a stored integration.
+func Open(box *secret.Box, in *model.Integration) (Sender, error) {
+	s, err := Unseal(box, in)
+	if err != nil {
+		return nil, err
+	}
+	return New(in.Kind, s)
+}
+
+// client is shared by all integrations.
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// errorBodyLength is the number of bytes of an error response kept in
+// errors.
+const errorBodyLength = 200
+
+// do sends a request and decodes a JSON response into res, if it isn't nil.
+// Responses other than 2xx are errors.
+func do(req *http.Request, res any) error {
+	req.Header.Set("User-Agent", httpx.UserAgentString)
+	resp, err := client.Do(req)
+	// the path may contain credentials, e.g. Telegram bot tokens, so only
+	// the host is included in errors
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", req.URL.Host, urlErr.Err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLength))
+		return fmt.Errorf("%s: %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// newJSONRequest returns a POST request with body encoded as JSON.
+func newJSONRequest(ctx context.Context, endpoint string, body any) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// endpoint joins a base URL from the settings and a path.
+func endpoint(base, path string) string {
+	return strings.TrimSuffix(base, "/") + path
+}
+
+// splitList splits a comma separated setting, skipping empty values.
+func splitList(s string) []string {
+	res := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// formValues returns a form body for the values.
+func formValues(values map[string]string) io.Reader {
+	form := url.Values{}
+	for k, v := range values {
+		form.Set(k, v)
+	}
+	return strings.NewReader(form.Encode())
+}
@@ -0,0 +1,269 @@
+package integration_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/secret"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+	"github.com/Sudo-Ivan/fusionx/service/integration"
+)
+
+// request is a request received by a stand-in.
+type request struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// standIn starts a server that records requests and answers with the
+// responses by path, or 200 with an empty JSON object.
+func standIn(t *testing.T, responses map[string]string) (string, func() []request) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Clone(), body})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if resp, ok := responses[r.URL.Path]; ok {
+			_, _ = io.WriteString(w, resp)
+			return
+		}
+		_, _ = io.WriteString(w, "{}")
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request(nil), requests...)
+	}
+}
+
+var entry = &integration.Entry{
+	ID:       7,
+	Title:    "Go 1.23 is released",
+	Link:     "https://go.dev/blog/go1.23",
+	Content:  "<p>Iterators</p>",
+	FeedName: "Go blog",
+	FeedLink: "https://go.dev/blog/feed.atom",
+}
+
+func decode(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(body, &v), string(body))
+	return v
+}
+
+func TestSend(t *testing.T) {
+	for _, tc := range []struct {
+		kind     string
+		settings func(base string) integration.Settings
+		check    func(t *testing.T, requests []request)
+	}{
+		{
+			kind: "wallabag",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{
+					"url": base + "/", "client_id": "id", "client_secret": "cs",
+					"username": "me", "password": "pw", "tags": "fusion, go",
+				}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 2)
+				assert.Equal(t, "/oauth/v2/token", requests[0].path)
+				form, err := url.ParseQuery(string(requests[0].body))
+				require.NoError(t, err)
+				assert.Equal(t, "password", form.Get("grant_type"))
+				assert.Equal(t, "pw", form.Get("password"))
+				assert.Equal(t, "/api/entries.json", requests[1].path)
+				assert.Equal(t, "Bearer wb-token", requests[1].header.Get("Authorization"))
+				body := decode(t, requests[1].body)
+				assert.Equal(t, entry.Link, body["url"])
+				assert.Equal(t, "fusion,go", body["tags"])
+			},
+		},
+		{
+			kind: "linkding",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{"url": base, "token": "ld", "tags": "fusion"}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 1)
+				assert.Equal(t, "/api/bookmarks/", requests[0].path)
+				assert.Equal(t, "Token ld", requests[0].header.Get("Authorization"))
+				body := decode(t, requests[0].body)
+				assert.Equal(t, entry.Link, body["url"])
+				assert.Equal(t, []any{"fusion"}, body["tag_names"])
+			},
+		},
+		{
+			kind: "readeck",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{"url": base, "token": "rd"}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 1)
+				assert.Equal(t, "/api/bookmarks", requests[0].path)
+				assert.Equal(t, "Bearer rd", requests[0].header.Get("Authorization"))
+				body := decode(t, requests[0].body)
+				assert.Equal(t, entry.Title, body["title"])
+				assert.Equal(t, []any{}, body["labels"])
+			},
+		},
+		{
+			kind: "raindrop",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{"api_url": base, "token": "rn"}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 1)
+				assert.Equal(t, "/rest/v1/raindrop", requests[0].path)
+				assert.Equal(t, "Bearer rn", requests[0].header.Get("Authorization"))
+				body := decode(t, requests[0].body)
+				assert.Equal(t, entry.Link, body["link"])
+				assert.Equal(t, map[string]any{"$id": float64(-1)}, body["collection"], "defaults to Unsorted")
+			},
+		},
+		{
+			kind: "telegram",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{"api_url": base, "bot_token": "123:abc", "chat_id": "-100"}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 1)
+				assert.Equal(t, "/bot123:abc/sendMessage", requests[0].path)
+				body := decode(t, requests[0].body)
+				assert.Equal(t, "-100", body["chat_id"])
+				assert.Equal(t, entry.Title+"\n"+entry.Link+"\nvia Go blog", body["text"])
+			},
+		},
+		{
+			kind: "webhook",
+			settings: func(base string) integration.Settings {
+				return integration.Settings{"url": base + "/hook", "secret": "s3cret"}
+			},
+			check: func(t *testing.T, requests []request) {
+				require.Len(t, requests, 1)
+				assert.Equal(t, "/hook", requests[0].path)
+				mac := hmac.New(sha256.New, []byte("s3cret"))
+				mac.Write(requests[0].body)
+				assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), requests[0].header.Get(integration.SignatureHeader))
+				body := decode(t, requests[0].body)
+				assert.Equal(t, float64(7), body["id"])
+				assert.Equal(t, entry.Content, body["content"])
+				assert.Equal(t, map[string]any{"name": "Go blog", "link": entry.FeedLink}, body["feed"])
+			},
+		},
+	} {
+		t.Run(tc.kind, func(t *testing.T) {
+			base, requests := standIn(t, map[string]string{
+				"/oauth/v2/token": `{"access_token":"wb-token"}`,
+			})
+			sender, err := integration.New(tc.kind, tc.settings(base))
+			require.NoError(t, err)
+			require.NoError(t, sender.Send(context.Background(), entry))
+			for _, r := range requests() {
+				assert.Equal(t, http.MethodPost, r.method)
+			}
+			tc.check(t, requests())
+		})
+	}
+}
+
+func TestSendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"ok":false,"description":"Bad Request: chat not found"}`)
+	}))
+	defer srv.Close()
+
+	sender, err := integration.New("telegram", integration.Settings{
+		"api_url": srv.URL, "bot_token": "123:secret", "chat_id": "1",
+	})
+	require.NoError(t, err)
+	err = sender.Send(context.Background(), entry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+	assert.NotContains(t, err.Error(), "123:secret")
+
+	srv.Close()
+	err = sender.Send(context.Background(), entry)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "123:secret")
+}
+
+func TestNew(t *testing.T) {
+	_, err := integration.New("linkding", integration.Settings{"url": "https://links.example.com"})
+	assert.ErrorContains(t, err, "API token is required")
+	_, err = integration.New("pocket", nil)
+	assert.ErrorIs(t, err, integration.ErrUnknownKind)
+
+	kind, err := integration.FindKind("wallabag")
+	require.NoError(t, err)
+	assert.Equal(t, integration.Settings{"url": "https://wb.example.com", "username": "me"},
+		kind.Public(integration.Settings{"url": "https://wb.example.com", "username": "me", "password": "pw"}))
+}
+
+type mockRepo struct {
+	integrations []*model.Integration
+}
+
+func (m *mockRepo) SendingBookmarks() ([]*model.Integration, error) {
+	return m.integrations, nil
+}
+
+type mockItemRepo struct{}
+
+func (mockItemRepo) Get(id uint) (*model.Item, error) {
+	return &model.Item{
+		ID:    id,
+		Title: ptr.To("Bookmarked"),
+		Link:  ptr.To("https://example.com/1"),
+		Feed:  model.Feed{Name: ptr.To("Example")},
+	}, nil
+}
+
+func TestAutoSender(t *testing.T) {
+	base, requests := standIn(t, nil)
+	box, err := secret.NewBox("key")
+	require.NoError(t, err)
+	settings, err := integration.Seal(box, integration.Settings{"url": base})
+	require.NoError(t, err)
+	repo := &mockRepo{integrations: []*model.Integration{
+		{ID: 1, Name: ptr.To("hook"), Kind: "webhook", Settings: settings},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go integration.NewAutoSender(repo, mockItemRepo{}, box).Run(ctx)
+	// wait for the subscription
+	time.Sleep(50 * time.Millisecond)
+
+	events.Publish(events.ItemBookmarkChanged, events.BookmarkChange{ID: 3, Bookmark: false})
+	events.Publish(events.ItemBookmarkChanged, events.BookmarkChange{ID: 4, Bookmark: true})
+	require.Eventually(t, func() bool { return len(requests()) > 0 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	got := requests()
+	require.Len(t, got, 1, "unbookmarking shouldn't send")
+	assert.Equal(t, float64(4), decode(t, got[0].body)["id"])
+}
@@ -0,0 +1,24 @@
+package integration
+
+import "context"
+
+type linkding struct {
+	url, token, tags string
+}
+
+func newLinkding(s Settings) Sender {
+	return &linkding{url: s["url"], token: s["token"], tags: s["tags"]}
+}
+
+func (l *linkding) Send(ctx context.Context, entry *Entry) error {
+	req, err := newJSONRequest(ctx, endpoint(l.url, "/api/bookmarks/"), map[string]any{
+		"url":       entry.Link,
+		"title":     entry.Title,
+		"tag_names": splitList(l.tags),
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+l.token)
+	return do(req, nil)
+}
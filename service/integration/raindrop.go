@@ -0,0 +1,35 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+type raindrop struct {
+	apiURL, token, collectionID, tags string
+}
+
+func newRaindrop(s Settings) Sender {
+	return &raindrop{apiURL: s["api_url"], token: s["token"], collectionID: s["collection_id"], tags: s["tags"]}
+}
+
+func (r *raindrop) Send(ctx context.Context, entry *Entry) error {
+	collection, err := strconv.Atoi(r.collectionID)
+	if err != nil {
+		return fmt.Errorf("invalid collection ID %q", r.collectionID)
+	}
+	req, err := newJSONRequest(ctx, endpoint(r.apiURL, "/rest/v1/raindrop"), map[string]any{
+		"link":       entry.Link,
+		"title":      entry.Title,
+		"tags":       splitList(r.tags),
+		"collection": map[string]int{"$id": collection},
+		// fetch the excerpt and cover in the background
+		"pleaseParse": map[string]any{},
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	return do(req, nil)
+}
@@ -0,0 +1,24 @@
+package integration
+
+import "context"
+
+type readeck struct {
+	url, token, labels string
+}
+
+func newReadeck(s Settings) Sender {
+	return &readeck{url: s["url"], token: s["token"], labels: s["labels"]}
+}
+
+func (r *readeck) Send(ctx context.Context, entry *Entry) error {
+	req, err := newJSONRequest(ctx, endpoint(r.url, "/api/bookmarks"), map[string]any{
+		"url":    entry.Link,
+		"title":  entry.Title,
+		"labels": splitList(r.labels),
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	return do(req, nil)
+}
@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"context"
+	"strings"
+)
+
+type telegram struct {
+	apiURL, botToken, chatID string
+}
+
+func newTelegram(s Settings) Sender {
+	return &telegram{apiURL: s["api_url"], botToken: s["bot_token"], chatID: s["chat_id"]}
+}
+
+func (t *telegram) Send(ctx context.Context, entry *Entry) error {
+	lines := make([]string, 0, 3)
+	for _, v := range []string{entry.Title, entry.Link} {
+		if v != "" {
+			lines = append(lines, v)
+		}
+	}
+	if entry.FeedName != "" {
+		lines = append(lines, "via "+entry.FeedName)
+	}
+	req, err := newJSONRequest(ctx, endpoint(t.apiURL, "/bot"+t.botToken+"/sendMessage"), map[string]string{
+		"chat_id": t.chatID,
+		"text":    strings.Join(lines, "\n"),
+	})
+	if err != nil {
+		return err
+	}
+	return do(req, nil)
+}
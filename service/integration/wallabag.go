@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// wallabag authenticates with the OAuth password grant for every entry, the
+// tokens expire after an hour anyway.
+type wallabag struct {
+	url, clientID, clientSecret, username, password, tags string
+}
+
+func newWallabag(s Settings) Sender {
+	return &wallabag{
+		url:          s["url"],
+		clientID:     s["client_id"],
+		clientSecret: s["client_secret"],
+		username:     s["username"],
+		password:     s["password"],
+		tags:         s["tags"],
+	}
+}
+
+func (w *wallabag) Send(ctx context.Context, entry *Entry) error {
+	token, err := w.token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := newJSONRequest(ctx, endpoint(w.url, "/api/entries.json"), map[string]string{
+		"url":   entry.Link,
+		"title": entry.Title,
+		"tags":  strings.Join(splitList(w.tags), ","),
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return do(req, nil)
+}
+
+func (w *wallabag) token(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(w.url, "/oauth/v2/token"), formValues(map[string]string{
+		"grant_type":    "password",
+		"client_id":     w.clientID,
+		"client_secret": w.clientSecret,
+		"username":      w.username,
+		"password":      w.password,
+	}))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := do(req, &resp); err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("wallabag returned no access token")
+	}
+	return resp.AccessToken, nil
+}
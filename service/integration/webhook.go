@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader is the header of webhook requests with the HMAC-SHA256 of
+// the body, keyed with the signing secret, as "sha256=<hex>".
+const SignatureHeader = "X-Fusion-Signature"
+
+type webhook struct {
+	url, secret string
+}
+
+func newWebhook(s Settings) Sender {
+	return &webhook{url: s["url"], secret: s["secret"]}
+}
+
+type webhookFeed struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+type webhookPayload struct {
+	ID      uint        `json:"id"`
+	Title   string      `json:"title"`
+	Link    string      `json:"link"`
+	Content string      `json:"content"`
+	PubDate *time.Time  `json:"pub_date"`
+	Feed    webhookFeed `json:"feed"`
+}
+
+func (w *webhook) Send(ctx context.Context, entry *Entry) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:      entry.ID,
+		Title:   entry.Title,
+		Link:    entry.Link,
+		Content: entry.Content,
+		PubDate: entry.PubDate,
+		Feed:    webhookFeed{Name: entry.FeedName, Link: entry.FeedLink},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return do(req, nil)
+}
@@ -0,0 +1,227 @@
+// Package jobs runs long operations in the background, such as refreshing
+// all feeds. Jobs are recorded in the database so that their status and
+// progress can be polled, they can be canceled, and only a limited number
+// runs at once.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+)
+
+type Repo interface {
+	Get(id uint) (*model.Job, error)
+	Create(job *model.Job) error
+	Update(id uint, job *model.Job) error
+	Interrupt() error
+	Prune(keep int) error
+}
+
+// Func is the work of a job. It should report its progress to p and return
+// soon after ctx is done.
+type Func func(ctx context.Context, p *Progress) error
+
+// ErrFinished is returned when canceling a job that already ended.
+var ErrFinished = errors.New("job already finished")
+
+const (
+	// keepJobs is the number of job records kept.
+	keepJobs = 200
+	// saveInterval limits how often progress is saved.
+	saveInterval = time.Second
+)
+
+type Manager struct {
+	repo  Repo
+	ctx   context.Context
+	slots chan struct{}
+
+	mu      sync.Mutex
+	cancels map[uint]context.CancelFunc
+	// running counts the jobs whose status isn't saved as ended yet.
+	running sync.WaitGroup
+}
+
+// NewManager returns a Manager that runs up to concurrency jobs at once.
+// All jobs are canceled when ctx is done.
+func NewManager(ctx context.Context, repo Repo, concurrency int) *Manager {
+	return &Manager{
+		repo:    repo,
+		ctx:     ctx,
+		slots:   make(chan struct{}, max(concurrency, 1)),
+		cancels: make(map[uint]context.CancelFunc),
+	}
+}
+
+// Interrupt fails the jobs left queued or running by a previous process.
+// It must only be called by the server on startup, as the admin CLI may
+// run at the same time.
+func (m *Manager) Interrupt() error {
+	return m.repo.Interrupt()
+}
+
+// Submit records a job of the given kind and runs fn in the background once
+// a slot is free.
+func (m *Manager) Submit(kind string, fn Func) (*model.Job, error) {
+	job := &model.Job{Kind: kind, Status: model.JobQueued}
+	if err := m.repo.Create(job); err != nil {
+		return nil, err
+	}
+	if err := m.repo.Prune(keepJobs); err != nil {
+		slog.Warn("failed to prune jobs", "error", err)
+	}
+	publish(job)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.mu.Lock()
+	m.cancels[job.ID] = cancel
+	m.mu.Unlock()
+
+	m.running.Add(1)
+	go m.run(ctx, *job, fn)
+	return job, nil
+}
+
+// Wait waits until every job has ended and saved its status, which happens
+// soon after the ctx of the Manager is done. It returns ctx.Err() if ctx is
+// done first.
+func (m *Manager) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel cancels a queued or running job. The job ends as soon as its
+// function returns.
+func (m *Manager) Cancel(id uint) error {
+	m.mu.Lock()
+	cancel, ok := m.cancels[id]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+
+	// the job ended, or it doesn't exist
+	if _, err := m.repo.Get(id); err != nil {
+		return err
+	}
+	return ErrFinished
+}
+
+func (m *Manager) run(ctx context.Context, job model.Job, fn Func) {
+	logger := slog.With("job_id", job.ID, "job_kind", job.Kind)
+	defer m.running.Done()
+	defer func() {
+		m.mu.Lock()
+		m.cancels[job.ID]()
+		delete(m.cancels, job.ID)
+		m.mu.Unlock()
+	}()
+
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		m.finish(ctx, &job, nil)
+		return
+	}
+
+	job.Status = model.JobRunning
+	job.StartedAt = ptr.To(time.Now())
+	m.save(&job)
+	logger.Info("job started")
+
+	p := &Progress{m: m, job: &job}
+	err := fn(ctx, p)
+	m.finish(ctx, &job, err)
+	if err != nil {
+		logger.Warn("job ended", "status", job.Status, "error", err)
+	} else {
+		logger.Info("job ended", "status", job.Status)
+	}
+}
+
+func (m *Manager) finish(ctx context.Context, job *model.Job, err error) {
+	switch {
+	case ctx.Err() != nil:
+		job.Status = model.JobCanceled
+		msg := "canceled"
+		if m.ctx.Err() != nil {
+			msg = "canceled by a shutdown"
+		}
+		job.Error = &msg
+	case err != nil:
+		job.Status = model.JobFailed
+		job.Error = ptr.To(err.Error())
+	default:
+		job.Status = model.JobSucceeded
+	}
+	job.FinishedAt = ptr.To(time.Now())
+	m.save(job)
+}
+
+func (m *Manager) save(job *model.Job) {
+	if err := m.repo.Update(job.ID, job); err != nil {
+		slog.Warn("failed to save job", "job_id", job.ID, "error", err)
+	}
+	publish(job)
+}
+
+func publish(job *model.Job) {
+	events.Publish(events.JobUpdated, events.Job{
+		ID:     job.ID,
+		Kind:   job.Kind,
+		Status: job.Status,
+		Done:   job.Done,
+		Total:  job.Total,
+		Error:  ptr.From(job.Error),
+	})
+}
+
+// Progress records the progress of a job. It is safe for concurrent use.
+type Progress struct {
+	m   *Manager
+	job *model.Job
+
+	mu    sync.Mutex
+	saved time.Time
+}
+
+// SetTotal sets the number of steps of the job.
+func (p *Progress) SetTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.job.Total = n
+	p.save()
+}
+
+// Add records that n more steps are done.
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.job.Done += n
+	if time.Since(p.saved) >= saveInterval || p.job.Done == p.job.Total {
+		p.save()
+	}
+}
+
+func (p *Progress) save() {
+	p.saved = time.Now()
+	p.m.save(p.job)
+}
@@ -0,0 +1,226 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/jobs"
+)
+
+// mockJobRepo keeps jobs in memory.
+type mockJobRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	jobs   map[uint]model.Job
+}
+
+func newMockJobRepo() *mockJobRepo {
+	return &mockJobRepo{jobs: make(map[uint]model.Job)}
+}
+
+func (m *mockJobRepo) Get(id uint) (*model.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, repo.ErrNotFound
+	}
+	return &job, nil
+}
+
+func (m *mockJobRepo) Create(job *model.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	job.ID = m.nextID
+	m.jobs[job.ID] = *job
+	return nil
+}
+
+func (m *mockJobRepo) Update(id uint, job *model.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[id] = *job
+	return nil
+}
+
+func (m *mockJobRepo) Interrupt() error { return nil }
+
+func (m *mockJobRepo) Prune(keep int) error { return nil }
+
+// waitStatus waits until the job has the given status and returns it.
+func waitStatus(t *testing.T, r *mockJobRepo, id uint, status string) *model.Job {
+	t.Helper()
+	var job *model.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = r.Get(id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond, "job %d never became %s", id, status)
+	return job
+}
+
+func TestManagerSubmit(t *testing.T) {
+	for _, tt := range []struct {
+		description string
+		fn          jobs.Func
+		wantStatus  string
+		wantError   *string
+	}{
+		{
+			description: "success records the progress",
+			fn: func(ctx context.Context, p *jobs.Progress) error {
+				p.SetTotal(3)
+				for range 3 {
+					p.Add(1)
+				}
+				return nil
+			},
+			wantStatus: model.JobSucceeded,
+		},
+		{
+			description: "failure records the error",
+			fn: func(ctx context.Context, p *jobs.Progress) error {
+				return errors.New("boom")
+			},
+			wantStatus: model.JobFailed,
+			wantError:  ptr.To("boom"),
+		},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			r := newMockJobRepo()
+			m := jobs.NewManager(context.Background(), r, 1)
+
+			job, err := m.Submit("test", tt.fn)
+			require.NoError(t, err)
+			assert.Equal(t, model.JobQueued, job.Status)
+
+			got := waitStatus(t, r, job.ID, tt.wantStatus)
+			assert.Equal(t, tt.wantError, got.Error)
+			assert.NotNil(t, got.StartedAt)
+			assert.NotNil(t, got.FinishedAt)
+			if tt.wantStatus == model.JobSucceeded {
+				assert.Equal(t, 3, got.Done)
+				assert.Equal(t, 3, got.Total)
+			}
+
+			assert.ErrorIs(t, m.Cancel(job.ID), jobs.ErrFinished)
+		})
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	r := newMockJobRepo()
+	m := jobs.NewManager(context.Background(), r, 1)
+
+	running, err := m.Submit("test", func(ctx context.Context, p *jobs.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+	waitStatus(t, r, running.ID, model.JobRunning)
+
+	// the only slot is taken, so this one stays queued
+	queued, err := m.Submit("test", func(ctx context.Context, p *jobs.Progress) error {
+		t.Error("a canceled queued job must not run")
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Cancel(queued.ID))
+	got := waitStatus(t, r, queued.ID, model.JobCanceled)
+	assert.Nil(t, got.StartedAt)
+
+	require.NoError(t, m.Cancel(running.ID))
+	got = waitStatus(t, r, running.ID, model.JobCanceled)
+	assert.Equal(t, ptr.To("canceled"), got.Error)
+
+	assert.ErrorIs(t, m.Cancel(100), repo.ErrNotFound)
+}
+
+func TestManagerConcurrency(t *testing.T) {
+	r := newMockJobRepo()
+	m := jobs.NewManager(context.Background(), r, 2)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	ids := make([]uint, 0, 5)
+	for range 5 {
+		job, err := m.Submit("test", func(ctx context.Context, p *jobs.Progress) error {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+		require.NoError(t, err)
+		ids = append(ids, job.ID)
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running == 2
+	}, 2*time.Second, 5*time.Millisecond)
+	close(release)
+	for _, id := range ids {
+		waitStatus(t, r, id, model.JobSucceeded)
+	}
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestManagerShutdown(t *testing.T) {
+	r := newMockJobRepo()
+	ctx, cancel := context.WithCancel(context.Background())
+	m := jobs.NewManager(ctx, r, 1)
+
+	job, err := m.Submit("test", func(ctx context.Context, p *jobs.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+	waitStatus(t, r, job.ID, model.JobRunning)
+
+	cancel()
+	require.NoError(t, m.Wait(context.Background()))
+	got, err := r.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobCanceled, got.Status, "the status is saved before Wait returns")
+	assert.Equal(t, ptr.To("canceled by a shutdown"), got.Error)
+}
+
+func TestManagerWaitTimeout(t *testing.T) {
+	r := newMockJobRepo()
+	m := jobs.NewManager(context.Background(), r, 1)
+	release := make(chan struct{})
+	job, err := m.Submit("test", func(ctx context.Context, p *jobs.Progress) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+	waitStatus(t, r, job.ID, model.JobRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Wait(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, m.Wait(context.Background()))
+	got, err := r.Get(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.JobSucceeded, got.Status)
+}
@@ -0,0 +1,101 @@
+// Package maintenance keeps the database small and fast: it purges
+// soft-deleted rows, releases free space and refreshes planner statistics.
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+)
+
+type Repo interface {
+	Purge(before time.Time) (int64, error)
+	Optimize() error
+	CreateRun(run *model.MaintenanceRun) error
+}
+
+type StatsRepo interface {
+	GetDatabaseSize() (int64, error)
+}
+
+type Job struct {
+	repo  Repo
+	stats StatsRepo
+	// purgeAfter is how long soft-deleted rows are kept. Zero disables
+	// purging.
+	purgeAfter time.Duration
+}
+
+func NewJob(repo Repo, stats StatsRepo, purgeAfter time.Duration) *Job {
+	return &Job{
+		repo:       repo,
+		stats:      stats,
+		purgeAfter: purgeAfter,
+	}
+}
+
+// Run runs the job every interval until ctx is done.
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := j.RunOnce()
+			if err != nil {
+				slog.Error("database maintenance failed", "error", err)
+				continue
+			}
+			slog.Info("database maintenance done", "purged_rows", run.PurgedRows,
+				"reclaimed_bytes", run.ReclaimedBytes, "duration_ms", run.Duration)
+		}
+	}
+}
+
+// RunOnce runs the maintenance and records the run, also when it fails.
+func (j *Job) RunOnce() (*model.MaintenanceRun, error) {
+	start := time.Now()
+	run := &model.MaintenanceRun{}
+	err := j.run(run)
+	run.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		msg := err.Error()
+		run.Error = &msg
+	}
+
+	if recordErr := j.repo.CreateRun(run); recordErr != nil {
+		slog.Warn("failed to record maintenance run", "error", recordErr)
+	}
+	return run, err
+}
+
+func (j *Job) run(run *model.MaintenanceRun) error {
+	before, err := j.stats.GetDatabaseSize()
+	if err != nil {
+		return err
+	}
+
+	if j.purgeAfter > 0 {
+		run.PurgedRows, err = j.repo.Purge(time.Now().Add(-j.purgeAfter))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := j.repo.Optimize(); err != nil {
+		return err
+	}
+
+	after, err := j.stats.GetDatabaseSize()
+	if err != nil {
+		return err
+	}
+	// the size may grow, e.g. from the ANALYZE statistics
+	run.ReclaimedBytes = max(before-after, 0)
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package metrics defines the Prometheus metrics exported by fusion.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "fusionx"
+
+// Registry holds all fusion metrics. A dedicated registry is used instead of
+// the global default one, so that nothing is exported unless registered here.
+var Registry = prometheus.NewRegistry()
+
+var (
+	feedFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_fetches_total",
+		Help:      "Number of feed fetch attempts by HTTP status class.",
+	}, []string{"status_class"})
+	feedFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_fetch_errors_total",
+		Help:      "Number of failed feed fetch attempts by HTTP status class.",
+	}, []string{"status_class"})
+	feedFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "feed_fetch_duration_seconds",
+		Help:      "Duration of feed fetch attempts by HTTP status class.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"status_class"})
+	itemsInserted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "items_inserted_total",
+		Help:      "Number of new items stored by the puller.",
+	})
+	pullerQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "puller_queue_depth",
+		Help:      "Number of feeds waiting for or being pulled.",
+	})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		feedFetches,
+		feedFetchErrors,
+		feedFetchDuration,
+		itemsInserted,
+		pullerQueueDepth,
+		httpRequestDuration,
+	)
+}
+
+// StatusClass groups an HTTP status code into "2xx", "4xx", etc. Requests
+// that never got a response are reported as "none".
+func StatusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "none"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
+
+// ObserveFetch records a single feed fetch attempt.
+func ObserveFetch(statusCode int, duration time.Duration, err error) {
+	class := StatusClass(statusCode)
+	feedFetches.WithLabelValues(class).Inc()
+	feedFetchDuration.WithLabelValues(class).Observe(duration.Seconds())
+	if err != nil {
+		feedFetchErrors.WithLabelValues(class).Inc()
+	}
+}
+
+// AddItemsInserted records the number of newly stored items.
+func AddItemsInserted(n int) {
+	itemsInserted.Add(float64(n))
+}
+
+// AddPullerQueue adjusts the puller queue depth by delta.
+func AddPullerQueue(delta int) {
+	pullerQueueDepth.Add(float64(delta))
+}
+
+// ObserveHTTPRequest records the latency of a handled HTTP request.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, fmt.Sprint(status)).Observe(duration.Seconds())
+}
+
+// State is a point-in-time view of data that lives in the database.
+type State struct {
+	FeedsOK        int
+	FeedsFailing   int
+	FeedsSuspended int
+	UnreadItems    int
+	DatabaseSize   int64
+}
+
+// StateFn loads the current State. It's called on every scrape.
+type StateFn func(ctx context.Context) (*State, error)
+
+// RegisterState exports the values returned by fn as gauges.
+func RegisterState(fn StateFn) error {
+	return Registry.Register(&stateCollector{fn: fn})
+}
+
+var (
+	feedsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "feeds"),
+		"Number of feeds by state.",
+		[]string{"state"}, nil,
+	)
+	unreadItemsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "unread_items"),
+		"Number of unread items.",
+		nil, nil,
+	)
+	databaseSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "database_size_bytes"),
+		"Size of the database in bytes.",
+		nil, nil,
+	)
+)
+
+type stateCollector struct {
+	fn StateFn
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- feedsDesc
+	ch <- unreadItemsDesc
+	ch <- databaseSizeDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	state, err := c.fn(ctx)
+	if err != nil {
+		slog.Warn("failed to collect metrics state", "error", err)
+		ch <- prometheus.NewInvalidMetric(feedsDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(feedsDesc, prometheus.GaugeValue, float64(state.FeedsOK), "ok")
+	ch <- prometheus.MustNewConstMetric(feedsDesc, prometheus.GaugeValue, float64(state.FeedsFailing), "failing")
+	ch <- prometheus.MustNewConstMetric(feedsDesc, prometheus.GaugeValue, float64(state.FeedsSuspended), "suspended")
+	ch <- prometheus.MustNewConstMetric(unreadItemsDesc, prometheus.GaugeValue, float64(state.UnreadItems))
+	ch <- prometheus.MustNewConstMetric(databaseSizeDesc, prometheus.GaugeValue, float64(state.DatabaseSize))
+}
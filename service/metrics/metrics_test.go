@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
+)
+
+func TestStatusClass(t *testing.T) {
+	for _, tt := range []struct {
+		statusCode int
+		expected   string
+	}{
+		{statusCode: 0, expected: "none"},
+		{statusCode: 200, expected: "2xx"},
+		{statusCode: 304, expected: "3xx"},
+		{statusCode: 404, expected: "4xx"},
+		{statusCode: 503, expected: "5xx"},
+		{statusCode: 999, expected: "none"},
+	} {
+		assert.Equal(t, tt.expected, metrics.StatusClass(tt.statusCode), "status code %d", tt.statusCode)
+	}
+}
+
+func TestRegisterState(t *testing.T) {
+	err := metrics.RegisterState(func(ctx context.Context) (*metrics.State, error) {
+		return &metrics.State{
+			FeedsOK:        3,
+			FeedsFailing:   2,
+			FeedsSuspended: 1,
+			UnreadItems:    42,
+			DatabaseSize:   4096,
+		}, nil
+	})
+	require.NoError(t, err)
+
+	expected := `
+# HELP fusionx_feeds Number of feeds by state.
+# TYPE fusionx_feeds gauge
+fusionx_feeds{state="failing"} 2
+fusionx_feeds{state="ok"} 3
+fusionx_feeds{state="suspended"} 1
+# HELP fusionx_unread_items Number of unread items.
+# TYPE fusionx_unread_items gauge
+fusionx_unread_items 42
+`
+	err = testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expected),
+		"fusionx_feeds", "fusionx_unread_items")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,269 @@
+package newsletter
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/sanitize"
+)
+
+// maxPartDepth is how deeply multipart bodies may be nested.
+const maxPartDepth = 10
+
+// ErrNoContent is returned for messages without a text or HTML body.
+var ErrNoContent = errors.New("message has no text or HTML body")
+
+// Message is an email parsed for a newsletter.
+type Message struct {
+	// ID is the Message-ID without the angle brackets, empty if missing.
+	ID string
+	// From is nil if the message has no valid sender.
+	From    *mail.Address
+	Subject string
+	Date    time.Time
+	// Content is sanitized HTML.
+	Content string
+	// Link is the web version of the newsletter, empty if there is none.
+	Link string
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// ParseMessage reads an email and converts its HTML body, or its text body
+// if it has none, to sanitized HTML.
+func ParseMessage(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Message{
+		ID:      strings.Trim(msg.Header.Get("Message-Id"), "<> \t"),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+	}
+	if from, err := (&mail.AddressParser{WordDecoder: wordDecoder}).Parse(msg.Header.Get("From")); err == nil {
+		res.From = from
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		res.Date = date
+	} else {
+		res.Date = time.Now()
+	}
+
+	htmlBody, textBody, err := readPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	content := htmlBody
+	if content == "" {
+		if textBody == "" {
+			return nil, ErrNoContent
+		}
+		content = textToHTML(textBody)
+	}
+	res.Link = webVersionLink(content)
+	res.Content, err = sanitize.HTML(content, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Sender is the name of the sender, or their address if the name is empty.
+func (m *Message) Sender() string {
+	switch {
+	case m.From == nil:
+		return ""
+	case m.From.Name != "":
+		return m.From.Name
+	default:
+		return m.From.Address
+	}
+}
+
+// Item returns the message as an item. The title is the subject, or the
+// sender if there is no subject. Messages without a Message-ID are told
+// apart by their content.
+func (m *Message) Item() *model.Item {
+	title := m.Subject
+	if title == "" {
+		title = "Newsletter from " + m.Sender()
+	}
+	guid := m.ID
+	if guid == "" {
+		sum := sha256.Sum256([]byte(m.Sender() + "\n" + m.Subject + "\n" + m.Date.UTC().String() + "\n" + m.Content))
+		guid = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	pubDate := m.Date
+	return &model.Item{
+		Title:   &title,
+		GUID:    &guid,
+		Link:    ptr.To(m.Link),
+		Content: ptr.To(m.Content),
+		PubDate: &pubDate,
+	}
+}
+
+// decodeHeader decodes the RFC 2047 encoded words of a header, and keeps the
+// header as is if they are broken.
+func decodeHeader(v string) string {
+	decoded, err := wordDecoder.DecodeHeader(v)
+	if err != nil {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// readPart returns the first HTML and text bodies of a part, looking into
+// multipart parts but skipping attachments.
+func readPart(contentType, transferEncoding string, body io.Reader, depth int) (htmlBody, textBody string, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// RFC 2045 defaults to plain text
+		mediaType, params = "text/plain", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		if depth >= maxPartDepth {
+			return "", "", errors.New("multipart message is nested too deeply")
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", "", err
+			}
+			if disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+			partHTML, partText, err := readPart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p, depth+1)
+			if err != nil {
+				return "", "", err
+			}
+			if htmlBody == "" {
+				htmlBody = partHTML
+			}
+			if textBody == "" {
+				textBody = partText
+			}
+		}
+		return htmlBody, textBody, nil
+	case mediaType == "text/html", mediaType == "text/plain":
+		text, err := readText(body, params["charset"])
+		if err != nil {
+			return "", "", err
+		}
+		if mediaType == "text/html" {
+			return text, "", nil
+		}
+		return "", text, nil
+	default:
+		return "", "", nil
+	}
+}
+
+// readText reads a text body and converts it from label to UTF-8.
+func readText(body io.Reader, label string) (string, error) {
+	if label != "" && !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "us-ascii") {
+		r, err := charset.NewReaderLabel(label, body)
+		if err != nil {
+			return "", fmt.Errorf("unsupported charset %q: %w", label, err)
+		}
+		body = r
+	}
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+var (
+	urlPattern       = regexp.MustCompile(`https?://[^\s<>"]+[^\s<>".,;:!?)\]]`)
+	paragraphPattern = regexp.MustCompile(`\n\s*\n`)
+)
+
+// textToHTML turns a plain text body into paragraphs with clickable links.
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var b strings.Builder
+	for _, para := range paragraphPattern.Split(strings.TrimSpace(text), -1) {
+		b.WriteString("<p>")
+		for i, line := range strings.Split(para, "\n") {
+			if i > 0 {
+				b.WriteString("<br>")
+			}
+			last := 0
+			for _, loc := range urlPattern.FindAllStringIndex(line, -1) {
+				b.WriteString(html.EscapeString(line[last:loc[0]]))
+				link := html.EscapeString(line[loc[0]:loc[1]])
+				b.WriteString(`<a href="` + link + `">` + link + `</a>`)
+				last = loc[1]
+			}
+			b.WriteString(html.EscapeString(line[last:]))
+		}
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+var webVersionPattern = regexp.MustCompile(`(?i)\b(view|read|open)\b.{0,20}\b(browser|online|web)\b|web version`)
+
+// webVersionLink returns the link to the web version that many newsletters
+// put at the top, such as "View in browser".
+func webVersionLink(content string) string {
+	doc, err := xhtml.Parse(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	for n := range doc.Descendants() {
+		if n.Type != xhtml.ElementNode || n.DataAtom != atom.A {
+			continue
+		}
+		var href string
+		for _, a := range n.Attr {
+			if a.Key == "href" {
+				href = strings.TrimSpace(a.Val)
+			}
+		}
+		if !strings.HasPrefix(href, "https://") && !strings.HasPrefix(href, "http://") {
+			continue
+		}
+		var text strings.Builder
+		for c := range n.Descendants() {
+			if c.Type == xhtml.TextNode {
+				text.WriteString(c.Data)
+			}
+		}
+		if webVersionPattern.MatchString(strings.Join(strings.Fields(text.String()), " ")) {
+			return href
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package newsletter_test
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/newsletter"
+)
+
+const multipartMessage = "From: =?utf-8?q?Caf=C3=A9_Weekly?= <news@cafe.example.com>\r\n" +
+	"To: abc@news.example.org\r\n" +
+	"Subject: =?iso-8859-1?q?Issue_=231:_Caf=E9?=\r\n" +
+	"Date: Mon, 12 Aug 2024 22:00:00 +0000\r\n" +
+	"Message-ID: <issue-1@cafe.example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"The text version\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>p { color: red }</style></head><body>\r\n" +
+	"<a href=3D\"https://cafe.example.com/issues/1\">View in your browser</a>\r\n" +
+	"<p onclick=3D\"evil()\">Caf=E9 news</p><script>alert(1)</script>\r\n" +
+	"</body></html>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/html\r\n" +
+	"Content-Disposition: attachment; filename=old.html\r\n" +
+	"\r\n" +
+	"<p>an attachment</p>\r\n" +
+	"--outer--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	msg, err := newsletter.ParseMessage(strings.NewReader(multipartMessage))
+	require.NoError(t, err)
+	assert.Equal(t, "issue-1@cafe.example.com", msg.ID)
+	assert.Equal(t, "Café Weekly", msg.Sender())
+	assert.Equal(t, "Issue #1: Café", msg.Subject)
+	assert.Equal(t, time.Date(2024, 8, 12, 22, 0, 0, 0, time.UTC), msg.Date.UTC())
+	assert.Equal(t, "https://cafe.example.com/issues/1", msg.Link)
+	assert.Contains(t, msg.Content, "<p>Café news</p>")
+	for _, removed := range []string{"script", "onclick", "color: red", "text version", "attachment"} {
+		assert.NotContains(t, msg.Content, removed)
+	}
+
+	item := msg.Item()
+	assert.Equal(t, "Issue #1: Café", ptr.From(item.Title))
+	assert.Equal(t, "issue-1@cafe.example.com", ptr.From(item.GUID))
+}
+
+func TestParseMessagePlainText(t *testing.T) {
+	msg, err := newsletter.ParseMessage(strings.NewReader("From: news@example.com\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"SGVsbG8gPHRoZXJlPiEKc2VlIGh0dHBzOi8vZXhhbXBsZS5jb20vYT9iPWMuCgpCeWU=\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "<p>Hello &lt;there&gt;!<br/>see "+
+		`<a href="https://example.com/a?b=c" target="_blank" rel="noopener noreferrer nofollow">https://example.com/a?b=c</a>.</p>`+
+		"\n<p>Bye</p>\n", msg.Content)
+
+	item := msg.Item()
+	assert.Equal(t, "Newsletter from news@example.com", ptr.From(item.Title), "the sender is the title without a subject")
+	assert.True(t, strings.HasPrefix(ptr.From(item.GUID), "sha256:"))
+	assert.Equal(t, ptr.From(item.GUID), ptr.From(msg.Item().GUID), "the GUID without a Message-ID is stable")
+
+	_, err = newsletter.ParseMessage(strings.NewReader("From: news@example.com\r\nContent-Type: image/png\r\n\r\nPNG"))
+	assert.ErrorIs(t, err, newsletter.ErrNoContent)
+}
+
+type mockRepo struct {
+	mu    sync.Mutex
+	feeds map[string]*model.Feed
+	items []*model.Item
+}
+
+func (m *mockRepo) GetByLink(link string) (*model.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed, ok := m.feeds[link]
+	if !ok {
+		return &model.Feed{}, repo.ErrNotFound
+	}
+	return feed, nil
+}
+
+func (m *mockRepo) Update(id uint, feed *model.Feed) error {
+	return nil
+}
+
+func (m *mockRepo) Insert(items []*model.Item) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, item := range items {
+		duplicate := false
+		for _, stored := range m.items {
+			if stored.FeedID == item.FeedID && ptr.From(stored.GUID) == ptr.From(item.GUID) {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			m.items = append(m.items, item)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockRepo) stored() []*model.Item {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*model.Item(nil), m.items...)
+}
+
+func newServer(t *testing.T) (*mockRepo, string, *model.Feed, *model.Feed) {
+	t.Helper()
+	cafe := &model.Feed{ID: 1, Link: ptr.To(newsletter.NewLink()), Kind: ptr.To(model.FeedKindNewsletter)}
+	other := &model.Feed{ID: 2, Link: ptr.To(newsletter.NewLink()), Kind: ptr.To(model.FeedKindNewsletter)}
+	rss := &model.Feed{ID: 3, Link: ptr.To("newsletter:rss"), Kind: ptr.To(model.FeedKindFeed)}
+	mock := &mockRepo{feeds: map[string]*model.Feed{*cafe.Link: cafe, *other.Link: other, *rss.Link: rss}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- newsletter.NewServer(newsletter.NewReceiver("News.example.org", mock, mock)).Serve(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return mock, ln.Addr().String(), cafe, other
+}
+
+func TestServerSMTP(t *testing.T) {
+	mock, addr, cafe, _ := newServer(t)
+	to := newsletter.Address(cafe, "news.example.org")
+
+	require.NoError(t, smtp.SendMail(addr, nil, "news@cafe.example.com", []string{to}, []byte(multipartMessage)))
+	require.NoError(t, smtp.SendMail(addr, nil, "news@cafe.example.com", []string{strings.ToUpper(to)}, []byte(multipartMessage)),
+		"a message that was already received is accepted")
+	items := mock.stored()
+	require.Len(t, items, 1, "messages are deduplicated by Message-ID")
+	assert.Equal(t, cafe.ID, items[0].FeedID)
+	assert.Equal(t, "Issue #1: Café", ptr.From(items[0].Title))
+
+	for _, rcpt := range []string{"rss@news.example.org", "unknown@news.example.org", strings.Replace(to, "news.example.org", "example.com", 1)} {
+		err := smtp.SendMail(addr, nil, "news@cafe.example.com", []string{rcpt}, []byte(multipartMessage))
+		assert.ErrorContains(t, err, "550", rcpt)
+	}
+}
+
+func TestServerLMTP(t *testing.T) {
+	mock, addr, cafe, other := newServer(t)
+	conn, err := textproto.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	expect := func(code int) string {
+		t.Helper()
+		_, msg, err := conn.ReadResponse(code)
+		require.NoError(t, err)
+		return msg
+	}
+	send := func(format string, args ...any) {
+		t.Helper()
+		require.NoError(t, conn.PrintfLine(format, args...))
+	}
+
+	expect(220)
+	send("LHLO client.example.org")
+	assert.Contains(t, expect(250), "SIZE")
+	send("DATA")
+	expect(503)
+	send("MAIL FROM:<news@cafe.example.com> SIZE=%d", newsletter.MaxMessageSize+1)
+	expect(552)
+	send("MAIL FROM:<news@cafe.example.com>")
+	expect(250)
+	send("RCPT TO:<%s>", newsletter.Address(cafe, "news.example.org"))
+	expect(250)
+	send("RCPT TO:<%s>", newsletter.Address(other, "news.example.org"))
+	expect(250)
+	send("DATA")
+	expect(354)
+	w := conn.DotWriter()
+	_, err = w.Write([]byte(strings.ReplaceAll(multipartMessage, "\r\n", "\n")))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	expect(250)
+	expect(250)
+	send("QUIT")
+	expect(221)
+
+	items := mock.stored()
+	require.Len(t, items, 2, "each recipient gets the message")
+	assert.ElementsMatch(t, []uint{cafe.ID, other.ID}, []uint{items[0].FeedID, items[1].FeedID})
+}
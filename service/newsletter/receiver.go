@@ -0,0 +1,104 @@
+// Package newsletter receives email newsletters over SMTP or LMTP and stores
+// them as items of newsletter feeds. Each newsletter has its own address,
+// whose local part is a random token kept in the link of the feed.
+package newsletter
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
+)
+
+// linkScheme prefixes the local part of the address in the link of a
+// newsletter.
+const linkScheme = "newsletter:"
+
+// ErrUnknownAddress is returned for addresses that don't belong to a
+// newsletter.
+var ErrUnknownAddress = errors.New("no newsletter has this address")
+
+// NewLink returns the link of a new newsletter, with a random address.
+func NewLink() string {
+	b := make([]byte, 10)
+	_, _ = rand.Read(b)
+	return linkScheme + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+// Address returns the email address of a newsletter feed at domain.
+func Address(feed *model.Feed, domain string) string {
+	return strings.TrimPrefix(ptr.From(feed.Link), linkScheme) + "@" + domain
+}
+
+type FeedRepo interface {
+	GetByLink(link string) (*model.Feed, error)
+	Update(id uint, feed *model.Feed) error
+}
+
+type ItemRepo interface {
+	Insert(items []*model.Item) (int, error)
+}
+
+// Receiver stores the messages sent to the addresses of newsletters.
+type Receiver struct {
+	domain string
+	feeds  FeedRepo
+	items  ItemRepo
+}
+
+// NewReceiver returns a Receiver for the addresses at domain.
+func NewReceiver(domain string, feeds FeedRepo, items ItemRepo) *Receiver {
+	return &Receiver{
+		domain: strings.ToLower(domain),
+		feeds:  feeds,
+		items:  items,
+	}
+}
+
+// Domain is the domain of the addresses of the newsletters.
+func (r *Receiver) Domain() string {
+	return r.domain
+}
+
+// Lookup returns the newsletter whose address is address.
+func (r *Receiver) Lookup(address string) (*model.Feed, error) {
+	local, domain, ok := strings.Cut(strings.ToLower(address), "@")
+	if !ok || local == "" || domain != r.domain {
+		return nil, ErrUnknownAddress
+	}
+	feed, err := r.feeds.GetByLink(linkScheme + local)
+	if errors.Is(err, repo.ErrNotFound) || (err == nil && !feed.IsNewsletter()) {
+		return nil, ErrUnknownAddress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Deliver stores msg as an item of feed. It reports whether the item is new,
+// messages with a Message-ID that the newsletter already has are skipped.
+func (r *Receiver) Deliver(feed *model.Feed, msg *Message) (bool, error) {
+	item := msg.Item()
+	item.FeedID = feed.ID
+	n, err := r.items.Insert([]*model.Item{item})
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if err := r.feeds.Update(feed.ID, &model.Feed{LastBuild: &now}); err != nil {
+		return false, err
+	}
+	metrics.AddItemsInserted(n)
+	if n > 0 {
+		events.Publish(events.ItemsNew, events.NewItems{FeedID: feed.ID, Count: n})
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,309 @@
+package newsletter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+)
+
+const (
+	// MaxMessageSize is the largest message that is accepted, in bytes.
+	MaxMessageSize = 10 << 20
+	// maxRecipients is how many recipients a message may have.
+	maxRecipients = 100
+	// maxLineLength is the longest command line, RFC 5321 allows 512 bytes
+	// plus extensions.
+	maxLineLength = 4096
+	// maxSessions is how many connections are served at the same time.
+	maxSessions = 20
+	// commandTimeout is how long the client may take to send a command or
+	// the message.
+	commandTimeout = 5 * time.Minute
+)
+
+// Server accepts mail for newsletters over SMTP, or LMTP when the client
+// greets with LHLO. It never relays mail, recipients that aren't the
+// address of a newsletter are rejected.
+type Server struct {
+	receiver *Receiver
+}
+
+// NewServer returns a Server that hands the messages to receiver.
+func NewServer(receiver *Receiver) *Server {
+	return &Server{receiver: receiver}
+}
+
+// ListenAndServe listens on addr and serves connections until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("receiving newsletters", "addr", ln.Addr().String(), "domain", s.receiver.Domain())
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves the connections of ln until ctx is done, then closes ln and
+// the open connections. It returns once the messages being received are
+// handed to the receiver.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() {
+		// #nosec G104 - Accept reports the error
+		ln.Close()
+	})
+	defer stop()
+
+	sessions := make(chan struct{}, maxSessions)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case sessions <- struct{}{}:
+		default:
+			// #nosec G104 - the client is turned away anyway
+			conn.Write([]byte("421 too many connections, try again later\r\n"))
+			conn.Close()
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-sessions }()
+			closeConn := context.AfterFunc(ctx, func() {
+				// #nosec G104 - the session ends with a read error
+				conn.Close()
+			})
+			defer closeConn()
+			s.serve(conn)
+		})
+	}
+}
+
+// session is the state of a mail transaction.
+type session struct {
+	greeted bool
+	lmtp    bool
+	from    *string
+	rcpts   []*model.Feed
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	logger := slog.With("remote_addr", conn.RemoteAddr().String())
+	br := bufio.NewReaderSize(conn, maxLineLength)
+	w := textproto.NewWriter(bufio.NewWriter(conn))
+	reply := func(code int, format string, args ...any) error {
+		return w.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+	}
+
+	var sess session
+	if err := reply(220, "%s FusionX ready", s.receiver.Domain()); err != nil {
+		return
+	}
+	for {
+		// #nosec G104 - a missed deadline ends the session with a read error
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// skip the rest of the line
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = br.ReadSlice('\n')
+			}
+			if err != nil || reply(500, "line too long") != nil {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(strings.TrimRight(string(line), "\r\n"), " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToUpper(verb) {
+		case "HELO", "EHLO", "LHLO":
+			if arg == "" {
+				err = reply(501, "domain is missing")
+				break
+			}
+			sess = session{greeted: true, lmtp: strings.EqualFold(verb, "LHLO")}
+			if strings.EqualFold(verb, "HELO") {
+				err = reply(250, "%s", s.receiver.Domain())
+				break
+			}
+			err = w.PrintfLine("250-%s\r\n250-8BITMIME\r\n250-PIPELINING\r\n250 SIZE %d", s.receiver.Domain(), MaxMessageSize)
+		case "MAIL":
+			err = s.mail(&sess, arg, reply)
+		case "RCPT":
+			err = s.rcpt(&sess, arg, reply, logger)
+		case "DATA":
+			if len(sess.rcpts) == 0 {
+				err = reply(503, "need RCPT first")
+				break
+			}
+			if err = reply(354, "end data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+			err = s.data(&sess, textproto.NewReader(br).DotReader(), reply, logger)
+			sess = session{greeted: true, lmtp: sess.lmtp}
+		case "RSET":
+			sess = session{greeted: sess.greeted, lmtp: sess.lmtp}
+			err = reply(250, "OK")
+		case "NOOP":
+			err = reply(250, "OK")
+		case "VRFY":
+			err = reply(252, "cannot verify addresses")
+		case "QUIT":
+			// #nosec G104 - the connection is closed anyway
+			reply(221, "bye")
+			return
+		default:
+			err = reply(502, "command not implemented")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) mail(sess *session, arg string, reply func(int, string, ...any) error) error {
+	switch {
+	case !sess.greeted:
+		return reply(503, "say hello first")
+	case sess.from != nil:
+		return reply(503, "nested MAIL command")
+	}
+	from, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		return reply(501, "syntax: MAIL FROM:<address>")
+	}
+	for _, p := range params {
+		key, value, _ := strings.Cut(p, "=")
+		if strings.EqualFold(key, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > MaxMessageSize {
+				return reply(552, "message is too large")
+			}
+		}
+	}
+	sess.from = &from
+	return reply(250, "OK")
+}
+
+func (s *Server) rcpt(sess *session, arg string, reply func(int, string, ...any) error, logger *slog.Logger) error {
+	if sess.from == nil {
+		return reply(503, "need MAIL first")
+	}
+	to, _, ok := parsePath(arg, "TO:")
+	if !ok {
+		return reply(501, "syntax: RCPT TO:<address>")
+	}
+	if len(sess.rcpts) >= maxRecipients {
+		return reply(452, "too many recipients")
+	}
+	feed, err := s.receiver.Lookup(to)
+	if errors.Is(err, ErrUnknownAddress) {
+		return reply(550, "no such newsletter")
+	}
+	if err != nil {
+		logger.Error("failed to look up newsletter", "error", err, "to", to)
+		return reply(451, "temporary failure, try again later")
+	}
+	sess.rcpts = append(sess.rcpts, feed)
+	return reply(250, "OK")
+}
+
+// data reads the message and delivers it to the recipients. In LMTP mode,
+// there is one reply per recipient.
+func (s *Server) data(sess *session, r io.Reader, reply func(int, string, ...any) error, logger *slog.Logger) error {
+	data, err := io.ReadAll(io.LimitReader(r, MaxMessageSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxMessageSize {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+		return s.replyAll(sess, reply, 552, "message is too large")
+	}
+
+	msg, err := ParseMessage(bytes.NewReader(data))
+	if err != nil {
+		logger.Warn("failed to parse newsletter", "error", err, "from", *sess.from)
+		return s.replyAll(sess, reply, 554, "cannot parse message: %s", err)
+	}
+
+	failed := false
+	for _, feed := range sess.rcpts {
+		code, text := 250, "OK"
+		if _, err := s.receiver.Deliver(feed, msg); err != nil {
+			logger.Error("failed to store newsletter", "error", err, "feed_id", feed.ID)
+			code, text, failed = 451, "temporary failure, try again later", true
+		} else {
+			logger.Info("received newsletter", "feed_id", feed.ID, "from", *sess.from, "message_id", msg.ID)
+		}
+		if sess.lmtp {
+			if err := reply(code, "%s", text); err != nil {
+				return err
+			}
+		}
+	}
+	if sess.lmtp {
+		return nil
+	}
+	// in SMTP, a message is accepted for all recipients or none, but items
+	// that were stored are skipped when it is sent again
+	if failed {
+		return reply(451, "temporary failure, try again later")
+	}
+	return reply(250, "OK")
+}
+
+// replyAll sends one reply in SMTP mode and the same reply for each
+// recipient in LMTP mode.
+func (s *Server) replyAll(sess *session, reply func(int, string, ...any) error, code int, format string, args ...any) error {
+	n := 1
+	if sess.lmtp {
+		n = len(sess.rcpts)
+	}
+	for range n {
+		if err := reply(code, format, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePath parses the "FROM:<address> PARAM=value" argument of MAIL and
+// RCPT.
+func parsePath(arg, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimSpace(arg[len(prefix):]))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	path := fields[0]
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", nil, false
+	}
+	return path[1 : len(path)-1], fields[1:], true
+}
@@ -0,0 +1,68 @@
+// Package opml parses OPML subscription lists.
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Feed is a subscription found in an OPML document.
+type Feed struct {
+	// Group is the title of the enclosing outline, or empty for top-level
+	// subscriptions.
+	Group string
+	Name  string
+	Link  string
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr"`
+	XMLURL   string    `xml:"xmlUrl,attr"`
+	Outlines []outline `xml:"outline"`
+}
+
+func (o outline) name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+type document struct {
+	Body struct {
+		Outlines []outline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// Parse reads the subscriptions from an OPML document. Nested groups are
+// flattened into the outermost one.
+func Parse(r io.Reader) ([]Feed, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var feeds []Feed
+	var walk func(group string, outlines []outline)
+	walk = func(group string, outlines []outline) {
+		for _, o := range outlines {
+			if link := strings.TrimSpace(o.XMLURL); link != "" {
+				name := strings.TrimSpace(o.name())
+				if name == "" {
+					name = link
+				}
+				feeds = append(feeds, Feed{Group: group, Name: name, Link: link})
+				continue
+			}
+			sub := group
+			if sub == "" {
+				sub = strings.TrimSpace(o.name())
+			}
+			walk(sub, o.Outlines)
+		}
+	}
+	walk("", doc.Body.Outlines)
+	return feeds, nil
+}
@@ -0,0 +1,43 @@
+package opml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/service/opml"
+)
+
+func TestParse(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Top Level" xmlUrl="https://example.com/top.xml"/>
+    <outline text="Tech" title="Tech">
+      <outline text="Blog A" title="Blog A" xmlUrl="https://a.example.com/feed"/>
+      <outline text="Nested">
+        <outline text="Blog B" xmlUrl="https://b.example.com/feed"/>
+      </outline>
+    </outline>
+    <outline text="" xmlUrl="https://c.example.com/feed"/>
+    <outline text="Empty Group"/>
+  </body>
+</opml>`
+
+	feeds, err := opml.Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+	assert.Equal(t, []opml.Feed{
+		{Group: "", Name: "Top Level", Link: "https://example.com/top.xml"},
+		{Group: "Tech", Name: "Blog A", Link: "https://a.example.com/feed"},
+		{Group: "Tech", Name: "Blog B", Link: "https://b.example.com/feed"},
+		{Group: "", Name: "https://c.example.com/feed", Link: "https://c.example.com/feed"},
+	}, feeds)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := opml.Parse(strings.NewReader("not xml"))
+	assert.Error(t, err)
+}
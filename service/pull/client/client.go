@@ -59,35 +59,58 @@ func (c FeedClient) FetchDeclaredLink(ctx context.Context, feedURL string, optio
 type FetchItemsResult struct {
 	LastBuild *time.Time
 	Items     []*model.Item
+	// StatusCode is the HTTP status code of the response, or 0 if no response
+	// was received. It is populated even when an error is returned.
+	StatusCode int
+	// Size is the number of bytes read from the response body.
+	Size int64
 }
 
 func (c FeedClient) FetchItems(ctx context.Context, feedURL string, options model.FeedRequestOptions) (FetchItemsResult, error) {
-	feed, err := c.fetchFeed(ctx, feedURL, options)
+	data, statusCode, err := c.fetch(ctx, feedURL, options)
+	result := FetchItemsResult{
+		StatusCode: statusCode,
+		Size:       int64(len(data)),
+	}
+	if err != nil {
+		return result, err
+	}
+
+	feed, err := gofeed.NewParser().ParseString(string(data))
 	if err != nil {
-		return FetchItemsResult{}, err
+		return result, err
 	}
 
-	return FetchItemsResult{
-		LastBuild: feed.UpdatedParsed,
-		Items:     ParseGoFeedItems(feedURL, feed.Items),
-	}, nil
+	result.LastBuild = feed.UpdatedParsed
+	result.Items = ParseGoFeedItems(feedURL, feed.Items)
+	return result, nil
 }
 
 func (c FeedClient) fetchFeed(ctx context.Context, feedURL string, options model.FeedRequestOptions) (*gofeed.Feed, error) {
-	resp, err := c.httpRequestFn(ctx, feedURL, options)
+	data, _, err := c.fetch(ctx, feedURL, options)
 	if err != nil {
 		return nil, err
 	}
+
+	return gofeed.NewParser().ParseString(string(data))
+}
+
+// fetch retrieves the raw body of feedURL along with the HTTP status code.
+func (c FeedClient) fetch(ctx context.Context, feedURL string, options model.FeedRequestOptions) ([]byte, int, error) {
+	resp, err := c.httpRequestFn(ctx, feedURL, options)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("got status code %d", resp.StatusCode)
+		return nil, resp.StatusCode, fmt.Errorf("got status code %d", resp.StatusCode)
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
-	return gofeed.NewParser().ParseString(string(data))
+	return data, resp.StatusCode, nil
 }
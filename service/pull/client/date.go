@@ -0,0 +1,55 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the date formats of scraped pages and JSON APIs, tried in
+// order.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"Jan. 2, 2006",
+	"Monday, January 2, 2006",
+	"Mon, Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+	"2006/01/02",
+	"01/02/2006",
+}
+
+// ordinalSuffix matches the suffix of "1st", "2nd" and so on.
+var ordinalSuffix = regexp.MustCompile(`\b(\d{1,2})(st|nd|rd|th)\b`)
+
+// ParseDate parses the common formats of dates, returning nil if s isn't a
+// known format. Dates without a time zone are in UTC.
+func ParseDate(s string) *time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return nil
+	}
+	s = ordinalSuffix.ReplaceAllString(s, "$1")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
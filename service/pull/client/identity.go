@@ -0,0 +1,100 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// Item identity strategies decide which key tells items of a feed apart.
+// The key is stored as the item's GUID.
+const (
+	// IdentityAuto uses the GUID, then the normalized link, then the
+	// content hash, whichever is available first.
+	IdentityAuto = "auto"
+	// IdentityLink ignores GUIDs, for feeds that change them on every
+	// build.
+	IdentityLink = "link"
+	// IdentityContent only uses the content hash, for feeds whose GUIDs
+	// and links are both unstable.
+	IdentityContent = "content"
+)
+
+// IdentityStrategies are the valid values for a feed's identity strategy.
+var IdentityStrategies = []string{IdentityAuto, IdentityLink, IdentityContent}
+
+// contentHashPrefix marks keys that are content hashes.
+const contentHashPrefix = "sha256:"
+
+// trackingParams are query parameters that don't change the linked page.
+var trackingParams = []string{
+	"fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid",
+	"_hsenc", "_hsmi", "yclid", "ref_src", "spm",
+}
+
+// ItemKey returns the identity of an item parsed by ParseGoFeedItems for
+// the given strategy. For IdentityAuto that is the GUID set by the parser.
+func ItemKey(strategy string, item *model.Item) string {
+	switch strategy {
+	case IdentityLink:
+		if link := NormalizeLink(ptr.From(item.Link)); link != "" {
+			return link
+		}
+	case IdentityContent:
+	default:
+		if guid := ptr.From(item.GUID); guid != "" {
+			return guid
+		}
+		if link := NormalizeLink(ptr.From(item.Link)); link != "" {
+			return link
+		}
+	}
+	return ContentHash(ptr.From(item.Title), item.PubDate, ptr.From(item.Content))
+}
+
+// NormalizeLink returns a canonical form of an absolute URL, so that links
+// that only differ in scheme, a "www." prefix, the fragment or tracking
+// parameters are equal. Anything that isn't an absolute URL is only
+// trimmed.
+func NormalizeLink(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return link
+	}
+
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Host = strings.TrimSuffix(strings.TrimSuffix(u.Host, ":443"), ":80")
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || slices.Contains(trackingParams, strings.ToLower(key)) {
+			query.Del(key)
+		}
+	}
+	// Encode sorts by key
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ContentHash returns a key derived from what a reader sees of an item.
+func ContentHash(title string, pubDate *time.Time, content string) string {
+	date := ""
+	if pubDate != nil {
+		date = pubDate.UTC().Format(time.RFC3339)
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(title) + "\x00" + date + "\x00" + strings.TrimSpace(content)))
+	return contentHashPrefix + hex.EncodeToString(sum[:])
+}
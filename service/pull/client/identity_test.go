@@ -0,0 +1,64 @@
+package client_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+func TestNormalizeLink(t *testing.T) {
+	for _, tt := range []struct {
+		link     string
+		expected string
+	}{
+		{link: "https://example.com/post", expected: "https://example.com/post"},
+		{link: "http://example.com/post", expected: "https://example.com/post"},
+		{link: "https://WWW.Example.com/post", expected: "https://example.com/post"},
+		{link: "https://example.com:443/post", expected: "https://example.com/post"},
+		{link: "https://example.com", expected: "https://example.com/"},
+		{link: "https://example.com/post#comments", expected: "https://example.com/post"},
+		{link: "https://example.com/post?utm_source=rss&utm_medium=feed", expected: "https://example.com/post"},
+		{link: "https://example.com/post?p=2&fbclid=abc&a=1", expected: "https://example.com/post?a=1&p=2"},
+		{link: "https://example.com/Post", expected: "https://example.com/Post"},
+		{link: "  https://example.com/post ", expected: "https://example.com/post"},
+		{link: "tag:example.com,2025:post-1", expected: "tag:example.com,2025:post-1"},
+		{link: "", expected: ""},
+	} {
+		assert.Equal(t, tt.expected, client.NormalizeLink(tt.link), tt.link)
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := &model.Item{
+		GUID:    ptr.To("guid-1"),
+		Link:    ptr.To("http://www.example.com/post?utm_source=rss"),
+		Title:   ptr.To("Title"),
+		Content: ptr.To("Content"),
+		PubDate: &date,
+	}
+	hash := client.ContentHash("Title", &date, "Content")
+
+	for _, tt := range []struct {
+		strategy string
+		item     *model.Item
+		expected string
+	}{
+		{strategy: client.IdentityAuto, item: item, expected: "guid-1"},
+		{strategy: "", item: item, expected: "guid-1"},
+		{strategy: client.IdentityAuto, item: &model.Item{Link: item.Link, Title: item.Title}, expected: "https://example.com/post"},
+		{strategy: client.IdentityLink, item: item, expected: "https://example.com/post"},
+		{strategy: client.IdentityLink, item: &model.Item{GUID: item.GUID, Title: item.Title, Content: item.Content, PubDate: &date}, expected: hash},
+		{strategy: client.IdentityContent, item: item, expected: hash},
+	} {
+		assert.Equal(t, tt.expected, client.ItemKey(tt.strategy, tt.item), tt.strategy)
+	}
+
+	other := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	assert.NotEqual(t, hash, client.ContentHash("Title", &other, "Content"), "the date should be part of the hash")
+}
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// JSONClient reads the items of a JSON API with the paths of a mapping.
+// Paths are keys separated by dots, such as "data.items" or
+// "author.name", where numbers index arrays. The JSONPath forms "$.data",
+// "items[0]", "['a key']" and a trailing "[*]" are accepted too, and "\."
+// is a dot within a key. An empty items path is the whole response.
+type JSONClient struct {
+	client  FeedClient
+	mapping model.ItemMapping
+}
+
+// NewJSONClient creates a JSON client with the default options.
+func NewJSONClient(mapping model.ItemMapping) JSONClient {
+	return NewJSONClientWithRequestFn(httpx.FusionRequest, mapping)
+}
+
+// NewJSONClientWithRequestFn creates a JSON client that uses a custom
+// HttpRequestFn to call the API.
+func NewJSONClientWithRequestFn(httpRequestFn HttpRequestFn, mapping model.ItemMapping) JSONClient {
+	return JSONClient{
+		client:  NewFeedClientWithRequestFn(httpRequestFn),
+		mapping: mapping,
+	}
+}
+
+func (c JSONClient) FetchItems(ctx context.Context, endpointURL string, options model.FeedRequestOptions) (FetchItemsResult, error) {
+	data, statusCode, err := c.client.fetch(ctx, endpointURL, options)
+	result := FetchItemsResult{
+		StatusCode: statusCode,
+		Size:       int64(len(data)),
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Items, err = JSONItems(endpointURL, data, c.mapping)
+	return result, err
+}
+
+// JSONItems returns the items of a JSON response. Elements of the list that
+// aren't objects, or that have neither a title nor a link, are skipped.
+func JSONItems(endpointURL string, data []byte, mapping model.ItemMapping) ([]*model.Item, error) {
+	paths, err := compileJSONMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	list, ok := lookupJSON(doc, paths.items)
+	if !ok {
+		return nil, fmt.Errorf("no value at the items path %q", mapping.Items)
+	}
+	elems, ok := list.([]any)
+	if !ok {
+		return nil, fmt.Errorf("the value at the items path %q is not a list", mapping.Items)
+	}
+
+	base, _ := url.Parse(endpointURL)
+	items := make([]*model.Item, 0, len(elems))
+	for _, elem := range elems {
+		if _, ok := elem.(map[string]any); !ok {
+			continue
+		}
+		item := &model.Item{
+			Title:   ptr.To(jsonText(elem, paths.title)),
+			GUID:    ptr.To(jsonText(elem, paths.id)),
+			Link:    ptr.To(resolveLink(base, jsonText(elem, paths.link))),
+			Content: ptr.To(jsonText(elem, paths.content)),
+			PubDate: jsonDate(elem, paths.date),
+			Unread:  ptr.To(true),
+		}
+		if *item.Title == "" && *item.Link == "" {
+			continue
+		}
+		item.GUID = ptr.To(ItemKey(IdentityAuto, item))
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ValidateJSONMapping checks that mapping has the title path, and that all
+// paths are valid.
+func ValidateJSONMapping(mapping model.ItemMapping) error {
+	_, err := compileJSONMapping(mapping)
+	return err
+}
+
+type jsonPaths struct {
+	items, id, title, link, date, content []string
+}
+
+func compileJSONMapping(mapping model.ItemMapping) (*jsonPaths, error) {
+	if strings.TrimSpace(mapping.Title) == "" {
+		return nil, errors.New("the title path is required")
+	}
+	res := &jsonPaths{}
+	for _, f := range []struct {
+		name string
+		path string
+		dst  *[]string
+	}{
+		{"items", mapping.Items, &res.items},
+		{"id", mapping.ID, &res.id},
+		{"title", mapping.Title, &res.title},
+		{"link", mapping.Link, &res.link},
+		{"date", mapping.Date, &res.date},
+		{"content", mapping.Content, &res.content},
+	} {
+		if f.name != "items" && strings.TrimSpace(f.path) == "" {
+			// an empty field path finds nothing, not the whole item
+			continue
+		}
+		keys, err := parseJSONPath(f.path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s path: %w", f.name, err)
+		}
+		*f.dst = keys
+	}
+	return res, nil
+}
+
+// parseJSONPath splits a path into keys. An empty path has no keys and is
+// the value itself.
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimSuffix(path, "[*]")
+	keys := make([]string, 0)
+	var key strings.Builder
+	// pending is set when a key was started, so that "a..b" is an error
+	pending := false
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		if key.Len() == 0 {
+			return errors.New("empty key")
+		}
+		keys = append(keys, key.String())
+		key.Reset()
+		pending = false
+		return nil
+	}
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 == len(path) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			key.WriteByte(path[i])
+			pending = true
+		case '.':
+			if i == 0 {
+				// "$.a" and ".a"
+				continue
+			}
+			if !pending && path[i-1] != ']' {
+				return nil, errors.New("empty key")
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case '[':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, errors.New("missing ]")
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				keys = append(keys, inner[1:len(inner)-1])
+				continue
+			}
+			if _, err := strconv.Atoi(inner); err != nil {
+				return nil, fmt.Errorf("invalid index %q", inner)
+			}
+			keys = append(keys, inner)
+		default:
+			key.WriteByte(c)
+			pending = true
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// lookupJSON returns the value at keys. A nil keys is the value itself.
+func lookupJSON(v any, keys []string) (any, bool) {
+	for _, key := range keys {
+		switch t := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = t[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonText returns a string, number or boolean at keys as text, and an
+// empty string for other values or when keys is nil.
+func jsonText(item any, keys []string) string {
+	if keys == nil {
+		return ""
+	}
+	v, _ := lookupJSON(item, keys)
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return ""
+	}
+}
+
+// jsonDate parses a date in a common format, or a Unix timestamp in seconds
+// or milliseconds.
+func jsonDate(item any, keys []string) *time.Time {
+	s := jsonText(item, keys)
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		if n > 1e12 {
+			return ptr.To(time.UnixMilli(int64(n)).UTC())
+		}
+		return ptr.To(time.Unix(int64(n), 0).UTC())
+	}
+	return ParseDate(s)
+}
+
+// resolveLink makes a link relative to the endpoint absolute.
+func resolveLink(base *url.URL, link string) string {
+	if base == nil || link == "" {
+		return link
+	}
+	u, err := base.Parse(link)
+	if err != nil {
+		return link
+	}
+	return u.String()
+}
@@ -0,0 +1,100 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+const releases = `{"data": {"releases": [
+	{"id": 2, "attributes": {"name": "Version 2.0", "body": "<p>New UI</p>"}, "links": [{"href": "/releases/2"}], "published": "2024-08-12T10:00:00Z"},
+	{"id": "r1", "attributes": {"name": "Version 1.0"}, "published": 1722470400},
+	{"id": 0, "attributes": {}},
+	"not an object"
+]}}`
+
+func TestJSONItems(t *testing.T) {
+	items, err := client.JSONItems("https://api.example.com/v1/releases", []byte(releases), model.ItemMapping{
+		Items:   "data.releases",
+		ID:      "id",
+		Title:   "attributes.name",
+		Link:    "links.0.href",
+		Date:    "published",
+		Content: "attributes.body",
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 2, "items without a title and a link are skipped")
+
+	assert.Equal(t, "Version 2.0", ptr.From(items[0].Title))
+	assert.Equal(t, "2", ptr.From(items[0].GUID))
+	assert.Equal(t, "https://api.example.com/releases/2", ptr.From(items[0].Link))
+	assert.Equal(t, time.Date(2024, 8, 12, 10, 0, 0, 0, time.UTC), *items[0].PubDate)
+	assert.Equal(t, "<p>New UI</p>", ptr.From(items[0].Content))
+
+	assert.Equal(t, "r1", ptr.From(items[1].GUID))
+	assert.Equal(t, "", ptr.From(items[1].Link))
+	assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), *items[1].PubDate, "unix timestamps are dates")
+	assert.Equal(t, "", ptr.From(items[1].Content))
+
+	// JSONPath forms, and a response that is the list itself
+	items, err = client.JSONItems("https://api.example.com/", []byte(`[{"a.b": {"t": "x"}, "links": [{"href": "https://example.com/x"}], "ms": 1723456789000}]`), model.ItemMapping{
+		Items: "$[*]",
+		Title: `a\.b.t`,
+		Link:  "$.links[0]['href']",
+		Date:  "ms",
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "x", ptr.From(items[0].Title))
+	assert.Equal(t, "https://example.com/x", ptr.From(items[0].Link))
+	assert.Equal(t, "https://example.com/x", ptr.From(items[0].GUID), "without an ID path, the link is the GUID")
+	assert.Equal(t, time.UnixMilli(1723456789000).UTC(), *items[0].PubDate)
+
+	_, err = client.JSONItems("https://api.example.com/", []byte(releases), model.ItemMapping{Items: "data", Title: "name"})
+	assert.ErrorContains(t, err, "not a list")
+	_, err = client.JSONItems("https://api.example.com/", []byte(releases), model.ItemMapping{Items: "data.posts", Title: "name"})
+	assert.ErrorContains(t, err, "no value")
+	_, err = client.JSONItems("https://api.example.com/", []byte(`<html>`), model.ItemMapping{Title: "name"})
+	assert.ErrorContains(t, err, "invalid JSON")
+}
+
+func TestValidateJSONMapping(t *testing.T) {
+	assert.NoError(t, client.ValidateJSONMapping(model.ItemMapping{Title: "title"}))
+	assert.NoError(t, client.ValidateJSONMapping(model.ItemMapping{Items: "$.data[*]", Title: "a.b[2]", Link: `["url"]`}))
+	assert.ErrorContains(t, client.ValidateJSONMapping(model.ItemMapping{Items: "data"}), "required")
+	assert.ErrorContains(t, client.ValidateJSONMapping(model.ItemMapping{Items: "data..items", Title: "t"}), "invalid items path")
+	assert.ErrorContains(t, client.ValidateJSONMapping(model.ItemMapping{Title: "t", Date: "a[x]"}), "invalid date path")
+	assert.ErrorContains(t, client.ValidateJSONMapping(model.ItemMapping{Title: "t", Link: "a[0"}), "invalid link path")
+}
+
+func TestJSONClientFetchItems(t *testing.T) {
+	var requested string
+	var proxy *string
+	c := client.NewJSONClientWithRequestFn(func(ctx context.Context, link string, options model.FeedRequestOptions) (*http.Response, error) {
+		requested = link
+		proxy = options.ReqProxy
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"posts": [{"title": "Hello", "url": "/hello"}]}`)),
+		}, nil
+	}, model.ItemMapping{Items: "posts", Title: "title", Link: "url"})
+
+	result, err := c.FetchItems(context.Background(), "https://example.com/api/posts", model.FeedRequestOptions{ReqProxy: ptr.To("http://proxy:8080")})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/api/posts", requested)
+	assert.Equal(t, "http://proxy:8080", ptr.From(proxy), "the request options are passed on")
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "Hello", ptr.From(result.Items[0].Title))
+	assert.Equal(t, "https://example.com/hello", ptr.From(result.Items[0].Link))
+}
@@ -22,22 +22,21 @@ func ParseGoFeedItems(feedURL string, gfItems []*gofeed.Item) []*model.Item {
 		if content == "" {
 			content = item.Description
 		}
-		guid := item.GUID
-		if guid == "" {
-			guid = item.Link
-		}
 		pubDate := item.PublishedParsed
 		if pubDate == nil {
 			pubDate = item.UpdatedParsed
 		}
-		items = append(items, &model.Item{
+		parsed := &model.Item{
 			Title:   &item.Title,
-			GUID:    &guid,
+			GUID:    ptr.To(item.GUID),
 			Link:    ptr.To(parseLink(feedURL, item.Link)),
 			Content: &content,
 			PubDate: pubDate,
 			Unread:  &unread,
-		})
+		}
+		// fall back to the link or the content when there is no GUID
+		parsed.GUID = ptr.To(ItemKey(IdentityAuto, parsed))
+		items = append(items, parsed)
 	}
 
 	return items
@@ -114,6 +114,49 @@ func TestParseGoFeedItems(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "normalizes the link when GUID is empty",
+			feedURL:     "https://example.com/feed",
+			gfItems: []*gofeed.Item{
+				{
+					Title: "Test Item",
+					Link:  "http://www.example.com/link?utm_source=rss&id=1#comments",
+				},
+			},
+			expected: []*model.Item{
+				{
+					Title:   ptr.To("Test Item"),
+					GUID:    ptr.To("https://example.com/link?id=1"),
+					Link:    ptr.To("http://www.example.com/link?utm_source=rss&id=1#comments"),
+					Content: ptr.To(""),
+					Unread:  ptr.To(true),
+				},
+			},
+		},
+		{
+			description: "uses a content hash when GUID and link are empty",
+			feedURL:     "https://example.com/feed",
+			gfItems: []*gofeed.Item{
+				{Title: "First", Content: "one"},
+				{Title: "Second", Content: "two"},
+			},
+			expected: []*model.Item{
+				{
+					Title:   ptr.To("First"),
+					GUID:    ptr.To(client.ContentHash("First", nil, "one")),
+					Link:    ptr.To(""),
+					Content: ptr.To("one"),
+					Unread:  ptr.To(true),
+				},
+				{
+					Title:   ptr.To("Second"),
+					GUID:    ptr.To(client.ContentHash("Second", nil, "two")),
+					Link:    ptr.To(""),
+					Content: ptr.To("two"),
+					Unread:  ptr.To(true),
+				},
+			},
+		},
 		{
 			description: "handles both empty content and empty GUID",
 			feedURL:     "https://example.com/feed",
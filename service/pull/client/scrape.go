@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/httpx"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+)
+
+// Scraper reads the items of an HTML page with the CSS selectors of a
+// mapping. A field selector may end with "@attr" to read an attribute
+// instead of the text, such as "a.title@href", and "@attr" alone reads an
+// attribute of the item element.
+type Scraper struct {
+	client  FeedClient
+	mapping model.ItemMapping
+}
+
+// NewScraper creates a scraper with the default options.
+func NewScraper(mapping model.ItemMapping) Scraper {
+	return NewScraperWithRequestFn(httpx.FusionRequest, mapping)
+}
+
+// NewScraperWithRequestFn creates a scraper that uses a custom HttpRequestFn
+// to retrieve pages.
+func NewScraperWithRequestFn(httpRequestFn HttpRequestFn, mapping model.ItemMapping) Scraper {
+	return Scraper{
+		client:  NewFeedClientWithRequestFn(httpRequestFn),
+		mapping: mapping,
+	}
+}
+
+func (s Scraper) FetchItems(ctx context.Context, pageURL string, options model.FeedRequestOptions) (FetchItemsResult, error) {
+	data, statusCode, err := s.client.fetch(ctx, pageURL, options)
+	result := FetchItemsResult{
+		StatusCode: statusCode,
+		Size:       int64(len(data)),
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Items, err = ScrapeItems(pageURL, data, s.mapping)
+	return result, err
+}
+
+// ScrapeItems returns the items of an HTML page. Items without a title and
+// a link are skipped.
+func ScrapeItems(pageURL string, page []byte, mapping model.ItemMapping) ([]*model.Item, error) {
+	sel, err := compileScrapeMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	r, err := charset.NewReader(bytes.NewReader(page), "")
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	resolveURLs(doc, pageURL)
+
+	items := make([]*model.Item, 0)
+	doc.FindMatcher(sel.items).Each(func(_ int, s *goquery.Selection) {
+		item := &model.Item{
+			Title:   ptr.To(sel.title.text(s)),
+			GUID:    ptr.To(sel.id.text(s)),
+			Link:    ptr.To(sel.link.text(s)),
+			Content: ptr.To(sel.content.html(s)),
+			PubDate: ParseDate(sel.date.date(s)),
+			Unread:  ptr.To(true),
+		}
+		if *item.Title == "" && *item.Link == "" {
+			return
+		}
+		item.GUID = ptr.To(ItemKey(IdentityAuto, item))
+		items = append(items, item)
+	})
+	return items, nil
+}
+
+// ValidateScrapeMapping checks that mapping has the item and title
+// selectors, and that all selectors are valid.
+func ValidateScrapeMapping(mapping model.ItemMapping) error {
+	_, err := compileScrapeMapping(mapping)
+	return err
+}
+
+type scrapeSelectors struct {
+	items                          goquery.Matcher
+	id, title, link, date, content *fieldSelector
+}
+
+func compileScrapeMapping(mapping model.ItemMapping) (*scrapeSelectors, error) {
+	if strings.TrimSpace(mapping.Items) == "" || strings.TrimSpace(mapping.Title) == "" {
+		return nil, errors.New("the items and title selectors are required")
+	}
+	items, err := cascadia.Compile(mapping.Items)
+	if err != nil {
+		return nil, fmt.Errorf("invalid items selector: %w", err)
+	}
+	res := &scrapeSelectors{items: items}
+	link := mapping.Link
+	if strings.TrimSpace(link) == "" {
+		link = "a[href]@href"
+	}
+	for _, f := range []struct {
+		name     string
+		selector string
+		dst      **fieldSelector
+	}{
+		{"id", mapping.ID, &res.id},
+		{"title", mapping.Title, &res.title},
+		{"link", link, &res.link},
+		{"date", mapping.Date, &res.date},
+		{"content", mapping.Content, &res.content},
+	} {
+		if *f.dst, err = parseFieldSelector(f.selector); err != nil {
+			return nil, fmt.Errorf("invalid %s selector: %w", f.name, err)
+		}
+	}
+	return res, nil
+}
+
+var attrName = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+
+// fieldSelector finds a field in an item. A nil fieldSelector finds
+// nothing.
+type fieldSelector struct {
+	// matcher is nil for the item element itself
+	matcher goquery.Matcher
+	attr    string
+}
+
+func parseFieldSelector(s string) (*fieldSelector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	res := &fieldSelector{}
+	if i := strings.LastIndex(s, "@"); i >= 0 && attrName.MatchString(s[i+1:]) {
+		res.attr = s[i+1:]
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "" {
+		return res, nil
+	}
+	m, err := cascadia.Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	res.matcher = m
+	return res, nil
+}
+
+func (f *fieldSelector) find(item *goquery.Selection) *goquery.Selection {
+	if f.matcher == nil {
+		return item
+	}
+	if item.IsMatcher(f.matcher) && f.attr != "" {
+		// such as the default link selector on an item that is a link
+		return item
+	}
+	return item.FindMatcher(f.matcher)
+}
+
+// text returns the attribute or the text of the first match, with the
+// spaces collapsed.
+func (f *fieldSelector) text(item *goquery.Selection) string {
+	if f == nil {
+		return ""
+	}
+	s := f.find(item).First()
+	if f.attr != "" {
+		return strings.TrimSpace(s.AttrOr(f.attr, ""))
+	}
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+// date is like text, but prefers the datetime attribute of time elements.
+func (f *fieldSelector) date(item *goquery.Selection) string {
+	if f == nil {
+		return ""
+	}
+	if f.attr == "" {
+		if v, ok := f.find(item).First().Attr("datetime"); ok {
+			return v
+		}
+	}
+	return f.text(item)
+}
+
+// html returns the HTML of all matches, or the attribute of the first match
+// as text.
+func (f *fieldSelector) html(item *goquery.Selection) string {
+	if f == nil {
+		return ""
+	}
+	if f.attr != "" {
+		return html.EscapeString(f.text(item))
+	}
+	var b strings.Builder
+	f.find(item).Each(func(_ int, s *goquery.Selection) {
+		if h, err := goquery.OuterHtml(s); err == nil {
+			b.WriteString(h)
+		}
+	})
+	return b.String()
+}
+
+// resolveURLs makes the links and sources of the page absolute, honouring
+// its base element.
+func resolveURLs(doc *goquery.Document, pageURL string) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+	for _, attr := range []string{"href", "src", "poster"} {
+		doc.Find("[" + attr + "]").Each(func(_ int, s *goquery.Selection) {
+			if u, err := base.Parse(strings.TrimSpace(s.AttrOr(attr, ""))); err == nil {
+				s.SetAttr(attr, u.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+const changelog = `<html><head><base href="/product/"></head><body>
+<div class="release" data-id="r2">
+	<h2><a href="v2">Version  2.0</a></h2>
+	<time datetime="2024-08-12T10:00:00Z">Monday</time>
+	<div class="notes"><p>New <img src="shot.png"> UI</p></div>
+</div>
+<div class="release" data-id="r1">
+	<h2>Version 1.0</h2>
+	<span class="date">August 1st, 2024</span>
+	<div class="notes"><p>First</p></div><div class="notes"><p>release</p></div>
+</div>
+<div class="release"></div>
+</body></html>`
+
+func TestScrapeItems(t *testing.T) {
+	items, err := client.ScrapeItems("https://example.com/changelog", []byte(changelog), model.ItemMapping{
+		Items:   ".release",
+		ID:      "@data-id",
+		Title:   "h2",
+		Date:    "time, .date",
+		Content: ".notes",
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 2, "items without a title and a link are skipped")
+
+	assert.Equal(t, "Version 2.0", ptr.From(items[0].Title))
+	assert.Equal(t, "r2", ptr.From(items[0].GUID))
+	assert.Equal(t, "https://example.com/product/v2", ptr.From(items[0].Link), "the base element is honoured")
+	assert.Equal(t, time.Date(2024, 8, 12, 10, 0, 0, 0, time.UTC), *items[0].PubDate)
+	assert.Equal(t, `<div class="notes"><p>New <img src="https://example.com/product/shot.png"/> UI</p></div>`, ptr.From(items[0].Content))
+
+	assert.Equal(t, "", ptr.From(items[1].Link))
+	assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), *items[1].PubDate)
+	assert.Equal(t, `<div class="notes"><p>First</p></div><div class="notes"><p>release</p></div>`, ptr.From(items[1].Content))
+
+	// without an ID selector, the link is the GUID
+	items, err = client.ScrapeItems("https://example.com/changelog", []byte(changelog), model.ItemMapping{
+		Items: ".release h2 a",
+		Title: "@href",
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "https://example.com/product/v2", ptr.From(items[0].Link), "the item itself is the link")
+	assert.Equal(t, "https://example.com/product/v2", ptr.From(items[0].GUID))
+}
+
+func TestValidateScrapeMapping(t *testing.T) {
+	assert.NoError(t, client.ValidateScrapeMapping(model.ItemMapping{Items: "li", Title: "a", Link: "a@href"}))
+	assert.ErrorContains(t, client.ValidateScrapeMapping(model.ItemMapping{Items: "li["}), "required")
+	assert.ErrorContains(t, client.ValidateScrapeMapping(model.ItemMapping{Items: "li[", Title: "a"}), "invalid items selector")
+	assert.ErrorContains(t, client.ValidateScrapeMapping(model.ItemMapping{Items: "li", Title: "a", Date: ">>"}), "invalid date selector")
+}
+
+func TestScraperFetchItems(t *testing.T) {
+	var requested string
+	scraper := client.NewScraperWithRequestFn(func(ctx context.Context, link string, options model.FeedRequestOptions) (*http.Response, error) {
+		requested = link
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			// the page declares its charset, "Caf\xe9" is "Café"
+			Body: io.NopCloser(strings.NewReader(`<meta charset="iso-8859-1"><ul><li><a href="/a">Caf` + "\xe9" + `</a></li></ul>`)),
+		}, nil
+	}, model.ItemMapping{Items: "li", Title: "a"})
+
+	result, err := scraper.FetchItems(context.Background(), "https://example.com/news", model.FeedRequestOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/news", requested)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "Café", ptr.From(result.Items[0].Title))
+	assert.Equal(t, "https://example.com/a", ptr.From(result.Items[0].Link))
+	assert.Equal(t, "", ptr.From(result.Items[0].Content))
+	assert.Nil(t, result.Items[0].PubDate)
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 8, 12, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2024-08-12", "August 12, 2024", "Aug 12th, 2024", " 12 Aug  2024 ", "Monday, August 12, 2024"} {
+		got := client.ParseDate(s)
+		if assert.NotNil(t, got, s) {
+			assert.True(t, want.Equal(*got), s)
+		}
+	}
+	assert.Nil(t, client.ParseDate("yesterday"))
+	assert.Nil(t, client.ParseDate(""))
+}
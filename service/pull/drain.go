@@ -0,0 +1,88 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned when a pull is requested after Drain was called.
+var ErrShuttingDown = errors.New("puller is shutting down")
+
+// pulls tracks every in-flight pull, including the ones started by API
+// requests, so that shutdown can wait for them to write their results.
+var pulls = newTracker()
+
+type tracker struct {
+	mu       sync.Mutex
+	count    int
+	draining bool
+	idle     chan struct{}
+
+	// ctx is canceled when draining takes too long, which aborts the
+	// remaining requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newTracker() *tracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tracker{
+		idle:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (t *tracker) acquire() (context.Context, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return nil, ErrShuttingDown
+	}
+	t.count++
+	return t.ctx, nil
+}
+
+func (t *tracker) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count--
+	if t.count == 0 && t.draining {
+		close(t.idle)
+	}
+}
+
+func (t *tracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		if t.count == 0 {
+			close(t.idle)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Out of time: abort the requests still running. They record the failure
+	// and return quickly, so give them a moment to finish their writes.
+	t.cancel()
+	select {
+	case <-t.idle:
+		return nil
+	case <-time.After(5 * time.Second):
+		return ctx.Err()
+	}
+}
+
+// Drain stops accepting new pulls and waits for in-flight ones to finish.
+// When ctx is done, the remaining pulls are canceled.
+func Drain(ctx context.Context) error {
+	return pulls.drain(ctx)
+}
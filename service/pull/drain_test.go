@@ -0,0 +1,50 @@
+package pull
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerDrain(t *testing.T) {
+	t.Run("drain without pulls returns immediately", func(t *testing.T) {
+		tr := newTracker()
+		require.NoError(t, tr.drain(context.Background()))
+
+		_, err := tr.acquire()
+		assert.ErrorIs(t, err, ErrShuttingDown)
+	})
+
+	t.Run("drain waits for in-flight pulls", func(t *testing.T) {
+		tr := newTracker()
+		pullCtx, err := tr.acquire()
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			tr.release()
+		}()
+
+		require.NoError(t, tr.drain(context.Background()))
+		assert.NoError(t, pullCtx.Err(), "finished pulls should not be canceled")
+	})
+
+	t.Run("drain cancels pulls after the deadline", func(t *testing.T) {
+		tr := newTracker()
+		pullCtx, err := tr.acquire()
+		require.NoError(t, err)
+
+		go func() {
+			<-pullCtx.Done()
+			tr.release()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.NoError(t, tr.drain(ctx))
+		assert.ErrorIs(t, pullCtx.Err(), context.Canceled)
+	})
+}
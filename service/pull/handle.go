@@ -13,12 +13,21 @@ import (
 
 func (p *Puller) do(ctx context.Context, f *model.Feed, force bool) error {
 	logger := slog.With("feed_id", f.ID, "feed_link", ptr.From(f.Link))
+
+	drainCtx, err := pulls.acquire()
+	if err != nil {
+		return err
+	}
+	defer pulls.release()
+
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
+	stop := context.AfterFunc(drainCtx, cancel)
+	defer stop()
 
 	currentInterval := p.getCurrentInterval()
 	updateAction, skipReason := DecideFeedUpdateAction(f, time.Now(), currentInterval)
-	if skipReason == &SkipReasonSuspended {
+	if skipReason == &SkipReasonSuspended || skipReason == &SkipReasonNewsletter {
 		logger.Info(fmt.Sprintf("skip: %s", skipReason))
 		return nil
 	}
@@ -35,11 +44,25 @@ func (p *Puller) do(ctx context.Context, f *model.Feed, force bool) error {
 	}
 
 	repo := defaultSingleFeedRepo{
-		feedID:   f.ID,
-		feedRepo: p.feedRepo,
-		itemRepo: p.itemRepo,
+		feedID:             f.ID,
+		markUnreadOnUpdate: ptr.From(f.MarkUnreadOnUpdate),
+		feedRepo:           p.feedRepo,
+		itemRepo:           p.itemRepo,
+		fetchLogRepo:       p.fetchLogRepo,
+	}
+	return NewSingleFeedPuller(readFeedFn(f), &repo).Pull(ctx, f)
+}
+
+// readFeedFn returns the ReadFeedItemsFn for the kind of f.
+func readFeedFn(f *model.Feed) ReadFeedItemsFn {
+	switch ptr.From(f.Kind) {
+	case model.FeedKindScrape:
+		return client.NewScraper(ptr.From(f.Mapping)).FetchItems
+	case model.FeedKindJSON:
+		return client.NewJSONClient(ptr.From(f.Mapping)).FetchItems
+	default:
+		return client.NewFeedClient().FetchItems
 	}
-	return NewSingleFeedPuller(client.NewFeedClient().FetchItems, &repo).Pull(ctx, f)
 }
 
 // FeedUpdateAction represents the action to take when considering checking a
@@ -64,10 +87,13 @@ var (
 	SkipReasonSuspended  = FeedSkipReason{"user suspended feed updates"}
 	SkipReasonCoolingOff = FeedSkipReason{"slowing down requests due to past failures to update feed"}
 	SkipReasonTooSoon    = FeedSkipReason{"feed was updated too recently"}
+	SkipReasonNewsletter = FeedSkipReason{"newsletters receive their items by email"}
 )
 
 func DecideFeedUpdateAction(f *model.Feed, now time.Time, currentInterval time.Duration) (FeedUpdateAction, *FeedSkipReason) {
-	if f.IsSuspended() {
+	if f.IsNewsletter() {
+		return ActionSkipUpdate, &SkipReasonNewsletter
+	} else if f.IsSuspended() {
 		return ActionSkipUpdate, &SkipReasonSuspended
 	} else if f.ConsecutiveFailures > 0 {
 		backoffTime := CalculateBackoffTime(f.ConsecutiveFailures, currentInterval)
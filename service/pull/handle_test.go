@@ -39,6 +39,16 @@ func TestDecideFeedUpdateAction(t *testing.T) {
 			expectedAction:     pull.ActionSkipUpdate,
 			expectedSkipReason: &pull.SkipReasonSuspended,
 		},
+		{
+			description: "newsletter should never be pulled",
+			currentTime: parseTime("2025-01-01T12:00:00Z"),
+			feed: model.Feed{
+				Kind:      ptr.To(model.FeedKindNewsletter),
+				UpdatedAt: parseTime("2024-01-01T12:00:00Z"),
+			},
+			expectedAction:     pull.ActionSkipUpdate,
+			expectedSkipReason: &pull.SkipReasonNewsletter,
+		},
 		{
 			description: "feed should be updated when conditions are met",
 			currentTime: parseTime("2025-01-01T12:00:00Z"),
@@ -0,0 +1,48 @@
+package pull
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// healthGrace is how long a pull round may overrun before health checks
+// fail, for the favicons fixed after pulling and a slow database.
+const healthGrace = 5 * time.Minute
+
+// heartbeat records the start and end of the scheduling rounds of Run, so
+// that health checks can tell whether it's stuck.
+type heartbeat struct {
+	// started and ended are the unix nanoseconds of the start of the
+	// current or last round and of the end of the last one.
+	started atomic.Int64
+	ended   atomic.Int64
+	// deadline is when the next start or end is due at the latest.
+	deadline atomic.Int64
+}
+
+// start records the start of a round that takes at most timeout.
+func (h *heartbeat) start(now time.Time, timeout time.Duration) {
+	h.started.Store(now.UnixNano())
+	h.deadline.Store(now.Add(timeout + healthGrace).UnixNano())
+}
+
+// end records the end of a round, the next one starts at most wait later.
+func (h *heartbeat) end(now time.Time, wait time.Duration) {
+	h.ended.Store(now.UnixNano())
+	h.deadline.Store(now.Add(wait + healthGrace).UnixNano())
+}
+
+// check reports an error if the next start or end is overdue at now. It
+// passes before the first round.
+func (h *heartbeat) check(now time.Time) error {
+	deadline := h.deadline.Load()
+	if deadline == 0 || now.UnixNano() <= deadline {
+		return nil
+	}
+	started, ended := time.Unix(0, h.started.Load()), time.Unix(0, h.ended.Load())
+	if ended.Before(started) {
+		return fmt.Errorf("pull round has been running for %s", now.Sub(started).Round(time.Second))
+	}
+	return fmt.Errorf("puller has not started a round for %s", now.Sub(ended).Round(time.Second))
+}
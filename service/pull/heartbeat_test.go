@@ -0,0 +1,27 @@
+package pull
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatCheck(t *testing.T) {
+	var h heartbeat
+	now := time.Now()
+	assert.NoError(t, h.check(now), "no round yet")
+
+	h.start(now, 15*time.Minute)
+	assert.NoError(t, h.check(now.Add(15*time.Minute+healthGrace)))
+	assert.ErrorContains(t, h.check(now.Add(15*time.Minute+healthGrace+time.Second)), "pull round has been running for 20m1s")
+
+	now = now.Add(10 * time.Minute)
+	h.end(now, 30*time.Minute)
+	assert.NoError(t, h.check(now.Add(30*time.Minute+healthGrace)), "waiting for the next round")
+	assert.ErrorContains(t, h.check(now.Add(time.Hour)), "puller has not started a round for 1h0m0s")
+
+	now = now.Add(30 * time.Minute)
+	h.start(now, 12*time.Hour)
+	assert.NoError(t, h.check(now.Add(6*time.Hour)), "long rounds with a long interval are fine")
+}
@@ -0,0 +1,98 @@
+package pull
+
+import (
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+type IdentityRepairRepo interface {
+	ListByFeed(feedID uint) ([]*model.Item, error)
+	Merge(keep *model.Item, duplicates []uint) error
+}
+
+// IdentityRepairResult is what RepairItemIdentity changed.
+type IdentityRepairResult struct {
+	// Rekeyed is the number of items whose GUID changed.
+	Rekeyed int `json:"rekeyed"`
+	// Merged is the number of duplicates that were removed.
+	Merged int `json:"merged"`
+}
+
+// RepairItemIdentity gives the stored items of a feed the keys that its
+// identity strategy produces for new pulls, and merges items that turn out
+// to be duplicates. The oldest item of each group is kept; it is read if any
+// copy was read and bookmarked if any copy was bookmarked.
+//
+// It's meant to run once after upgrading from versions that fell back to the
+// raw link, and after changing a feed's strategy. Running it again is safe.
+func RepairItemIdentity(itemRepo IdentityRepairRepo, feed *model.Feed) (IdentityRepairResult, error) {
+	var res IdentityRepairResult
+	strategy := ptr.From(feed.ItemIdentity)
+
+	items, err := itemRepo.ListByFeed(feed.ID)
+	if err != nil {
+		return res, err
+	}
+
+	groups := make(map[string][]*model.Item)
+	order := make([]string, 0)
+	for _, item := range items {
+		key := repairGroupKey(strategy, item)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		keep := group[0]
+		if len(group) == 1 && ptr.From(keep.GUID) == key {
+			continue
+		}
+
+		duplicates := make([]uint, 0, len(group)-1)
+		for _, dup := range group[1:] {
+			duplicates = append(duplicates, dup.ID)
+			if !ptr.From(dup.Unread) {
+				keep.Unread = ptr.To(false)
+			}
+			if ptr.From(dup.Bookmark) {
+				keep.Bookmark = ptr.To(true)
+				// the earliest bookmark counts
+				if keep.BookmarkedAt == nil || dup.BookmarkedAt != nil && dup.BookmarkedAt.Before(*keep.BookmarkedAt) {
+					keep.BookmarkedAt = dup.BookmarkedAt
+				}
+			}
+		}
+		if ptr.From(keep.GUID) != key {
+			res.Rekeyed++
+		}
+		keep.GUID = ptr.To(key)
+		if err := itemRepo.Merge(keep, duplicates); err != nil {
+			return res, err
+		}
+		res.Merged += len(duplicates)
+	}
+	return res, nil
+}
+
+// repairGroupKey returns the key that duplicates of item share.
+func repairGroupKey(strategy string, item *model.Item) string {
+	if strategy == client.IdentityLink || strategy == client.IdentityContent {
+		return client.ItemKey(strategy, item)
+	}
+	return autoKey(item)
+}
+
+// autoKey returns the key the parser produces now for an item stored by an
+// older version. A GUID equal to the link was the old fallback for items
+// without a GUID, which is a normalized link now. Other GUIDs are kept as
+// they are: GUIDs that only differ in the fragment are distinct items.
+func autoKey(item *model.Item) string {
+	if guid := ptr.From(item.GUID); guid != "" && guid == ptr.From(item.Link) {
+		return client.NormalizeLink(guid)
+	}
+	return client.ItemKey(client.IdentityAuto, item)
+}
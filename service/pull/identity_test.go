@@ -0,0 +1,83 @@
+package pull_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sudo-Ivan/fusionx/model"
+	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/service/pull"
+	"github.com/Sudo-Ivan/fusionx/service/pull/client"
+)
+
+// mockIdentityRepairRepo keeps the items of one feed in memory.
+type mockIdentityRepairRepo struct {
+	items []*model.Item
+}
+
+func (m *mockIdentityRepairRepo) ListByFeed(feedID uint) ([]*model.Item, error) {
+	res := make([]*model.Item, 0, len(m.items))
+	for _, item := range m.items {
+		copied := *item
+		res = append(res, &copied)
+	}
+	return res, nil
+}
+
+func (m *mockIdentityRepairRepo) Merge(keep *model.Item, duplicates []uint) error {
+	items := make([]*model.Item, 0, len(m.items))
+	for _, item := range m.items {
+		switch {
+		case item.ID == keep.ID:
+			items = append(items, keep)
+		case !slices.Contains(duplicates, item.ID):
+			items = append(items, item)
+		}
+	}
+	m.items = items
+	return nil
+}
+
+func TestRepairItemIdentity(t *testing.T) {
+	feed := &model.Feed{ID: 1}
+	// older versions stored the raw link when there was no GUID
+	itemRepo := &mockIdentityRepairRepo{items: []*model.Item{
+		{ID: 1, GUID: ptr.To("http://www.example.com/post?utm_source=rss"), Link: ptr.To("http://www.example.com/post?utm_source=rss"), Title: ptr.To("Post"), Unread: ptr.To(false)},
+		{ID: 2, GUID: ptr.To("https://example.com/post#comments"), Link: ptr.To("https://example.com/post#comments"), Title: ptr.To("Post"), Unread: ptr.To(true), Bookmark: ptr.To(true)},
+		{ID: 3, GUID: ptr.To("tag:example.com,2024:other"), Link: ptr.To("https://example.com/other"), Title: ptr.To("Other"), Unread: ptr.To(true)},
+	}}
+
+	res, err := pull.RepairItemIdentity(itemRepo, feed)
+	require.NoError(t, err)
+	assert.Equal(t, pull.IdentityRepairResult{Rekeyed: 1, Merged: 1}, res)
+
+	require.Len(t, itemRepo.items, 2)
+	post := itemRepo.items[0]
+	assert.Equal(t, "https://example.com/post", ptr.From(post.GUID))
+	assert.False(t, ptr.From(post.Unread))
+	assert.True(t, ptr.From(post.Bookmark))
+	assert.Equal(t, "tag:example.com,2024:other", ptr.From(itemRepo.items[1].GUID))
+
+	res, err = pull.RepairItemIdentity(itemRepo, feed)
+	require.NoError(t, err)
+	assert.Equal(t, pull.IdentityRepairResult{}, res, "repairing again should change nothing")
+}
+
+func TestRepairItemIdentityDistinctGUIDs(t *testing.T) {
+	// real GUIDs that only differ in the fragment, such as the entries of
+	// a changelog, are distinct items
+	itemRepo := &mockIdentityRepairRepo{items: []*model.Item{
+		{ID: 1, GUID: ptr.To("https://example.com/changelog#v1"), Link: ptr.To("https://example.com/changelog"), Title: ptr.To("v1")},
+		{ID: 2, GUID: ptr.To("https://example.com/changelog#v2"), Link: ptr.To("https://example.com/changelog"), Title: ptr.To("v2")},
+	}}
+
+	for _, strategy := range []string{"", client.IdentityAuto} {
+		res, err := pull.RepairItemIdentity(itemRepo, &model.Feed{ID: 1, ItemIdentity: ptr.To(strategy)})
+		require.NoError(t, err)
+		assert.Equal(t, pull.IdentityRepairResult{}, res)
+		assert.Len(t, itemRepo.items, 2)
+	}
+}
@@ -5,18 +5,24 @@ import (
 	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Sudo-Ivan/fusionx/model"
 	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
 	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/events"
 	"github.com/Sudo-Ivan/fusionx/service/favicon"
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
 )
 
 var (
 	interval = 30 * time.Minute
 )
 
+// fetchLogLimit is the number of fetch logs kept for each feed.
+const fetchLogLimit = 100
+
 type FeedRepo interface {
 	List(filter *repo.FeedListFilter) ([]*model.Feed, error)
 	Get(id uint) (*model.Feed, error)
@@ -24,7 +30,12 @@ type FeedRepo interface {
 }
 
 type ItemRepo interface {
-	Insert(items []*model.Item) error
+	Save(items []*model.Item, markUnread bool) (repo.ItemSaveResult, error)
+}
+
+type FetchLogRepo interface {
+	Create(log *model.FetchLog) error
+	Prune(feedID uint, keep int) error
 }
 
 type ConfigRepo interface {
@@ -32,37 +43,58 @@ type ConfigRepo interface {
 }
 
 type Puller struct {
-	feedRepo   FeedRepo
-	itemRepo   ItemRepo
-	configRepo ConfigRepo
-	faviconSvc *favicon.Service
+	feedRepo     FeedRepo
+	itemRepo     ItemRepo
+	configRepo   ConfigRepo
+	fetchLogRepo FetchLogRepo
+	faviconSvc   *favicon.Service
+
+	// running and heartbeat let health checks tell whether Run is alive.
+	running   atomic.Bool
+	heartbeat heartbeat
 }
 
 // TODO: cache favicon
 
-func NewPuller(feedRepo FeedRepo, itemRepo ItemRepo, configRepo ConfigRepo) *Puller {
+func NewPuller(feedRepo FeedRepo, itemRepo ItemRepo, configRepo ConfigRepo, fetchLogRepo FetchLogRepo) *Puller {
 	return &Puller{
-		feedRepo:   feedRepo,
-		itemRepo:   itemRepo,
-		configRepo: configRepo,
-		faviconSvc: favicon.NewService("./cache/favicons"),
+		feedRepo:     feedRepo,
+		itemRepo:     itemRepo,
+		configRepo:   configRepo,
+		fetchLogRepo: fetchLogRepo,
+		faviconSvc:   favicon.NewService(favicon.CacheDir),
 	}
 }
 
-func (p *Puller) Run() {
+// Run pulls all feeds periodically until ctx is done. Pulls that are in
+// flight when ctx is done keep running; use Drain to wait for them.
+func (p *Puller) Run(ctx context.Context) {
+	p.running.Store(true)
+	defer p.running.Store(false)
+
 	// Get initial interval
 	currentInterval := p.getCurrentInterval()
 	ticker := time.NewTicker(currentInterval)
 	defer ticker.Stop()
 
 	for {
+		p.heartbeat.start(time.Now(), pullTimeout(currentInterval))
+
 		// #nosec G104 - PullAll errors are logged internally, service should continue running
-		p.PullAll(context.Background(), false)
-		
-		// Also try to fix missing favicons
-		p.FixMissingFavicons(context.Background())
+		p.PullAll(ctx, false, nil)
 
-		<-ticker.C
+		if ctx.Err() == nil {
+			// Also try to fix missing favicons
+			p.FixMissingFavicons(ctx)
+		}
+		p.heartbeat.end(time.Now(), currentInterval)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("puller stopped")
+			return
+		case <-ticker.C:
+		}
 
 		// Check if interval has changed and update ticker if needed
 		newInterval := p.getCurrentInterval()
@@ -74,6 +106,19 @@ func (p *Puller) Run() {
 	}
 }
 
+// Check reports an error if Run is not running or seems to be stuck.
+func (p *Puller) Check() error {
+	if !p.running.Load() {
+		return errors.New("puller is not running")
+	}
+	return p.heartbeat.check(time.Now())
+}
+
+// pullTimeout is how long PullAll may take with the refresh interval.
+func pullTimeout(interval time.Duration) time.Duration {
+	return interval / 2
+}
+
 func (p *Puller) getCurrentInterval() time.Duration {
 	if p.configRepo == nil {
 		return interval
@@ -107,9 +152,18 @@ func (p *Puller) FixMissingFavicons(ctx context.Context) {
 	}
 }
 
-func (p *Puller) PullAll(ctx context.Context, force bool) error {
+// Progress receives the progress of PullAll, in feeds.
+type Progress interface {
+	SetTotal(n int)
+	Add(n int)
+}
+
+// PullAll pulls every feed and reports each pulled feed to progress, which
+// may be nil. When ctx is done, no more feeds are dispatched, but the pulls
+// already started are left to finish or be drained.
+func (p *Puller) PullAll(ctx context.Context, force bool, progress Progress) error {
 	currentInterval := p.getCurrentInterval()
-	ctx, cancel := context.WithTimeout(ctx, currentInterval/2)
+	pullCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pullTimeout(currentInterval))
 	defer cancel()
 
 	feeds, err := p.feedRepo.List(nil)
@@ -123,19 +177,36 @@ func (p *Puller) PullAll(ctx context.Context, force bool) error {
 		return nil
 	}
 
+	if progress != nil {
+		progress.SetTotal(len(feeds))
+	}
+	events.Publish(events.RefreshStarted, events.Refresh{Feeds: len(feeds)})
+	defer events.Publish(events.RefreshFinished, events.Refresh{Feeds: len(feeds)})
+
+	metrics.AddPullerQueue(len(feeds))
 	routinePool := make(chan struct{}, 10)
 	defer close(routinePool)
 	wg := sync.WaitGroup{}
-	for _, f := range feeds {
-		routinePool <- struct{}{}
+	for i, f := range feeds {
+		select {
+		case routinePool <- struct{}{}:
+		case <-ctx.Done():
+			metrics.AddPullerQueue(-(len(feeds) - i))
+			wg.Wait()
+			return ctx.Err()
+		}
 		wg.Add(1)
 		go func(f *model.Feed) {
 			defer func() {
+				metrics.AddPullerQueue(-1)
+				if progress != nil {
+					progress.Add(1)
+				}
 				wg.Done()
 				<-routinePool
 			}()
 
-			if err := p.do(ctx, f, force); err != nil {
+			if err := p.do(pullCtx, f, force); err != nil {
 				slog.Error("failed to pull feed", "error", err, "feed_id", f.ID, "feed_link", ptr.From(f.Link))
 			}
 		}(f)
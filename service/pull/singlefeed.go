@@ -8,6 +8,10 @@ import (
 
 	"github.com/Sudo-Ivan/fusionx/model"
 	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/pkg/simhash"
+	"github.com/Sudo-Ivan/fusionx/repo"
+	"github.com/Sudo-Ivan/fusionx/service/events"
+	"github.com/Sudo-Ivan/fusionx/service/metrics"
 	"github.com/Sudo-Ivan/fusionx/service/pull/client"
 )
 
@@ -24,9 +28,12 @@ type UpdateFeedInStoreFn func(feedID uint, items []*model.Item, lastBuild *time.
 
 // SingleFeedRepo represents a datastore for storing information about a feed.
 type SingleFeedRepo interface {
-	InsertItems(items []*model.Item) error
+	// SaveItems stores new items and updates the changed ones.
+	SaveItems(items []*model.Item) (repo.ItemSaveResult, error)
 	RecordSuccess(lastBuild *time.Time) error
 	RecordFailure(readErr error) error
+	// RecordFetch appends an entry to the feed's fetch history.
+	RecordFetch(log *model.FetchLog) error
 }
 
 type SingleFeedPuller struct {
@@ -44,17 +51,22 @@ func NewSingleFeedPuller(readFeed ReadFeedItemsFn, repo SingleFeedRepo) SingleFe
 
 // defaultSingleFeedRepo is the default implementation of SingleFeedRepo
 type defaultSingleFeedRepo struct {
-	feedID   uint
-	feedRepo FeedRepo
-	itemRepo ItemRepo
+	feedID             uint
+	markUnreadOnUpdate bool
+	feedRepo           FeedRepo
+	itemRepo           ItemRepo
+	fetchLogRepo       FetchLogRepo
 }
 
-func (r *defaultSingleFeedRepo) InsertItems(items []*model.Item) error {
-	// Set the correct feed ID for all items.
+func (r *defaultSingleFeedRepo) SaveItems(items []*model.Item) (repo.ItemSaveResult, error) {
+	// Set the correct feed ID for all items, and the keys to find the same
+	// story in other feeds.
 	for _, item := range items {
 		item.FeedID = r.feedID
+		item.CanonicalLink = ptr.To(client.NormalizeLink(ptr.From(item.Link)))
+		item.TitleHash = simhash.Title(ptr.From(item.Title))
 	}
-	return r.itemRepo.Insert(items)
+	return r.itemRepo.Save(items, r.markUnreadOnUpdate)
 }
 
 func (r *defaultSingleFeedRepo) RecordSuccess(lastBuild *time.Time) error {
@@ -77,31 +89,90 @@ func (r *defaultSingleFeedRepo) RecordFailure(readErr error) error {
 	})
 }
 
+func (r *defaultSingleFeedRepo) RecordFetch(log *model.FetchLog) error {
+	if r.fetchLogRepo == nil {
+		return nil
+	}
+	if err := r.fetchLogRepo.Create(log); err != nil {
+		return err
+	}
+	return r.fetchLogRepo.Prune(r.feedID, fetchLogLimit)
+}
+
 func (p SingleFeedPuller) Pull(ctx context.Context, feed *model.Feed) error {
 	logger := slog.With("feed_id", feed.ID, "feed_link", ptr.From(feed.Link))
 
+	events.Publish(events.FeedFetchStarted, events.FeedFetch{FeedID: feed.ID})
+
 	// We don't exit on error, as we want to record any error in the data store.
+	start := time.Now()
 	fetchResult, readErr := p.readFeed(ctx, *feed.Link, feed.FeedRequestOptions)
+	duration := time.Since(start)
+	metrics.ObserveFetch(fetchResult.StatusCode, duration, readErr)
 	if readErr == nil {
 		logger.Info(fmt.Sprintf("fetched %d items", len(fetchResult.Items)))
 	} else {
 		logger.Warn("failed to fetch feed", "error", readErr)
 	}
 
-	return p.updateFeedInStore(feed.ID, fetchResult.Items, fetchResult.LastBuild, readErr)
+	if strategy := ptr.From(feed.ItemIdentity); strategy != "" && strategy != client.IdentityAuto {
+		for _, item := range fetchResult.Items {
+			item.GUID = ptr.To(client.ItemKey(strategy, item))
+		}
+	}
+
+	saved, err := p.updateFeedInStore(feed.ID, fetchResult.Items, fetchResult.LastBuild, readErr)
+	metrics.AddItemsInserted(saved.New)
+
+	fetchLog := &model.FetchLog{
+		FeedID:       feed.ID,
+		StatusCode:   fetchResult.StatusCode,
+		Duration:     duration.Milliseconds(),
+		Size:         fetchResult.Size,
+		ItemsParsed:  len(fetchResult.Items),
+		ItemsNew:     saved.New,
+		ItemsUpdated: saved.Updated,
+		Error:        ptr.To(""),
+	}
+	if readErr != nil {
+		fetchLog.Error = ptr.To(readErr.Error())
+	} else if err != nil {
+		fetchLog.Error = ptr.To(err.Error())
+	}
+	if logErr := p.repo.RecordFetch(fetchLog); logErr != nil {
+		logger.Warn("failed to record fetch history", "error", logErr)
+	}
+
+	if msg := ptr.From(fetchLog.Error); msg != "" {
+		events.Publish(events.FeedFetchFailed, events.FeedFetch{FeedID: feed.ID, Error: msg})
+	} else {
+		events.Publish(events.FeedFetchFinished, events.FeedFetch{
+			FeedID:       feed.ID,
+			NewItems:     saved.New,
+			UpdatedItems: saved.Updated,
+		})
+	}
+	if saved.New > 0 {
+		events.Publish(events.ItemsNew, events.NewItems{FeedID: feed.ID, Count: saved.New})
+	}
+
+	return err
 }
 
 // updateFeedInStore saves the result of a feed fetch to the data store.
 // If the fetch failed, it records that in the data store.
-// If the fetch succeeds, it stores the latest build time and adds any new feed items.
-func (p SingleFeedPuller) updateFeedInStore(feedID uint, items []*model.Item, lastBuild *time.Time, requestError error) error {
+// If the fetch succeeds, it stores the latest build time and adds any new feed
+// items and updates the changed ones. It returns how many items were stored
+// or updated.
+func (p SingleFeedPuller) updateFeedInStore(feedID uint, items []*model.Item, lastBuild *time.Time, requestError error) (repo.ItemSaveResult, error) {
 	if requestError != nil {
-		return p.repo.RecordFailure(requestError)
+		return repo.ItemSaveResult{}, p.repo.RecordFailure(requestError)
 	}
 
-	if err := p.repo.InsertItems(items); err != nil {
-		return err
+	saved, err := p.repo.SaveItems(items)
+	if err != nil {
+		return repo.ItemSaveResult{}, err
 	}
 
-	return p.repo.RecordSuccess(lastBuild)
+	return saved, p.repo.RecordSuccess(lastBuild)
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Sudo-Ivan/fusionx/model"
 	"github.com/Sudo-Ivan/fusionx/pkg/ptr"
+	"github.com/Sudo-Ivan/fusionx/repo"
 	"github.com/Sudo-Ivan/fusionx/service/pull"
 	"github.com/Sudo-Ivan/fusionx/service/pull/client"
 )
@@ -33,17 +34,20 @@ func (m *mockFeedReader) Read(ctx context.Context, feedURL string, options model
 // mockSingleFeedRepo is a mock implementation of the SingleFeedRepo interface
 type mockSingleFeedRepo struct {
 	err          error
+	updated      int
 	items        []*model.Item
 	lastBuild    *time.Time
 	requestError error
+	fetchLog     *model.FetchLog
 }
 
-func (m *mockSingleFeedRepo) InsertItems(items []*model.Item) error {
+// SaveItems reports the first m.updated items as updated and the rest as new.
+func (m *mockSingleFeedRepo) SaveItems(items []*model.Item) (repo.ItemSaveResult, error) {
 	if m.err != nil {
-		return m.err
+		return repo.ItemSaveResult{}, m.err
 	}
 	m.items = items
-	return nil
+	return repo.ItemSaveResult{New: len(items) - m.updated, Updated: m.updated}, nil
 }
 
 func (m *mockSingleFeedRepo) RecordSuccess(lastBuild *time.Time) error {
@@ -63,16 +67,23 @@ func (m *mockSingleFeedRepo) RecordFailure(readErr error) error {
 	return nil
 }
 
+func (m *mockSingleFeedRepo) RecordFetch(log *model.FetchLog) error {
+	m.fetchLog = log
+	return nil
+}
+
 func TestSingleFeedPullerPull(t *testing.T) {
 	for _, tt := range []struct {
 		description                string
 		feed                       model.Feed
 		mockFeedReader             *mockFeedReader
 		mockDbErr                  error
+		mockUpdated                int
 		expectedErrMsg             string
 		expectedStoredItems        []*model.Item
 		expectedStoredLastBuild    *time.Time
 		expectedStoredRequestError error
+		expectedFetchLog           model.FetchLog
 	}{
 		{
 			description: "successful pull with no errors",
@@ -86,7 +97,9 @@ func TestSingleFeedPullerPull(t *testing.T) {
 			},
 			mockFeedReader: &mockFeedReader{
 				result: client.FetchItemsResult{
-					LastBuild: mustParseTime("2025-01-01T12:00:00Z"),
+					LastBuild:  mustParseTime("2025-01-01T12:00:00Z"),
+					StatusCode: 200,
+					Size:       1024,
 					Items: []*model.Item{
 						{
 							Title:   ptr.To("Test Item 1"),
@@ -125,6 +138,49 @@ func TestSingleFeedPullerPull(t *testing.T) {
 			},
 			expectedStoredLastBuild:    mustParseTime("2025-01-01T12:00:00Z"),
 			expectedStoredRequestError: nil,
+			expectedFetchLog: model.FetchLog{
+				FeedID:      42,
+				StatusCode:  200,
+				Size:        1024,
+				ItemsParsed: 2,
+				ItemsNew:    2,
+				Error:       ptr.To(""),
+			},
+		},
+		{
+			description: "changed items are counted as updated",
+			feed: model.Feed{
+				ID:   42,
+				Name: ptr.To("Test Feed"),
+				Link: ptr.To("https://example.com/feed.xml"),
+			},
+			mockFeedReader: &mockFeedReader{
+				result: client.FetchItemsResult{
+					StatusCode: 200,
+					Items: []*model.Item{
+						{
+							Title:  ptr.To("Corrected title"),
+							GUID:   ptr.To("guid1"),
+							FeedID: 42,
+						},
+					},
+				},
+			},
+			mockUpdated: 1,
+			expectedStoredItems: []*model.Item{
+				{
+					Title:  ptr.To("Corrected title"),
+					GUID:   ptr.To("guid1"),
+					FeedID: 42,
+				},
+			},
+			expectedFetchLog: model.FetchLog{
+				FeedID:       42,
+				StatusCode:   200,
+				ItemsParsed:  1,
+				ItemsUpdated: 1,
+				Error:        ptr.To(""),
+			},
 		},
 		{
 			description: "readFeed returns error",
@@ -134,12 +190,20 @@ func TestSingleFeedPullerPull(t *testing.T) {
 				Link: ptr.To("https://example.com/feed.xml"),
 			},
 			mockFeedReader: &mockFeedReader{
+				result: client.FetchItemsResult{
+					StatusCode: 503,
+				},
 				err: errors.New("dummy feed read error"),
 			},
 			expectedErrMsg:             "",
 			expectedStoredItems:        nil,
 			expectedStoredLastBuild:    nil,
 			expectedStoredRequestError: errors.New("dummy feed read error"),
+			expectedFetchLog: model.FetchLog{
+				FeedID:     42,
+				StatusCode: 503,
+				Error:      ptr.To("dummy feed read error"),
+			},
 		},
 		{
 			description: "readFeed succeeds but updateFeedInStore fails",
@@ -150,7 +214,8 @@ func TestSingleFeedPullerPull(t *testing.T) {
 			},
 			mockFeedReader: &mockFeedReader{
 				result: client.FetchItemsResult{
-					LastBuild: mustParseTime("2025-01-01T12:00:00Z"),
+					LastBuild:  mustParseTime("2025-01-01T12:00:00Z"),
+					StatusCode: 200,
 					Items: []*model.Item{
 						{
 							Title:   ptr.To("Test Item 1"),
@@ -168,11 +233,18 @@ func TestSingleFeedPullerPull(t *testing.T) {
 			expectedStoredItems:        nil,
 			expectedStoredLastBuild:    nil,
 			expectedStoredRequestError: nil,
+			expectedFetchLog: model.FetchLog{
+				FeedID:      42,
+				StatusCode:  200,
+				ItemsParsed: 1,
+				Error:       ptr.To("dummy database error"),
+			},
 		},
 	} {
 		t.Run(tt.description, func(t *testing.T) {
 			mockRepo := &mockSingleFeedRepo{
-				err: tt.mockDbErr,
+				err:     tt.mockDbErr,
+				updated: tt.mockUpdated,
 			}
 
 			err := pull.NewSingleFeedPuller(tt.mockFeedReader.Read, mockRepo).Pull(context.Background(), &tt.feed)
@@ -190,6 +262,11 @@ func TestSingleFeedPullerPull(t *testing.T) {
 			assert.Equal(t, tt.expectedStoredRequestError, mockRepo.requestError)
 			assert.Equal(t, tt.expectedStoredItems, mockRepo.items)
 			assert.Equal(t, tt.expectedStoredLastBuild, mockRepo.lastBuild)
+
+			require.NotNil(t, mockRepo.fetchLog)
+			// the duration depends on the wall clock, so don't compare it
+			mockRepo.fetchLog.Duration = 0
+			assert.Equal(t, tt.expectedFetchLog, *mockRepo.fetchLog)
 		})
 	}
 }
@@ -0,0 +1,100 @@
+// Package unread publishes the unread counts of feeds when they change.
+package unread
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/Sudo-Ivan/fusionx/service/events"
+)
+
+type Repo interface {
+	UnreadCounts() (map[uint]int, error)
+}
+
+// debounce is how long to wait for more changes before counting, so that a
+// refresh of all feeds doesn't count after every feed.
+const debounce = 500 * time.Millisecond
+
+// recount is how often to count without an event, for the changes that
+// don't publish one, such as deleted feeds and items, imports and merges.
+const recount = time.Minute
+
+type Counter struct {
+	repo Repo
+	last map[uint]int
+}
+
+func NewCounter(repo Repo) *Counter {
+	return &Counter{
+		repo: repo,
+	}
+}
+
+// Run counts the unread items after events that may change them, and
+// periodically for the other changes, and publishes the counts that changed,
+// until ctx is done.
+func (c *Counter) Run(ctx context.Context) {
+	types := []events.Type{events.FeedFetchFinished, events.ItemsNew, events.ItemsReadChanged}
+	ch, unsubscribe := events.Subscribe(256, types...)
+	defer func() { unsubscribe() }()
+
+	var err error
+	if c.last, err = c.repo.UnreadCounts(); err != nil {
+		slog.Warn("failed to count unread items", "error", err)
+	}
+
+	ticker := time.NewTicker(recount)
+	defer ticker.Stop()
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				// dropped for being too slow, so changes may be missed
+				ch, unsubscribe = events.Subscribe(256, types...)
+			} else if f, isFetch := e.Data.(events.FeedFetch); isFetch && f.NewItems == 0 && f.UpdatedItems == 0 {
+				continue
+			}
+			if timer == nil {
+				timer = time.After(debounce)
+			}
+		case <-timer:
+			timer = nil
+			c.publish()
+		case <-ticker.C:
+			if timer == nil {
+				c.publish()
+			}
+		}
+	}
+}
+
+func (c *Counter) publish() {
+	counts, err := c.repo.UnreadCounts()
+	if err != nil {
+		slog.Warn("failed to count unread items", "error", err)
+		return
+	}
+
+	changed := make(map[uint]int)
+	for id, n := range counts {
+		if c.last[id] != n {
+			changed[id] = n
+		}
+	}
+	for id := range c.last {
+		if _, ok := counts[id]; !ok {
+			changed[id] = 0
+		}
+	}
+	c.last = maps.Clone(counts)
+	if len(changed) > 0 {
+		events.Publish(events.UnreadCountsChanged, events.UnreadCounts{Counts: changed})
+	}
+}